@@ -0,0 +1,29 @@
+package v1
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+)
+
+// ExpressionVariable is the name of the variable that a channel's Expression refers to the bundle by. It is a map
+// with the keys "name", "package", "version", and "image", whose values are strings, and "properties", a list of
+// maps with the keys "type" and "value", where value is the property's JSON value.
+const ExpressionVariable = "bundle"
+
+// CompileExpression compiles a channel's Expression into a program that evaluates it for a bundle, given as the
+// value of ExpressionVariable.
+func CompileExpression(expression string) (cel.Program, error) {
+	env, err := cel.NewEnv(cel.Variable(ExpressionVariable, cel.MapType(cel.StringType, cel.DynType)))
+	if err != nil {
+		return nil, err
+	}
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if t := ast.OutputType(); t != cel.BoolType && t != cel.DynType {
+		return nil, fmt.Errorf("expression must evaluate to a bool, not %s", t)
+	}
+	return env.Program(ast)
+}
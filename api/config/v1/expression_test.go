@@ -0,0 +1,34 @@
+package v1
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompileExpression(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		wantErr    string
+	}{
+		{name: "bool", expression: `bundle.version.startsWith("1.")`},
+		{name: "dynamic", expression: `bundle.properties.exists(p, p.type == "olm.deprecated")`},
+		{name: "not a bool", expression: `size(bundle.properties)`, wantErr: "expression must evaluate to a bool, not int"},
+		{name: "syntax error", expression: `bundle.version ==`, wantErr: "Syntax error"},
+		{name: "unknown variable", expression: `csv.version == "1.0.0"`, wantErr: "undeclared reference to 'csv'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CompileExpression(tt.expression)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
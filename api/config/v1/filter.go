@@ -1,21 +1,246 @@
 package v1
 
 import (
+	"fmt"
+	"strings"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const (
+	// Kind is the kind of every filter configuration.
+	Kind = "FilterConfiguration"
+	// APIVersion is the apiVersion of the filter configurations described by this package.
+	APIVersion = "olm.operatorframework.io/v1"
+)
+
 type FilterConfiguration struct {
-	metav1.TypeMeta `json:",inline"`
-	Packages        []Package `json:"packages"`
+	metav1.TypeMeta  `json:",inline"`
+	TargetOLMVersion string    `json:"targetOLMVersion"`
+	Packages         []Package `json:"packages"`
+
+	// PackageSelector selects additional packages to keep, with all of their channels, by the labels of their
+	// olm.package object. A package's labels are its properties with string values, keyed by property type. Packages
+	// listed in Packages are configured by their entry there, whether or not they match.
+	PackageSelector *metav1.LabelSelector `json:"packageSelector"`
+
+	// ExcludePackages names the packages to remove, keeping every other package unfiltered. It cannot be combined
+	// with Packages or PackageSelector.
+	ExcludePackages []string `json:"excludePackages"`
+
+	// DefaultExcludeChannelRegex matches the names of channels that are dropped from packages that do not list any
+	// channels. When empty, DefaultExcludeChannelRegexBuiltIn is used.
+	DefaultExcludeChannelRegex string `json:"defaultExcludeChannelRegex"`
 }
 
+// DefaultExcludeChannelRegexBuiltIn matches channels such as "test", "ci", or "dev-preview" that usually should not
+// ship in a filtered catalog.
+const DefaultExcludeChannelRegexBuiltIn = `^(test|ci|dev)\b`
+
 type Package struct {
-	Name           string    `json:"name"`
+	Name string `json:"name"`
+
+	// NameRegex configures every catalog package whose name matches this regular expression with the rest of this
+	// entry, in place of Name. Packages configured by name are not matched.
+	NameRegex string `json:"nameRegex"`
+
 	DefaultChannel string    `json:"defaultChannel"`
 	Channels       []Channel `json:"channels"`
+
+	// DeriveDefaultChannelFrom selects where to find a new default channel when the catalog's default channel is
+	// filtered out and DefaultChannel is not set. The only supported value is "annotation", which reads the channel
+	// name from the DefaultChannelAnnotation CSV annotation of the package's highest version bundle.
+	DeriveDefaultChannelFrom string `json:"deriveDefaultChannelFrom"`
+	DefaultChannelAnnotation string `json:"defaultChannelAnnotation"`
+
+	// DefaultChannelStrategy chooses a new default channel among the kept channels when the catalog's default channel
+	// is filtered out and neither DefaultChannel nor DeriveDefaultChannelFrom is set. It is one of the
+	// DefaultChannelStrategy constants, and defaults to DefaultChannelStrategyError.
+	DefaultChannelStrategy string `json:"defaultChannelStrategy"`
+
+	// DropChannelsWithNoMatches removes configured channels in which no bundle is selected, with a warning, instead
+	// of failing. Filtering still fails if every channel is dropped or no default channel remains.
+	DropChannelsWithNoMatches bool `json:"dropChannelsWithNoMatches"`
+
+	// Migrate overrides the global --migrate flag for this package's bundles. When unset, the global flag applies.
+	// Migration happens when the catalog is rendered, before filtering, and never changes bundle versions.
+	Migrate *bool `json:"migrate"`
+
+	// KubeVersion keeps only the bundles that can be installed on this Kubernetes version, judged by the
+	// minKubeVersion of their CSV, in every channel that does not set its own. Bundles that do not declare a
+	// minKubeVersion are kept with a warning, which --strict turns into an error.
+	KubeVersion string `json:"kubeVersion"`
+
+	// MergeChannels combines channels of this package after their bundles are filtered. Each merge replaces its
+	// source channels with a single channel containing the union of their bundles.
+	MergeChannels []ChannelMerge `json:"mergeChannels"`
+
+	// SkipRanges rewrites the skipRange of every kept bundle of the package after filtering: SkipRangesRecompute
+	// narrows each skipRange to the kept versions it covers, and SkipRangesStrip removes them. A channel's
+	// SkipRangeOverride still applies to its head afterwards.
+	SkipRanges string `json:"skipRanges"`
+}
+
+const (
+	// SkipRangesRecompute replaces each skipRange with ">=<lowest> <=<highest>" of the kept versions it covers, or
+	// removes it when it covers none. A skipRange whose narrowed range would also cover kept versions that it did
+	// not, because it is not contiguous, is left as it is.
+	SkipRangesRecompute = "recompute"
+	// SkipRangesStrip removes every skipRange.
+	SkipRangesStrip = "strip"
+)
+
+// ChannelMerge combines the From channels into the Into channel, which may be one of them or a new channel. A channel
+// named Into that is not listed in From is merged as well, ahead of the From channels.
+//
+// A bundle in more than one merged channel keeps the upgrade edges of the earliest channel it is in, and also skips
+// the bundles that the later channels' entries replace or skip. Two distinct bundles with the same version cannot be
+// merged, and the merged upgrade graphs must end in a single head, as they do when one channel promotes the bundles of
+// another.
+type ChannelMerge struct {
+	Into string   `json:"into"`
+	From []string `json:"from"`
 }
 
+const (
+	// DefaultChannelStrategyError fails filtering, so that the new default channel is chosen in the configuration.
+	DefaultChannelStrategyError = "error"
+	// DefaultChannelStrategyHighestHeadVersion chooses the channel whose head has the highest version, and the first
+	// of those by name if several do.
+	DefaultChannelStrategyHighestHeadVersion = "highest-head-version"
+	// DefaultChannelStrategyFirstAlphabetical chooses the channel whose name sorts first.
+	DefaultChannelStrategyFirstAlphabetical = "first-alphabetical"
+)
+
+const (
+	DeriveDefaultChannelFromAnnotation = "annotation"
+
+	// DefaultDefaultChannelAnnotation is the annotation used by DeriveDefaultChannelFrom when
+	// DefaultChannelAnnotation is not set.
+	DefaultDefaultChannelAnnotation = "operators.operatorframework.io.bundle.channel.default.v1"
+)
+
 type Channel struct {
+	// Name is the channel name, or a glob pattern with path.Match syntax when it contains '*' or '?'. A pattern
+	// applies the rest of this entry to every channel of the package it matches, except channels configured by name.
 	Name         string `json:"name"`
 	VersionRange string `json:"versionRange"`
+
+	// MinVersion and MaxVersion select the bundles between two versions, both inclusive, as an alternative to
+	// VersionRange. Either can be left empty to leave that end of the range open.
+	MinVersion string `json:"minVersion"`
+	MaxVersion string `json:"maxVersion"`
+
+	// SkipRangeOverride rewrites the skipRange of the channel head after filtering. The value "auto" recomputes it
+	// to span from the lowest kept version up to the head, so that it no longer covers filtered bundles. Any other
+	// value is used as the head's skipRange verbatim.
+	SkipRangeOverride string `json:"skipRangeOverride"`
+
+	// CoversVersion keeps only the bundles needed for an installation of this version to upgrade to the channel
+	// head. It cannot be combined with VersionRange or WithinOfHead.
+	CoversVersion string `json:"coversVersion"`
+
+	// WithinOfHead keeps only the bundles within a distance of the channel head's version, written as
+	// <count>.<unit> where unit is major, minor, or patch (e.g. "2.minor"). The distance is measured from the head
+	// of the source channel, so the kept bundles follow the head as new versions ship. It cannot be combined with
+	// VersionRange or CoversVersion.
+	WithinOfHead string `json:"withinOfHead"`
+
+	// HeadOnly keeps only the channel head. It cannot be combined with VersionRange, CoversVersion, or
+	// WithinOfHead.
+	HeadOnly bool `json:"headOnly"`
+
+	// KeepLatest keeps the first this many bundles of the channel's replaces chain, starting from the head, whatever
+	// their versions, along with the bundles they skip that are among them. A channel with fewer bundles on its
+	// replaces chain keeps all of them. It cannot be combined with the other options that select bundles.
+	KeepLatest int `json:"keepLatest"`
+
+	// MatchSkipRange also selects the bundles whose skipRange covers the version of a bundle selected by VersionRange
+	// or WithinOfHead, whatever their own version, so that channels which rely on skipRange rather than replaces
+	// edges keep their upgrade paths.
+	MatchSkipRange bool `json:"matchSkipRange"`
+
+	// KeepHeadAlways keeps the channel head even when it is outside the selected bundles, along with the bundles
+	// that connect it to them, instead of choosing a new head from the selected bundles. It requires an option that
+	// selects bundles, other than CoversVersion, which always keeps the head.
+	KeepHeadAlways bool `json:"keepHeadAlways"`
+
+	// KubeVersion overrides the package's KubeVersion for this channel. It applies on top of the channel's other
+	// options.
+	KubeVersion string `json:"kubeVersion"`
+
+	// ProvidedAPIs keeps only the bundles whose olm.gvk properties provide every one of these APIs. It applies on
+	// top of the channel's other options.
+	ProvidedAPIs []GVK `json:"providedAPIs"`
+
+	// RecommendedVersion is the version of the bundle that consumers of the filtered catalog should install from this
+	// channel. That bundle is kept, with a warning if it is outside the selected bundles, and the version is
+	// recorded in a property of the channel. Filtering fails if an option that removes bundles after they are
+	// selected, such as excludeVersions, kubeVersion, providedAPIs, or deprecation exclusion, removes that bundle. It
+	// cannot be combined with CoversVersion.
+	RecommendedVersion string `json:"recommendedVersion"`
+
+	// IncludeVersions keeps the bundles with these versions even when the channel's other options do not select
+	// them, along with the bundles that connect them to the selected bundles. It cannot be combined with
+	// CoversVersion, and requires an option that selects bundles.
+	IncludeVersions []string `json:"includeVersions"`
+
+	// ExcludeVersions drops the bundles with these versions even when the channel's other options select them or
+	// they are needed to keep the channel coherent. Bundles left without an upgrade path to the channel head are
+	// dropped as well, with a warning.
+	ExcludeVersions []string `json:"excludeVersions"`
+
+	// Expression is a CEL expression that bundles must satisfy to be selected, written in terms of the
+	// ExpressionVariable, e.g. `bundle.properties.exists(p, p.type == "olm.gvk" && p.value.kind == "Memcached")`.
+	// With another option that selects bundles it narrows the selection; on its own it selects the bundles it
+	// matches. It cannot be combined with CoversVersion.
+	Expression string `json:"expression"`
+
+	// ExcludePrereleases deselects the bundles whose version has a prerelease part, such as 1.3.0-rc1. A prerelease
+	// bundle is still kept, with a warning, when the channel's upgrade graph needs it to connect the selected
+	// bundles. With another option that selects bundles it narrows the selection; on its own it selects every
+	// release. It cannot be combined with CoversVersion.
+	ExcludePrereleases bool `json:"excludePrereleases"`
+
+	// IncludePrereleases lets prerelease versions satisfy VersionRange, MinVersion, and MaxVersion when their release
+	// version does, so that ">=1.2.0" selects 1.3.0-rc1 but "<1.3.0" does not. By default, as in semver ranges
+	// generally, a prerelease version only satisfies a range that names a prerelease of the same version, e.g.
+	// ">=1.3.0-0".
+	IncludePrereleases bool `json:"includePrereleases"`
+}
+
+// VersionConstraint returns the version constraint selecting the channel's bundles: VersionRange, or the inclusive
+// range between MinVersion and MaxVersion. It is empty when neither is set.
+func (c Channel) VersionConstraint() string {
+	if c.VersionRange != "" {
+		return c.VersionRange
+	}
+	var constraints []string
+	if c.MinVersion != "" {
+		constraints = append(constraints, ">="+c.MinVersion)
+	}
+	if c.MaxVersion != "" {
+		constraints = append(constraints, "<="+c.MaxVersion)
+	}
+	return strings.Join(constraints, " ")
 }
+
+// GVK identifies an API provided by a bundle. An empty Version matches any version of the group and kind.
+type GVK struct {
+	Group   string `json:"group"`
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+}
+
+func (g GVK) String() string {
+	if g.Version == "" {
+		return fmt.Sprintf("%s, Kind=%s", g.Group, g.Kind)
+	}
+	return fmt.Sprintf("%s/%s, Kind=%s", g.Group, g.Version, g.Kind)
+}
+
+const SkipRangeOverrideAuto = "auto"
+
+// LastHeadSentinel, when used in a channel's version range, is replaced by the version of the channel head
+// recorded in the --state-file by the previous run, e.g. ">=@lastHead".
+const LastHeadSentinel = "@lastHead"
@@ -0,0 +1,24 @@
+package v1
+
+import "testing"
+
+func TestChannelVersionConstraint(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel Channel
+		want    string
+	}{
+		{name: "none", channel: Channel{}, want: ""},
+		{name: "versionRange", channel: Channel{VersionRange: ">=1.0.0 <2.0.0"}, want: ">=1.0.0 <2.0.0"},
+		{name: "minVersion", channel: Channel{MinVersion: "1.0.0"}, want: ">=1.0.0"},
+		{name: "maxVersion", channel: Channel{MaxVersion: "2.0.0"}, want: "<=2.0.0"},
+		{name: "minVersion and maxVersion", channel: Channel{MinVersion: "1.0.0", MaxVersion: "2.0.0"}, want: ">=1.0.0 <=2.0.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.channel.VersionConstraint(); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package v1
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	mmsemver "github.com/Masterminds/semver/v3"
+	"github.com/blang/semver/v4"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// ValidateTypeMeta trims surrounding whitespace from the configuration's kind and apiVersion, then checks that each
+// of them has the expected value.
+func (c *FilterConfiguration) ValidateTypeMeta() error {
+	c.Kind = strings.TrimSpace(c.Kind)
+	c.APIVersion = strings.TrimSpace(c.APIVersion)
+	var errs []error
+	if c.Kind != Kind {
+		errs = append(errs, fmt.Errorf("kind must be %q, got %q", Kind, c.Kind))
+	}
+	if c.APIVersion != APIVersion {
+		errs = append(errs, fmt.Errorf("apiVersion must be %q, got %q", APIVersion, c.APIVersion))
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// Validate reports combinations of options that contradict each other.
+func (c FilterConfiguration) Validate() error {
+	var errs []error
+	if len(c.ExcludePackages) > 0 && len(c.Packages) > 0 {
+		errs = append(errs, fmt.Errorf("packages and excludePackages cannot both be set: list either the packages to keep or the packages to remove"))
+	}
+	if len(c.ExcludePackages) > 0 && c.PackageSelector != nil {
+		errs = append(errs, fmt.Errorf("packageSelector and excludePackages cannot both be set"))
+	}
+	names, nameRegexes := sets.New[string](), sets.New[string]()
+	for _, p := range c.Packages {
+		switch {
+		case p.NameRegex != "" && nameRegexes.Has(p.NameRegex):
+			errs = append(errs, fmt.Errorf("package matching %q is configured more than once", p.NameRegex))
+		case p.NameRegex == "" && names.Has(p.Name):
+			errs = append(errs, fmt.Errorf("package %q is configured more than once", p.Name))
+		}
+		if p.NameRegex != "" {
+			nameRegexes.Insert(p.NameRegex)
+		} else {
+			names.Insert(p.Name)
+		}
+		if err := p.Validate(); err != nil {
+			if p.NameRegex != "" {
+				errs = append(errs, fmt.Errorf("package matching %q: %v", p.NameRegex, err))
+				continue
+			}
+			errs = append(errs, fmt.Errorf("package %q: %v", p.Name, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// Validate reports combinations of package and channel options that contradict each other.
+func (p Package) Validate() error {
+	var errs []error
+	if p.Name != "" && p.NameRegex != "" {
+		errs = append(errs, fmt.Errorf("name and nameRegex cannot both be set"))
+	}
+	if p.NameRegex != "" {
+		if _, err := regexp.Compile(p.NameRegex); err != nil {
+			errs = append(errs, fmt.Errorf("invalid nameRegex %q: %v", p.NameRegex, err))
+		}
+	}
+	if p.DefaultChannel != "" && p.DeriveDefaultChannelFrom != "" {
+		errs = append(errs, fmt.Errorf("defaultChannel and deriveDefaultChannelFrom cannot both be set"))
+	}
+	switch p.DefaultChannelStrategy {
+	case "", DefaultChannelStrategyError:
+	case DefaultChannelStrategyHighestHeadVersion, DefaultChannelStrategyFirstAlphabetical:
+		if p.DefaultChannel != "" || p.DeriveDefaultChannelFrom != "" {
+			errs = append(errs, fmt.Errorf("defaultChannelStrategy cannot be combined with defaultChannel or deriveDefaultChannelFrom"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("invalid defaultChannelStrategy %q: must be one of %q, %q, or %q", p.DefaultChannelStrategy, DefaultChannelStrategyError, DefaultChannelStrategyHighestHeadVersion, DefaultChannelStrategyFirstAlphabetical))
+	}
+	if p.DefaultChannelAnnotation != "" && p.DeriveDefaultChannelFrom != DeriveDefaultChannelFromAnnotation {
+		errs = append(errs, fmt.Errorf("defaultChannelAnnotation can only be set when deriveDefaultChannelFrom is %q", DeriveDefaultChannelFromAnnotation))
+	}
+	if p.SkipRanges != "" && p.SkipRanges != SkipRangesRecompute && p.SkipRanges != SkipRangesStrip {
+		errs = append(errs, fmt.Errorf("invalid skipRanges %q: must be %q or %q", p.SkipRanges, SkipRangesRecompute, SkipRangesStrip))
+	}
+	if p.KubeVersion != "" {
+		if _, err := semver.ParseTolerant(p.KubeVersion); err != nil {
+			errs = append(errs, fmt.Errorf("invalid kubeVersion %q: %v", p.KubeVersion, err))
+		}
+	}
+	for _, ch := range p.Channels {
+		if err := ch.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("channel %q: %v", ch.Name, err))
+		}
+	}
+	merged := map[string]string{}
+	for i, mc := range p.MergeChannels {
+		if mc.Into == "" {
+			errs = append(errs, fmt.Errorf("mergeChannels[%d]: into must be set", i))
+		}
+		if len(mc.From) == 0 {
+			errs = append(errs, fmt.Errorf("mergeChannels[%d]: from must list at least one channel", i))
+		}
+		for _, name := range append([]string{mc.Into}, mc.From...) {
+			if into, ok := merged[name]; ok && into != mc.Into {
+				errs = append(errs, fmt.Errorf("mergeChannels[%d]: channel %q is already merged into %q", i, name, into))
+			}
+			merged[name] = mc.Into
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// Validate reports combinations of channel options that contradict each other. At most one option selecting the
+// channel's bundles can be set.
+func (c Channel) Validate() error {
+	selectors := []struct {
+		field string
+		set   bool
+	}{
+		{"versionRange", c.VersionRange != ""},
+		{"minVersion or maxVersion", c.MinVersion != "" || c.MaxVersion != ""},
+		{"coversVersion", c.CoversVersion != ""},
+		{"withinOfHead", c.WithinOfHead != ""},
+		{"headOnly", c.HeadOnly},
+		{"keepLatest", c.KeepLatest != 0},
+	}
+	var errs []error
+	for i, a := range selectors {
+		for _, b := range selectors[i+1:] {
+			if a.set && b.set {
+				errs = append(errs, fmt.Errorf("%s and %s cannot both be set", a.field, b.field))
+			}
+		}
+	}
+	if c.KeepLatest < 0 {
+		errs = append(errs, fmt.Errorf("keepLatest must be positive, got %d", c.KeepLatest))
+	}
+	if c.VersionRange != "" {
+		// the last head is only known once the state file is read; any version checks the rest of the syntax.
+		if _, err := mmsemver.NewConstraint(strings.ReplaceAll(c.VersionRange, LastHeadSentinel, "0.0.0")); err != nil {
+			errs = append(errs, fmt.Errorf("invalid versionRange %q: %v", c.VersionRange, err))
+		}
+	}
+	var minVersion, maxVersion *semver.Version
+	for _, bound := range []struct {
+		field   string
+		value   string
+		version **semver.Version
+	}{
+		{"minVersion", c.MinVersion, &minVersion},
+		{"maxVersion", c.MaxVersion, &maxVersion},
+	} {
+		if bound.value == "" {
+			continue
+		}
+		v, err := semver.ParseTolerant(bound.value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s %q: %v", bound.field, bound.value, err))
+			continue
+		}
+		*bound.version = &v
+	}
+	if minVersion != nil && maxVersion != nil && minVersion.GT(*maxVersion) {
+		errs = append(errs, fmt.Errorf("minVersion %s is greater than maxVersion %s", minVersion, maxVersion))
+	}
+	if c.MatchSkipRange && c.VersionConstraint() == "" && c.WithinOfHead == "" {
+		errs = append(errs, fmt.Errorf("matchSkipRange can only be set with versionRange, minVersion, maxVersion, or withinOfHead"))
+	}
+	if c.KubeVersion != "" {
+		if _, err := semver.ParseTolerant(c.KubeVersion); err != nil {
+			errs = append(errs, fmt.Errorf("invalid kubeVersion %q: %v", c.KubeVersion, err))
+		}
+	}
+	if c.RecommendedVersion != "" {
+		if _, err := semver.ParseTolerant(c.RecommendedVersion); err != nil {
+			errs = append(errs, fmt.Errorf("invalid recommendedVersion %q: %v", c.RecommendedVersion, err))
+		}
+		if c.CoversVersion != "" {
+			errs = append(errs, fmt.Errorf("recommendedVersion and coversVersion cannot both be set"))
+		}
+	}
+	// includeVersions and keepHeadAlways extend the bundles selected by one of these options, and have nothing to
+	// extend without one.
+	selected := c.CoversVersion == "" && (c.VersionConstraint() != "" || c.WithinOfHead != "" || c.HeadOnly || c.KeepLatest != 0 || c.Expression != "" || c.ExcludePrereleases)
+	if len(c.IncludeVersions) > 0 && !selected {
+		errs = append(errs, fmt.Errorf("includeVersions can only be set with versionRange, minVersion, maxVersion, withinOfHead, headOnly, keepLatest, expression, or excludePrereleases"))
+	}
+	if c.KeepHeadAlways && !selected {
+		errs = append(errs, fmt.Errorf("keepHeadAlways can only be set with versionRange, minVersion, maxVersion, withinOfHead, headOnly, keepLatest, expression, or excludePrereleases"))
+	}
+	if c.IncludePrereleases && c.VersionConstraint() == "" {
+		errs = append(errs, fmt.Errorf("includePrereleases can only be set with versionRange, minVersion, or maxVersion"))
+	}
+	if c.IncludePrereleases && c.ExcludePrereleases {
+		errs = append(errs, fmt.Errorf("includePrereleases and excludePrereleases cannot both be set"))
+	}
+	if c.ExcludePrereleases && c.CoversVersion != "" {
+		errs = append(errs, fmt.Errorf("excludePrereleases and coversVersion cannot both be set"))
+	}
+	if c.Expression != "" {
+		if _, err := CompileExpression(c.Expression); err != nil {
+			errs = append(errs, fmt.Errorf("invalid expression %q: %v", c.Expression, err))
+		}
+		if c.CoversVersion != "" {
+			errs = append(errs, fmt.Errorf("expression and coversVersion cannot both be set"))
+		}
+	}
+	excluded := map[string]bool{}
+	for _, version := range c.ExcludeVersions {
+		v, err := semver.ParseTolerant(version)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid excludeVersions entry %q: %v", version, err))
+			continue
+		}
+		excluded[v.String()] = true
+	}
+	for _, version := range c.IncludeVersions {
+		v, err := semver.ParseTolerant(version)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid includeVersions entry %q: %v", version, err))
+			continue
+		}
+		if excluded[v.String()] {
+			errs = append(errs, fmt.Errorf("version %s cannot be both included and excluded", v))
+		}
+	}
+	if v, err := semver.ParseTolerant(c.RecommendedVersion); err == nil && excluded[v.String()] {
+		errs = append(errs, fmt.Errorf("recommended version %s cannot be excluded", v))
+	}
+	for i, api := range c.ProvidedAPIs {
+		if api.Kind == "" {
+			errs = append(errs, fmt.Errorf("providedAPIs[%d]: kind must be set", i))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
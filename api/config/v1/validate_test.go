@@ -0,0 +1,229 @@
+package v1
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFilterConfigurationValidateTypeMeta(t *testing.T) {
+	tests := []struct {
+		name       string
+		kind       string
+		apiVersion string
+		// wantErr is the whole error text, since both fields are reported together.
+		wantErr string
+	}{
+		{name: "valid", kind: Kind, apiVersion: APIVersion},
+		{name: "surrounding whitespace", kind: " " + Kind + "\n", apiVersion: "\t" + APIVersion + " "},
+		{
+			name:       "bad kind",
+			kind:       "Catalog",
+			apiVersion: APIVersion,
+			wantErr:    `kind must be "FilterConfiguration", got "Catalog"`,
+		},
+		{
+			name:       "bad apiVersion",
+			kind:       Kind,
+			apiVersion: "olm.operatorframework.io/v2",
+			wantErr:    `apiVersion must be "olm.operatorframework.io/v1", got "olm.operatorframework.io/v2"`,
+		},
+		{
+			name:    "both bad",
+			kind:    " Catalog ",
+			wantErr: `[kind must be "FilterConfiguration", got "Catalog", apiVersion must be "olm.operatorframework.io/v1", got ""]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := FilterConfiguration{}
+			config.Kind, config.APIVersion = tt.kind, tt.apiVersion
+			err := config.ValidateTypeMeta()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if config.Kind != Kind || config.APIVersion != APIVersion {
+					t.Errorf("expected kind and apiVersion to be trimmed, got %q and %q", config.Kind, config.APIVersion)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestFilterConfigurationValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  FilterConfiguration
+		wantErr string
+	}{
+		{
+			name:   "distinct packages",
+			config: FilterConfiguration{Packages: []Package{{Name: "foo"}, {Name: "bar"}, {NameRegex: "^foo"}, {NameRegex: "^bar"}}},
+		},
+		{
+			name:    "duplicate name",
+			config:  FilterConfiguration{Packages: []Package{{Name: "foo"}, {Name: "bar"}, {Name: "foo"}}},
+			wantErr: `package "foo" is configured more than once`,
+		},
+		{
+			name:    "duplicate nameRegex",
+			config:  FilterConfiguration{Packages: []Package{{NameRegex: "^foo"}, {NameRegex: "^foo"}}},
+			wantErr: `package matching "^foo" is configured more than once`,
+		},
+		{
+			name:    "packages and excludePackages",
+			config:  FilterConfiguration{Packages: []Package{{Name: "foo"}}, ExcludePackages: []string{"bar"}},
+			wantErr: "packages and excludePackages cannot both be set",
+		},
+		{
+			name: "every invalid versionRange",
+			config: FilterConfiguration{Packages: []Package{
+				{Name: "foo", Channels: []Channel{{Name: "stable", VersionRange: ">=one"}, {Name: "fast", VersionRange: ">=1.0.0"}, {Name: "beta", VersionRange: "<<2"}}},
+				{Name: "bar", Channels: []Channel{{Name: "stable", VersionRange: "1.x || ~"}}},
+			}},
+			wantErr: `[package "foo": [channel "stable": invalid versionRange ">=one": improper constraint: >=one, ` +
+				`channel "beta": invalid versionRange "<<2": improper constraint: <<2], ` +
+				`package "bar": channel "stable": invalid versionRange "1.x || ~": improper constraint:  ~]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestChannelValidateSelectorPairs(t *testing.T) {
+	selectors := []struct {
+		field   string
+		channel Channel
+	}{
+		{"versionRange", Channel{VersionRange: ">=1.0.0"}},
+		{"minVersion or maxVersion", Channel{MinVersion: "1.0.0"}},
+		{"minVersion or maxVersion", Channel{MaxVersion: "2.0.0"}},
+		{"coversVersion", Channel{CoversVersion: "1.0.0"}},
+		{"withinOfHead", Channel{WithinOfHead: "2.minor"}},
+		{"headOnly", Channel{HeadOnly: true}},
+		{"keepLatest", Channel{KeepLatest: 2}},
+	}
+	for i, a := range selectors {
+		if err := a.channel.Validate(); err != nil {
+			t.Errorf("%s alone: unexpected error: %v", a.field, err)
+		}
+		for _, b := range selectors[i+1:] {
+			if a.field == b.field {
+				continue
+			}
+			c := a.channel
+			c.VersionRange += b.channel.VersionRange
+			c.MinVersion += b.channel.MinVersion
+			c.MaxVersion += b.channel.MaxVersion
+			c.CoversVersion += b.channel.CoversVersion
+			c.WithinOfHead += b.channel.WithinOfHead
+			c.HeadOnly = c.HeadOnly || b.channel.HeadOnly
+			c.KeepLatest += b.channel.KeepLatest
+
+			want := fmt.Sprintf("%s and %s cannot both be set", a.field, b.field)
+			if err := c.Validate(); err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("%+v: expected error containing %q, got %v", c, want, err)
+			}
+		}
+	}
+}
+
+func TestChannelValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel Channel
+		wantErr string
+	}{
+		{name: "min and max", channel: Channel{MinVersion: "1.0.0", MaxVersion: "2.0.0"}},
+		{name: "min above max", channel: Channel{MinVersion: "2.0.0", MaxVersion: "1.0.0"}, wantErr: "minVersion 2.0.0 is greater than maxVersion 1.0.0"},
+		{name: "negative keepLatest", channel: Channel{KeepLatest: -1}, wantErr: "keepLatest must be positive, got -1"},
+		{name: "invalid versionRange", channel: Channel{VersionRange: ">=one"}, wantErr: `invalid versionRange ">=one"`},
+		{name: "versionRange with the last head", channel: Channel{VersionRange: ">" + LastHeadSentinel}},
+		{name: "matchSkipRange without a range", channel: Channel{MatchSkipRange: true}, wantErr: "matchSkipRange can only be set with"},
+		{name: "matchSkipRange with withinOfHead", channel: Channel{WithinOfHead: "1.minor", MatchSkipRange: true}},
+		{name: "recommendedVersion and coversVersion", channel: Channel{RecommendedVersion: "1.0.0", CoversVersion: "1.0.0"}, wantErr: "recommendedVersion and coversVersion cannot both be set"},
+		{name: "includeVersions without a selector", channel: Channel{IncludeVersions: []string{"1.0.0"}}, wantErr: "includeVersions can only be set with"},
+		{name: "includeVersions with coversVersion", channel: Channel{CoversVersion: "1.0.0", IncludeVersions: []string{"1.0.0"}}, wantErr: "includeVersions can only be set with"},
+		{name: "includeVersions with headOnly", channel: Channel{HeadOnly: true, IncludeVersions: []string{"1.0.0"}}},
+		{name: "keepHeadAlways without a selector", channel: Channel{KeepHeadAlways: true}, wantErr: "keepHeadAlways can only be set with"},
+		{name: "keepHeadAlways with an expression", channel: Channel{Expression: `bundle.version != ""`, KeepHeadAlways: true}},
+		{name: "includePrereleases without a range", channel: Channel{IncludePrereleases: true}, wantErr: "includePrereleases can only be set with"},
+		{name: "includePrereleases and excludePrereleases", channel: Channel{MinVersion: "1.0.0", IncludePrereleases: true, ExcludePrereleases: true}, wantErr: "includePrereleases and excludePrereleases cannot both be set"},
+		{name: "excludePrereleases and coversVersion", channel: Channel{CoversVersion: "1.0.0", ExcludePrereleases: true}, wantErr: "excludePrereleases and coversVersion cannot both be set"},
+		{name: "expression and coversVersion", channel: Channel{CoversVersion: "1.0.0", Expression: `bundle.version != ""`}, wantErr: "expression and coversVersion cannot both be set"},
+		{name: "invalid expression", channel: Channel{Expression: "bundle.version +"}, wantErr: "invalid expression"},
+		{name: "included and excluded", channel: Channel{HeadOnly: true, IncludeVersions: []string{"v1.0"}, ExcludeVersions: []string{"1.0.0"}}, wantErr: "version 1.0.0 cannot be both included and excluded"},
+		{name: "recommended and excluded", channel: Channel{RecommendedVersion: "1.0.0", ExcludeVersions: []string{"1.0.0"}}, wantErr: "recommended version 1.0.0 cannot be excluded"},
+		{name: "invalid excludeVersions", channel: Channel{ExcludeVersions: []string{"latest"}}, wantErr: `invalid excludeVersions entry "latest"`},
+		{name: "invalid kubeVersion", channel: Channel{KubeVersion: "new"}, wantErr: `invalid kubeVersion "new"`},
+		{name: "providedAPIs without a kind", channel: Channel{ProvidedAPIs: []GVK{{Group: "example.com"}}}, wantErr: "providedAPIs[0]: kind must be set"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.channel.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestPackageValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		pkg     Package
+		wantErr string
+	}{
+		{name: "name and nameRegex", pkg: Package{Name: "foo", NameRegex: "^foo"}, wantErr: "name and nameRegex cannot both be set"},
+		{name: "invalid nameRegex", pkg: Package{NameRegex: "("}, wantErr: `invalid nameRegex "("`},
+		{name: "defaultChannel and deriveDefaultChannelFrom", pkg: Package{Name: "foo", DefaultChannel: "stable", DeriveDefaultChannelFrom: DeriveDefaultChannelFromAnnotation}, wantErr: "defaultChannel and deriveDefaultChannelFrom cannot both be set"},
+		{name: "defaultChannelStrategy and defaultChannel", pkg: Package{Name: "foo", DefaultChannel: "stable", DefaultChannelStrategy: DefaultChannelStrategyFirstAlphabetical}, wantErr: "defaultChannelStrategy cannot be combined with defaultChannel or deriveDefaultChannelFrom"},
+		{name: "defaultChannelStrategy error with defaultChannel", pkg: Package{Name: "foo", DefaultChannel: "stable", DefaultChannelStrategy: DefaultChannelStrategyError}},
+		{name: "invalid defaultChannelStrategy", pkg: Package{Name: "foo", DefaultChannelStrategy: "newest"}, wantErr: `invalid defaultChannelStrategy "newest"`},
+		{name: "defaultChannelAnnotation without annotation", pkg: Package{Name: "foo", DefaultChannelAnnotation: "example.com/default"}, wantErr: "defaultChannelAnnotation can only be set when deriveDefaultChannelFrom is"},
+		{name: "defaultChannelAnnotation with annotation", pkg: Package{Name: "foo", DeriveDefaultChannelFrom: DeriveDefaultChannelFromAnnotation, DefaultChannelAnnotation: "example.com/default"}},
+		{name: "invalid skipRanges", pkg: Package{Name: "foo", SkipRanges: "drop"}, wantErr: `invalid skipRanges "drop"`},
+		{name: "invalid kubeVersion", pkg: Package{Name: "foo", KubeVersion: "new"}, wantErr: `invalid kubeVersion "new"`},
+		{name: "invalid channel", pkg: Package{Name: "foo", Channels: []Channel{{Name: "stable", HeadOnly: true, KeepLatest: 1}}}, wantErr: `channel "stable": headOnly and keepLatest cannot both be set`},
+		{name: "merge without into", pkg: Package{Name: "foo", MergeChannels: []ChannelMerge{{From: []string{"fast"}}}}, wantErr: "mergeChannels[0]: into must be set"},
+		{name: "merge without from", pkg: Package{Name: "foo", MergeChannels: []ChannelMerge{{Into: "stable"}}}, wantErr: "mergeChannels[0]: from must list at least one channel"},
+		{name: "channel merged twice", pkg: Package{Name: "foo", MergeChannels: []ChannelMerge{{Into: "stable", From: []string{"fast"}}, {Into: "candidate", From: []string{"fast"}}}}, wantErr: `mergeChannels[1]: channel "fast" is already merged into "stable"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.pkg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
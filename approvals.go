@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/operator-framework/operator-registry/alpha/model"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/yaml"
+)
+
+// loadApprovals reads an approvals manifest, which is a YAML or JSON list of approved bundle image digests.
+// Signatures are not verified here: a signed manifest's detached signature must be checked before it is passed in.
+func loadApprovals(path string) (sets.Set[string], error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []string
+	if err := yaml.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parse approvals: %v", err)
+	}
+	approved := sets.New[string]()
+	for i, e := range entries {
+		d, err := digest.Parse(e)
+		if err != nil {
+			return nil, fmt.Errorf("approvals entry %d: invalid digest %q: %v", i, e, err)
+		}
+		approved.Insert(d.String())
+	}
+	return approved, nil
+}
+
+// unapprovedBundles describes each kept bundle whose image is not pinned to one of the approved digests, sorted by
+// package and bundle name.
+func unapprovedBundles(m model.Model, approved sets.Set[string]) []string {
+	var unapproved []string
+	for _, pkgName := range sets.List(sets.KeySet(m)) {
+		bundles := map[string]*model.Bundle{}
+		for _, ch := range m[pkgName].Channels {
+			for _, b := range ch.Bundles {
+				bundles[b.Name] = b
+			}
+		}
+		for _, bName := range sets.List(sets.KeySet(bundles)) {
+			b := bundles[bName]
+			d := imageDigest(b.Image)
+			switch {
+			case d == "":
+				unapproved = append(unapproved, fmt.Sprintf("bundle %q in package %q is not approved: its image %q is not pinned by digest", b.Name, pkgName, b.Image))
+			case !approved.Has(d):
+				unapproved = append(unapproved, fmt.Sprintf("bundle %q in package %q is not approved: digest %s is not in the approvals list", b.Name, pkgName, d))
+			}
+		}
+	}
+	return unapproved
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const (
+	approvedDigest   = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	unapprovedDigest = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+)
+
+func TestLoadApprovals(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []string
+		wantErr string
+	}{
+		{name: "yaml", data: "- " + approvedDigest + "\n- " + unapprovedDigest + "\n", want: []string{approvedDigest, unapprovedDigest}},
+		{name: "json", data: `["` + approvedDigest + `"]`, want: []string{approvedDigest}},
+		{name: "invalid digest", data: "- " + approvedDigest + "\n- sha256:abc\n", wantErr: `approvals entry 1: invalid digest "sha256:abc"`},
+		{name: "not a list", data: "digest: " + approvedDigest + "\n", wantErr: "parse approvals"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "approvals.yaml")
+			if err := os.WriteFile(path, []byte(tt.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := loadApprovals(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(sets.List(got), tt.want) {
+				t.Errorf("expected approvals %v, got %v", tt.want, sets.List(got))
+			}
+		})
+	}
+}
+
+func TestUnapprovedBundles(t *testing.T) {
+	bundle := func(version, image string) declcfg.Bundle {
+		return declcfg.Bundle{
+			Schema:     declcfg.SchemaBundle,
+			Name:       "foo.v" + version,
+			Package:    "foo",
+			Image:      image,
+			Properties: []property.Property{property.MustBuildPackage("foo", version)},
+		}
+	}
+	// foo.v1.0.0 is in both channels, so that it is only reported once.
+	m, err := declcfg.ConvertToModel(declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{{Schema: declcfg.SchemaPackage, Name: "foo", DefaultChannel: "stable"}},
+		Channels: []declcfg.Channel{
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "stable", Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v1.0.0"},
+				{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+				{Name: "foo.v1.2.0", Replaces: "foo.v1.1.0"},
+			}},
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "fast", Entries: []declcfg.ChannelEntry{{Name: "foo.v1.0.0"}}},
+		},
+		Bundles: []declcfg.Bundle{
+			bundle("1.0.0", "example.com/foo@"+unapprovedDigest),
+			bundle("1.1.0", "example.com/foo@"+approvedDigest),
+			bundle("1.2.0", "example.com/foo:v1.2.0"),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`bundle "foo.v1.0.0" in package "foo" is not approved: digest ` + unapprovedDigest + ` is not in the approvals list`,
+		`bundle "foo.v1.2.0" in package "foo" is not approved: its image "example.com/foo:v1.2.0" is not pinned by digest`,
+	}
+	if got := unapprovedBundles(m, sets.New(approvedDigest)); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected unapproved bundles %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/operator-framework/operator-registry/alpha/action"
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/model"
+	"sigs.k8s.io/yaml"
+
+	v1 "fbc-filter/api/config/v1"
+	"fbc-filter/pkg/filter"
+)
+
+// loadConfig reads, expands, parses, and validates a configuration file.
+func loadConfig(ctx context.Context, location string) (v1.FilterConfiguration, error) {
+	var config v1.FilterConfiguration
+	configData, err := readConfigData(ctx, location, nil, 30*time.Second, false)
+	if err != nil {
+		return config, fmt.Errorf("error reading configuration file: %v", err)
+	}
+	configData, err = expandConfigTemplate(configData)
+	if err != nil {
+		return config, fmt.Errorf("error expanding configuration file: %v", err)
+	}
+	if err := yaml.Unmarshal(configData, &config); err != nil {
+		return config, fmt.Errorf("error parsing configuration file: %v", err)
+	}
+	if err := config.ValidateTypeMeta(); err != nil {
+		return config, fmt.Errorf("invalid configuration file: %v", err)
+	}
+	if err := config.Validate(); err != nil {
+		return config, fmt.Errorf("invalid configuration file: %v", err)
+	}
+	return config, nil
+}
+
+// stdinRef is the catalog reference that reads an already-rendered stream of catalog objects from stdin instead of
+// rendering a catalog. --migrate is still applied to it.
+const stdinRef = "-"
+
+// renderAndFilter renders the catalog references into a model and, when configFile is set, filters it with the
+// configuration using the default filter options. It is used by the subcommands that inspect a filtered catalog.
+func renderAndFilter(ctx context.Context, refs []string, configFile string, warnf func(filter.Warning)) (model.Model, error) {
+	var (
+		fbc *declcfg.DeclarativeConfig
+		err error
+	)
+	if len(refs) == 1 && refs[0] == stdinRef {
+		if fbc, err = declcfg.LoadReader(os.Stdin); err != nil {
+			return nil, fmt.Errorf("error reading input from stdin: %v", err)
+		}
+	} else {
+		r := action.Render{
+			Refs:           refs,
+			AllowedRefMask: action.RefDCDir | action.RefDCImage | action.RefSqliteFile | action.RefSqliteImage,
+		}
+		if fbc, err = r.Run(ctx); err != nil {
+			return nil, fmt.Errorf("error rendering input: %v", err)
+		}
+	}
+
+	if configFile == "" {
+		m, err := convertToModel(*fbc)
+		if err != nil {
+			return nil, fmt.Errorf("error converting input: %v", err)
+		}
+		return m, nil
+	}
+	config, err := loadConfig(ctx, configFile)
+	if err != nil {
+		return nil, err
+	}
+	return applyConfig(fbc, config, warnf)
+}
+
+// applyConfig filters a rendered catalog with the configuration using the default filter options.
+func applyConfig(fbc *declcfg.DeclarativeConfig, config v1.FilterConfiguration, warnf func(filter.Warning)) (model.Model, error) {
+	if err := filter.ExpandNameRegexes(&config, packageNames(fbc)); err != nil {
+		return nil, fmt.Errorf("invalid configuration file: %v", err)
+	}
+	if err := selectPackages(fbc, &config); err != nil {
+		return nil, fmt.Errorf("invalid configuration file: %v", err)
+	}
+	m, err := convertToModel(*fbc)
+	if err != nil {
+		return nil, fmt.Errorf("error converting input: %v", err)
+	}
+	excludeChannels, err := filter.DefaultExcludeChannelRegex(config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration file: %v", err)
+	}
+	if _, err := filter.FilterModel(m, config, filter.WithWarnings(warnf), filter.WithExcludeChannels(excludeChannels)); err != nil {
+		return nil, fmt.Errorf("error filtering input: %v", err)
+	}
+	return m, nil
+}
+
+// filteredCatalog converts a filtered model back into a catalog. The model does not round-trip deprecations or objects
+// with other schemas, so the ones of the kept packages are carried over from the input.
+func filteredCatalog(m model.Model, deprecations []declcfg.Deprecation, others []declcfg.Meta) declcfg.DeclarativeConfig {
+	fbc := declcfg.ConvertFromModel(m)
+	fbc.Deprecations = filter.RetainedDeprecations(deprecations, m)
+	fbc.Others = filter.RetainedOthers(others, m)
+	return fbc
+}
+
+func packageNames(fbc *declcfg.DeclarativeConfig) []string {
+	names := make([]string, 0, len(fbc.Packages))
+	for _, p := range fbc.Packages {
+		names = append(names, p.Name)
+	}
+	return names
+}
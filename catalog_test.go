@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	v1 "fbc-filter/api/config/v1"
+	"fbc-filter/pkg/filter"
+)
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{name: "valid", data: testConfig},
+		{name: "template", data: testConfig + "- name: {{ \"bar\" }}\n"},
+		{name: "invalid template", data: "packages: {{ undefined }}", wantErr: "error expanding configuration file"},
+		{name: "not yaml", data: "packages: [", wantErr: "error parsing configuration file"},
+		{name: "wrong kind", data: strings.Replace(testConfig, "FilterConfiguration", "Catalog", 1), wantErr: "invalid configuration file: kind must be"},
+		{name: "invalid", data: testConfig + "  channels:\n  - name: stable\n    headOnly: true\n    keepLatest: 2\n", wantErr: `invalid configuration file: package "foo": channel "stable": headOnly and keepLatest cannot both be set`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := loadConfig(context.Background(), path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	if _, err := loadConfig(context.Background(), filepath.Join(t.TempDir(), "missing.yaml")); err == nil || !strings.Contains(err.Error(), "error reading configuration file") {
+		t.Errorf("expected error containing %q, got %v", "error reading configuration file", err)
+	}
+}
+
+func TestApplyConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		packages []v1.Package
+		modify   func(*declcfg.DeclarativeConfig)
+		want     []string
+		wantErr  string
+	}{
+		{name: "named package", packages: []v1.Package{{Name: "bar"}}, want: []string{"bar"}},
+		{name: "name regex", packages: []v1.Package{{NameRegex: "^f"}}, want: []string{"foo"}},
+		{
+			name:     "invalid input",
+			packages: []v1.Package{{Name: "foo"}},
+			modify:   func(fbc *declcfg.DeclarativeConfig) { fbc.Bundles[0].Properties = nil },
+			wantErr:  "error converting input: rendered input is not a valid catalog",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fbc := outputCatalog()
+			if tt.modify != nil {
+				tt.modify(&fbc)
+			}
+			config := v1.FilterConfiguration{
+				TypeMeta: metav1.TypeMeta{Kind: v1.Kind, APIVersion: v1.APIVersion},
+				Packages: tt.packages,
+			}
+			m, err := applyConfig(&fbc, config, func(filter.Warning) {})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := sets.List(sets.KeySet(m)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected packages %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
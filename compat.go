@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+
+	mmsemver "github.com/Masterminds/semver/v3"
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+
+	"fbc-filter/pkg/filter"
+)
+
+// olmFeature is a catalog feature that is only understood by OLM starting at minVersion.
+type olmFeature struct {
+	description string
+	minVersion  *mmsemver.Version
+	usedBy      func(declcfg.DeclarativeConfig) bool
+}
+
+var olmFeatures = []olmFeature{
+	{
+		description: fmt.Sprintf("%s bundle properties", property.TypeCSVMetadata),
+		minVersion:  mmsemver.MustParse("0.24.0"),
+		usedBy: func(fbc declcfg.DeclarativeConfig) bool {
+			for _, b := range fbc.Bundles {
+				if hasPropertyType(b.Properties, property.TypeCSVMetadata) {
+					return true
+				}
+			}
+			return false
+		},
+	},
+	{
+		description: fmt.Sprintf("%s objects", declcfg.SchemaDeprecation),
+		minVersion:  mmsemver.MustParse("0.27.0"),
+		usedBy: func(fbc declcfg.DeclarativeConfig) bool {
+			return len(fbc.Deprecations) > 0
+		},
+	},
+}
+
+func parseTargetOLMVersion(targetOLMVersion string) (*mmsemver.Version, error) {
+	if targetOLMVersion == "" {
+		return nil, nil
+	}
+	v, err := mmsemver.NewVersion(targetOLMVersion)
+	if err != nil {
+		return nil, fmt.Errorf("invalid targetOLMVersion %q: %v", targetOLMVersion, err)
+	}
+	return v, nil
+}
+
+// checkOLMCompatibility warns about each feature used by the filtered catalog that is not supported by the
+// configured target OLM version.
+func checkOLMCompatibility(fbc declcfg.DeclarativeConfig, targetOLMVersion string, warnf func(filter.Warning)) error {
+	target, err := parseTargetOLMVersion(targetOLMVersion)
+	if err != nil || target == nil {
+		return err
+	}
+	for _, f := range olmFeatures {
+		if target.LessThan(f.minVersion) && f.usedBy(fbc) {
+			warnf(filter.Warning{
+				Code:    filter.WarningOLMFeatureUnsupported,
+				Message: fmt.Sprintf("output contains %s, which require OLM %s or later, but the target OLM version is %s", f.description, f.minVersion, target),
+			})
+		}
+	}
+	return nil
+}
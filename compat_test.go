@@ -0,0 +1,76 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+
+	"fbc-filter/pkg/filter"
+)
+
+func TestCheckOLMCompatibility(t *testing.T) {
+	// fbc uses both olm.csv.metadata bundle properties (OLM 0.24.0) and olm.deprecations objects (OLM 0.27.0).
+	fbc := declcfg.DeclarativeConfig{
+		Bundles: []declcfg.Bundle{{
+			Schema:     declcfg.SchemaBundle,
+			Name:       "foo.v1.0.0",
+			Package:    "foo",
+			Properties: []property.Property{property.MustBuildCSVMetadata(v1alpha1.ClusterServiceVersion{})},
+		}},
+		Deprecations: []declcfg.Deprecation{{Schema: declcfg.SchemaDeprecation, Package: "foo"}},
+	}
+	tests := []struct {
+		name    string
+		fbc     declcfg.DeclarativeConfig
+		target  string
+		want    []string
+		wantErr string
+	}{
+		{name: "no target", fbc: fbc},
+		{name: "supported", fbc: fbc, target: "0.27.0"},
+		{
+			name:   "deprecations unsupported",
+			fbc:    fbc,
+			target: "v0.25.1",
+			want:   []string{"output contains olm.deprecations objects, which require OLM 0.27.0 or later, but the target OLM version is 0.25.1"},
+		},
+		{
+			name:   "both unsupported",
+			fbc:    fbc,
+			target: "0.22.0",
+			want: []string{
+				"output contains olm.csv.metadata bundle properties, which require OLM 0.24.0 or later, but the target OLM version is 0.22.0",
+				"output contains olm.deprecations objects, which require OLM 0.27.0 or later, but the target OLM version is 0.22.0",
+			},
+		},
+		{name: "features not used", target: "0.22.0"},
+		{name: "invalid target", fbc: fbc, target: "latest", wantErr: `invalid targetOLMVersion "latest"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			err := checkOLMCompatibility(tt.fbc, tt.target, func(w filter.Warning) {
+				if w.Code != filter.WarningOLMFeatureUnsupported {
+					t.Errorf("expected warning code %q, got %q", filter.WarningOLMFeatureUnsupported, w.Code)
+				}
+				got = append(got, w.Message)
+			})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected warnings %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
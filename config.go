@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// readConfigData reads a configuration file from a local path or, when location is an http:// or https:// URL,
+// fetches it with a GET request that carries the given headers (each in "Name: value" form) and is bounded by
+// timeout. Because headers usually carry credentials, they are only sent over plain HTTP, including after a
+// redirect, when allowInsecure is set.
+func readConfigData(ctx context.Context, location string, headers []string, timeout time.Duration, allowInsecure bool) ([]byte, error) {
+	if !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		return os.ReadFile(location)
+	}
+	insecureHeaders := func(scheme string) error {
+		if len(headers) > 0 && scheme != "https" && !allowInsecure {
+			return fmt.Errorf("refusing to send --config-header over plain HTTP to %s (use an https URL, or --allow-insecure-credentials)", location)
+		}
+		return nil
+	}
+	if err := insecureHeaders(strings.SplitN(location, ":", 2)[0]); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range headers {
+		name, value, ok := strings.Cut(h, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid header %q: must have the form \"Name: value\"", h)
+		}
+		req.Header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if err := insecureHeaders(req.URL.Scheme); err != nil {
+				return fmt.Errorf("redirected to %s: %v", req.URL.Redacted(), err)
+			}
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after 10 redirects")
+			}
+			return nil
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %v", location, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("fetch %s: unexpected response status %q", location, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %v", location, err)
+	}
+	return data, nil
+}
+
+// configTemplateFuncs are the only functions, besides the text/template builtins, that a configuration file can
+// call. They give access to the environment and nothing else.
+var configTemplateFuncs = template.FuncMap{
+	// env returns the value of an environment variable, or "" if it is unset.
+	"env": os.Getenv,
+	// default returns value, or def if value is empty, e.g. {{ env "MIN_VERSION" | default "1.0.0" }}.
+	"default": func(def, value string) string {
+		if value == "" {
+			return def
+		}
+		return value
+	},
+}
+
+// expandConfigTemplate expands Go template actions in a configuration file, such as {{ env "MIN_VERSION" }},
+// before the file is parsed.
+func expandConfigTemplate(data []byte) ([]byte, error) {
+	if !bytes.Contains(data, []byte("{{")) {
+		return data, nil
+	}
+	tmpl, err := template.New("config").Funcs(configTemplateFuncs).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, nil); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
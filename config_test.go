@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testConfig = `apiVersion: olm.operatorframework.io/v1
+kind: FilterConfiguration
+packages:
+- name: foo
+`
+
+func TestReadConfigData(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/config.yaml":
+			w.Write([]byte(testConfig))
+		case "/private.yaml":
+			if r.Header.Get("Authorization") != "Bearer token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(testConfig))
+		case "/slow.yaml":
+			time.Sleep(200 * time.Millisecond)
+			w.Write([]byte(testConfig))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	local := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(local, []byte(testConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		location      string
+		headers       []string
+		timeout       time.Duration
+		allowInsecure bool
+		wantErr       string
+	}{
+		{name: "local file", location: local},
+		{name: "url", location: srv.URL + "/config.yaml"},
+		{name: "headers over http are refused", location: srv.URL + "/private.yaml", headers: []string{"Authorization: Bearer token"}, wantErr: "refusing to send --config-header over plain HTTP"},
+		{name: "headers over http when allowed", location: srv.URL + "/private.yaml", headers: []string{"Authorization: Bearer token"}, allowInsecure: true},
+		{name: "missing header", location: srv.URL + "/private.yaml", wantErr: `unexpected response status "401 Unauthorized"`},
+		{name: "invalid header", location: srv.URL + "/config.yaml", headers: []string{"Authorization"}, allowInsecure: true, wantErr: `invalid header "Authorization"`},
+		{name: "not found", location: srv.URL + "/missing.yaml", wantErr: `unexpected response status "404 Not Found"`},
+		{name: "timeout", location: srv.URL + "/slow.yaml", timeout: 50 * time.Millisecond, wantErr: "context deadline exceeded"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeout := tt.timeout
+			if timeout == 0 {
+				timeout = 5 * time.Second
+			}
+			data, err := readConfigData(context.Background(), tt.location, tt.headers, timeout, tt.allowInsecure)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(data) != testConfig {
+				t.Errorf("unexpected configuration %q", data)
+			}
+		})
+	}
+}
+
+func TestReadConfigDataRefusesRedirectToHTTP(t *testing.T) {
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testConfig))
+	}))
+	defer plain.Close()
+	secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, plain.URL+"/config.yaml", http.StatusFound)
+	}))
+	defer secure.Close()
+
+	// the test server's certificate is only trusted by its own client.
+	defaultTransport := http.DefaultTransport
+	http.DefaultTransport = secure.Client().Transport
+	defer func() { http.DefaultTransport = defaultTransport }()
+
+	_, err := readConfigData(context.Background(), secure.URL+"/config.yaml", []string{"Authorization: Bearer token"}, 5*time.Second, false)
+	if err == nil || !strings.Contains(err.Error(), "redirected to "+plain.URL) {
+		t.Fatalf("expected the redirect to plain HTTP to be refused, got %v", err)
+	}
+	if _, err := readConfigData(context.Background(), secure.URL+"/config.yaml", nil, 5*time.Second, false); err != nil {
+		t.Fatalf("expected a redirect without headers to be followed, got %v", err)
+	}
+}
+
+func TestExpandConfigTemplate(t *testing.T) {
+	t.Setenv("FBC_FILTER_TEST_MIN", "1.2.0")
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{name: "no template", in: "minVersion: 1.0.0", want: "minVersion: 1.0.0"},
+		{name: "env", in: `minVersion: {{ env "FBC_FILTER_TEST_MIN" }}`, want: "minVersion: 1.2.0"},
+		{name: "default", in: `minVersion: {{ env "FBC_FILTER_TEST_UNSET" | default "0.1.0" }}`, want: "minVersion: 0.1.0"},
+		{name: "unknown function", in: `minVersion: {{ exec "true" }}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandConfigTemplate([]byte(tt.in))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
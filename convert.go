@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/model"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// convertToModel converts the rendered input to a model. A failure means the input is not a valid catalog, so the
+// error says so and, when the problem is confined to one package, names that package.
+func convertToModel(fbc declcfg.DeclarativeConfig) (model.Model, error) {
+	m, err := declcfg.ConvertToModel(fbc)
+	if err == nil {
+		return m, nil
+	}
+	// the error from the model often names the package already.
+	if pkg := invalidPackage(fbc); pkg != "" && !strings.Contains(err.Error(), fmt.Sprintf("package %q", pkg)) {
+		return nil, fmt.Errorf("rendered input is not a valid catalog: package %q: %v", pkg, err)
+	}
+	return nil, fmt.Errorf("rendered input is not a valid catalog: %v", err)
+}
+
+// invalidPackage converts the objects of each package of the catalog on their own, and returns the name of the
+// package that fails to convert. It returns "" unless exactly one package fails, since objects that reference the
+// wrong package make several packages fail.
+func invalidPackage(fbc declcfg.DeclarativeConfig) string {
+	parts := map[string]*declcfg.DeclarativeConfig{}
+	part := func(name string) *declcfg.DeclarativeConfig {
+		if _, ok := parts[name]; !ok {
+			parts[name] = &declcfg.DeclarativeConfig{}
+		}
+		return parts[name]
+	}
+	for _, p := range fbc.Packages {
+		part(p.Name).Packages = append(part(p.Name).Packages, p)
+	}
+	for _, ch := range fbc.Channels {
+		part(ch.Package).Channels = append(part(ch.Package).Channels, ch)
+	}
+	for _, b := range fbc.Bundles {
+		part(b.Package).Bundles = append(part(b.Package).Bundles, b)
+	}
+	for _, d := range fbc.Deprecations {
+		part(d.Package).Deprecations = append(part(d.Package).Deprecations, d)
+	}
+	var invalid []string
+	for _, name := range sets.List(sets.KeySet(parts)) {
+		if _, err := declcfg.ConvertToModel(*parts[name]); err != nil {
+			invalid = append(invalid, name)
+		}
+	}
+	if len(invalid) != 1 {
+		return ""
+	}
+	return invalid[0]
+}
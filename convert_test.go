@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+)
+
+func TestConvertToModel(t *testing.T) {
+	badVersion := func(fbc *declcfg.DeclarativeConfig, i int) {
+		fbc.Bundles[i].Properties = []property.Property{property.MustBuildPackage(fbc.Bundles[i].Package, "x")}
+	}
+	tests := []struct {
+		name    string
+		modify  func(*declcfg.DeclarativeConfig)
+		wantErr string
+	}{
+		{name: "valid", modify: func(*declcfg.DeclarativeConfig) {}},
+		{
+			name:    "package named",
+			modify:  func(fbc *declcfg.DeclarativeConfig) { badVersion(fbc, 0) },
+			wantErr: `rendered input is not a valid catalog: package "foo": error parsing bundle "foo.v1.0.0" version "x": No Major.Minor.Patch elements found`,
+		},
+		{
+			name:    "package already named",
+			modify:  func(fbc *declcfg.DeclarativeConfig) { fbc.Bundles[0].Properties = nil },
+			wantErr: `rendered input is not a valid catalog: package "foo" bundle "foo.v1.0.0" must have exactly 1 "olm.package" property, found 0`,
+		},
+		{
+			name: "several packages invalid",
+			modify: func(fbc *declcfg.DeclarativeConfig) {
+				badVersion(fbc, 0)
+				badVersion(fbc, 1)
+			},
+			wantErr: `rendered input is not a valid catalog: error parsing bundle`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fbc := outputCatalog()
+			tt.modify(&fbc)
+			m, err := convertToModel(fbc)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if len(m) != 2 {
+					t.Errorf("expected 2 packages, got %d", len(m))
+				}
+				return
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error starting with %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
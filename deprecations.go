@@ -0,0 +1,55 @@
+package main
+
+import (
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/model"
+	"github.com/operator-framework/operator-registry/alpha/property"
+)
+
+// propertyTypeDeprecated is the type of the property added by --annotate-deprecated to mark deprecated packages,
+// channels, and bundles inline.
+const propertyTypeDeprecated = "fbc-filter.deprecated"
+
+type deprecatedProperty struct {
+	Message string `json:"message"`
+}
+
+func init() {
+	property.AddToScheme(propertyTypeDeprecated, &deprecatedProperty{})
+}
+
+// annotateDeprecations adds a deprecation marker property to every package, channel, and bundle in fbc that is
+// deprecated in the filtered model m. This keeps the deprecation status visible on the objects themselves, even if
+// a separate olm.deprecations object does not survive filtering.
+func annotateDeprecations(fbc *declcfg.DeclarativeConfig, m model.Model) {
+	for i, p := range fbc.Packages {
+		if pkg, ok := m[p.Name]; ok && pkg.Deprecation != nil {
+			fbc.Packages[i].Properties = append(fbc.Packages[i].Properties, deprecationMarker(pkg.Deprecation))
+		}
+	}
+	for i, c := range fbc.Channels {
+		pkg, ok := m[c.Package]
+		if !ok {
+			continue
+		}
+		if ch, ok := pkg.Channels[c.Name]; ok && ch.Deprecation != nil {
+			fbc.Channels[i].Properties = append(fbc.Channels[i].Properties, deprecationMarker(ch.Deprecation))
+		}
+	}
+	for i, b := range fbc.Bundles {
+		pkg, ok := m[b.Package]
+		if !ok {
+			continue
+		}
+		for _, ch := range pkg.Channels {
+			if mb, ok := ch.Bundles[b.Name]; ok && mb.Deprecation != nil {
+				fbc.Bundles[i].Properties = append(fbc.Bundles[i].Properties, deprecationMarker(mb.Deprecation))
+				break
+			}
+		}
+	}
+}
+
+func deprecationMarker(d *model.Deprecation) property.Property {
+	return property.MustBuild(&deprecatedProperty{Message: d.Message})
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+)
+
+func TestAnnotateDeprecations(t *testing.T) {
+	fbc := splitCatalog()
+	fbc.Deprecations[0].Entries = append(fbc.Deprecations[0].Entries, declcfg.DeprecationEntry{
+		Reference: declcfg.PackageScopedReference{Schema: declcfg.SchemaPackage},
+		Message:   "foo is deprecated",
+	})
+	m, err := declcfg.ConvertToModel(fbc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	annotateDeprecations(&fbc, m)
+
+	got := map[string]string{}
+	for _, p := range fbc.Packages {
+		for _, prop := range p.Properties {
+			if prop.Type == propertyTypeDeprecated {
+				got["package "+p.Name] = markerMessage(t, prop.Value)
+			}
+		}
+	}
+	for _, c := range fbc.Channels {
+		for _, prop := range c.Properties {
+			if prop.Type == propertyTypeDeprecated {
+				got["channel "+c.Name] = markerMessage(t, prop.Value)
+			}
+		}
+	}
+	for _, b := range fbc.Bundles {
+		for _, prop := range b.Properties {
+			if prop.Type == propertyTypeDeprecated {
+				got["bundle "+b.Name] = markerMessage(t, prop.Value)
+			}
+		}
+	}
+	want := map[string]string{
+		"package foo":       "foo is deprecated",
+		"channel v2":        "v2 is deprecated",
+		"bundle foo.v1.0.0": "foo.v1.0.0 is deprecated",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected deprecation markers %v, got %v", want, got)
+	}
+}
+
+func markerMessage(t *testing.T, value json.RawMessage) string {
+	t.Helper()
+	var marker deprecatedProperty
+	if err := json.Unmarshal(value, &marker); err != nil {
+		t.Fatal(err)
+	}
+	return marker.Message
+}
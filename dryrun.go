@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/operator-framework/operator-registry/alpha/model"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// catalogSnapshot records the default channel and the bundle versions of each channel of a model, keyed by package
+// name, so that the model can be compared with itself after it is filtered.
+type catalogSnapshot map[string]packageSnapshot
+
+type packageSnapshot struct {
+	defaultChannel string
+	channels       map[string]map[string]semver.Version
+}
+
+func snapshotModel(m model.Model) catalogSnapshot {
+	snapshot := catalogSnapshot{}
+	for _, pkg := range m {
+		p := packageSnapshot{channels: map[string]map[string]semver.Version{}}
+		if pkg.DefaultChannel != nil {
+			p.defaultChannel = pkg.DefaultChannel.Name
+		}
+		for _, ch := range pkg.Channels {
+			p.channels[ch.Name] = map[string]semver.Version{}
+			for _, b := range ch.Bundles {
+				p.channels[ch.Name][b.Name] = b.Version
+			}
+		}
+		snapshot[pkg.Name] = p
+	}
+	return snapshot
+}
+
+// writeDryRunSummary describes how filtering changed the source catalog: for each kept package, its default channel,
+// the channels that were kept and dropped, and the versions of the bundles kept and dropped from each channel.
+// Packages that were dropped entirely are only counted.
+func writeDryRunSummary(w io.Writer, source catalogSnapshot, filtered model.Model) {
+	filteredSnapshot := snapshotModel(filtered)
+	for _, pkgName := range sets.List(sets.KeySet(filteredSnapshot)) {
+		src, kept := source[pkgName], filteredSnapshot[pkgName]
+		defaultChannel := kept.defaultChannel
+		if kept.defaultChannel != src.defaultChannel {
+			defaultChannel = fmt.Sprintf("%s (was %s)", kept.defaultChannel, src.defaultChannel)
+		}
+		fmt.Fprintf(w, "package %s: default channel %s\n", pkgName, defaultChannel)
+		for _, chName := range sets.List(sets.KeySet(src.channels)) {
+			keptBundles, ok := kept.channels[chName]
+			if !ok {
+				fmt.Fprintf(w, "  channel %s: dropped with %s\n", chName, describeVersions(src.channels[chName]))
+				continue
+			}
+			dropped := map[string]semver.Version{}
+			for name, v := range src.channels[chName] {
+				if _, ok := keptBundles[name]; !ok {
+					dropped[name] = v
+				}
+			}
+			fmt.Fprintf(w, "  channel %s: kept %s, dropped %s\n", chName, describeVersions(keptBundles), describeVersions(dropped))
+		}
+	}
+	if droppedPackages := len(source) - len(filteredSnapshot); droppedPackages > 0 {
+		fmt.Fprintf(w, "packages dropped: %d\n", droppedPackages)
+	}
+}
+
+// describeVersions returns the number of bundles followed by their versions, lowest first.
+func describeVersions(bundles map[string]semver.Version) string {
+	versions := make([]semver.Version, 0, len(bundles))
+	for _, v := range bundles {
+		versions = append(versions, v)
+	}
+	if len(versions) == 0 {
+		return "0 bundles"
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].LT(versions[j]) })
+	strs := make([]string, 0, len(versions))
+	for _, v := range versions {
+		strs = append(strs, v.String())
+	}
+	noun := "bundles"
+	if len(versions) == 1 {
+		noun = "bundle"
+	}
+	return fmt.Sprintf("%d %s (%s)", len(versions), noun, strings.Join(strs, ", "))
+}
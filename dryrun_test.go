@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/model"
+)
+
+func TestWriteDryRunSummary(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter func(m model.Model)
+		want   string
+	}{
+		{
+			name:   "unchanged",
+			filter: func(m model.Model) {},
+			want: `package bar: default channel alpha
+  channel alpha: kept 1 bundle (0.1.0), dropped 0 bundles
+package foo: default channel stable
+  channel 2.0: kept 1 bundle (2.0.0), dropped 0 bundles
+  channel stable: kept 2 bundles (1.0.0, 1.1.0), dropped 0 bundles
+`,
+		},
+		{
+			name: "dropped bundles and package",
+			filter: func(m model.Model) {
+				delete(m, "bar")
+				delete(m["foo"].Channels["stable"].Bundles, "foo.v1.0.0")
+			},
+			want: `package foo: default channel stable
+  channel 2.0: kept 1 bundle (2.0.0), dropped 0 bundles
+  channel stable: kept 1 bundle (1.1.0), dropped 1 bundle (1.0.0)
+packages dropped: 1
+`,
+		},
+		{
+			name: "dropped default channel",
+			filter: func(m model.Model) {
+				delete(m["foo"].Channels, "stable")
+				m["foo"].DefaultChannel = m["foo"].Channels["2.0"]
+			},
+			want: `package bar: default channel alpha
+  channel alpha: kept 1 bundle (0.1.0), dropped 0 bundles
+package foo: default channel 2.0 (was stable)
+  channel 2.0: kept 1 bundle (2.0.0), dropped 0 bundles
+  channel stable: dropped with 2 bundles (1.0.0, 1.1.0)
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := snapshotModel(generateModel(t))
+			filtered := generateModel(t)
+			tt.filter(filtered)
+
+			var buf bytes.Buffer
+			writeDryRunSummary(&buf, source, filtered)
+			if buf.String() != tt.want {
+				t.Errorf("expected\n%s\ngot\n%s", tt.want, buf.String())
+			}
+		})
+	}
+}
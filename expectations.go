@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/operator-framework/operator-registry/alpha/model"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/yaml"
+
+	"fbc-filter/pkg/filter"
+)
+
+// expectation is a single item of an --expectations file, which is a YAML list of these entries. Each entry
+// asserts facts about a package, or about one of its channels when Channel is set, in the filtered catalog. Unset
+// fields are not checked.
+type expectation struct {
+	Package string `json:"package"`
+	Channel string `json:"channel,omitempty"`
+
+	// Absent asserts that the package, or channel, was filtered out.
+	Absent bool `json:"absent,omitempty"`
+
+	// DefaultChannel and Channels are only checked for packages.
+	DefaultChannel string   `json:"defaultChannel,omitempty"`
+	Channels       []string `json:"channels,omitempty"`
+
+	// Bundles is the number of distinct bundles kept in the package or channel.
+	Bundles *int `json:"bundles,omitempty"`
+
+	// Head is the version of the channel head. It is only checked for channels.
+	Head string `json:"head,omitempty"`
+}
+
+func loadExpectations(path string) ([]expectation, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var expectations []expectation
+	if err := yaml.Unmarshal(data, &expectations); err != nil {
+		return nil, fmt.Errorf("parse expectations: %v", err)
+	}
+	for i, e := range expectations {
+		if e.Package == "" {
+			return nil, fmt.Errorf("expectation %d: package must be set", i)
+		}
+	}
+	return expectations, nil
+}
+
+// check returns a description of each way in which the filtered model does not meet the expectation.
+func (e expectation) check(m model.Model) []string {
+	pkg, ok := m[e.Package]
+	if e.Channel == "" {
+		if e.Absent || !ok {
+			if e.Absent == ok {
+				return []string{fmt.Sprintf("expected present %t, got %t", !e.Absent, ok)}
+			}
+			return nil
+		}
+		var failures []string
+		if e.DefaultChannel != "" && pkg.DefaultChannel.Name != e.DefaultChannel {
+			failures = append(failures, fmt.Sprintf("expected default channel %q, got %q", e.DefaultChannel, pkg.DefaultChannel.Name))
+		}
+		if e.Channels != nil && !sets.New(e.Channels...).Equal(sets.KeySet(pkg.Channels)) {
+			failures = append(failures, fmt.Sprintf("expected channels [%s], got [%s]", strings.Join(sets.List(sets.New(e.Channels...)), ", "), strings.Join(sets.List(sets.KeySet(pkg.Channels)), ", ")))
+		}
+		if e.Bundles != nil {
+			bundles := sets.New[string]()
+			for _, ch := range pkg.Channels {
+				bundles.Insert(sets.List(sets.KeySet(ch.Bundles))...)
+			}
+			if bundles.Len() != *e.Bundles {
+				failures = append(failures, fmt.Sprintf("expected %d bundles, got %d", *e.Bundles, bundles.Len()))
+			}
+		}
+		return failures
+	}
+
+	var ch *model.Channel
+	if ok {
+		ch = pkg.Channels[e.Channel]
+	}
+	if e.Absent || ch == nil {
+		if e.Absent == (ch != nil) {
+			return []string{fmt.Sprintf("expected present %t, got %t", !e.Absent, ch != nil)}
+		}
+		return nil
+	}
+	var failures []string
+	if e.Bundles != nil && len(ch.Bundles) != *e.Bundles {
+		failures = append(failures, fmt.Sprintf("expected %d bundles, got %d", *e.Bundles, len(ch.Bundles)))
+	}
+	if e.Head != "" {
+		head, err := ch.Head()
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("expected head %s, got error: %v", e.Head, err))
+		} else if head.Version.String() != e.Head {
+			failures = append(failures, fmt.Sprintf("expected head %s, got %s", e.Head, head.Version))
+		}
+	}
+	return failures
+}
+
+func (e expectation) String() string {
+	if e.Channel == "" {
+		return fmt.Sprintf("package %q", e.Package)
+	}
+	return fmt.Sprintf("package %q, channel %q", e.Package, e.Channel)
+}
+
+func newTestCmd() *cobra.Command {
+	var (
+		configFile       string
+		expectationsFile string
+	)
+	cmd := &cobra.Command{
+		Use:   "test <catalog>",
+		Short: "Filter a catalog and check the result against a list of expectations",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := loggerFrom(cmd.Context())
+			expectations, err := loadExpectations(expectationsFile)
+			if err != nil {
+				logFatalf(logger, "error loading expectations: %v", err)
+			}
+			warnf := func(w filter.Warning) {
+				logger.Warn(w.Message, warningAttrs(w)...)
+			}
+			m, err := renderAndFilter(cmd.Context(), args, configFile, warnf)
+			if err != nil {
+				logFatalf(logger, "%v", err)
+			}
+
+			failed := 0
+			for _, e := range expectations {
+				failures := e.check(m)
+				if len(failures) == 0 {
+					fmt.Printf("PASS %s\n", e)
+					continue
+				}
+				failed++
+				fmt.Printf("FAIL %s: %s\n", e, strings.Join(failures, "; "))
+			}
+			if failed > 0 {
+				logFatalf(logger, "%d of %d expectations failed", failed, len(expectations))
+			}
+		},
+	}
+	cmd.Flags().StringVarP(&configFile, "config", "c", "", "Path or http(s) URL of the filter configuration file")
+	cmd.Flags().StringVar(&expectationsFile, "expectations", "", "Path to the list of expectations about the filtered catalog")
+	cmd.MarkFlagRequired("config")
+	cmd.MarkFlagRequired("expectations")
+	return cmd
+}
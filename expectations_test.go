@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadExpectations(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    int
+		wantErr string
+	}{
+		{name: "list", data: "- package: foo\n  defaultChannel: stable\n- package: foo\n  channel: stable\n  bundles: 2\n", want: 2},
+		{name: "missing package", data: "- package: foo\n- channel: stable\n", wantErr: "expectation 1: package must be set"},
+		{name: "not a list", data: "package: foo\n", wantErr: "parse expectations"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "expectations.yaml")
+			if err := os.WriteFile(path, []byte(tt.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := loadExpectations(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != tt.want {
+				t.Errorf("expected %d expectations, got %d", tt.want, len(got))
+			}
+		})
+	}
+}
+
+func TestExpectationCheck(t *testing.T) {
+	count := func(n int) *int { return &n }
+	tests := []struct {
+		name        string
+		expectation expectation
+		want        []string
+	}{
+		{
+			name:        "package",
+			expectation: expectation{Package: "foo", DefaultChannel: "stable", Channels: []string{"stable", "2.0"}, Bundles: count(3)},
+		},
+		{
+			name:        "package mismatches",
+			expectation: expectation{Package: "foo", DefaultChannel: "2.0", Channels: []string{"stable"}, Bundles: count(2)},
+			want: []string{
+				`expected default channel "2.0", got "stable"`,
+				"expected channels [stable], got [2.0, stable]",
+				"expected 2 bundles, got 3",
+			},
+		},
+		{name: "package absent", expectation: expectation{Package: "baz", Absent: true}},
+		{name: "package present", expectation: expectation{Package: "bar", Absent: true}, want: []string{"expected present false, got true"}},
+		{name: "package missing", expectation: expectation{Package: "baz"}, want: []string{"expected present true, got false"}},
+		{name: "channel", expectation: expectation{Package: "foo", Channel: "stable", Bundles: count(2), Head: "1.1.0"}},
+		{
+			name:        "channel mismatches",
+			expectation: expectation{Package: "foo", Channel: "stable", Bundles: count(1), Head: "1.0.0"},
+			want:        []string{"expected 1 bundles, got 2", "expected head 1.0.0, got 1.1.0"},
+		},
+		{name: "channel absent", expectation: expectation{Package: "foo", Channel: "fast", Absent: true}},
+		{name: "channel of a missing package", expectation: expectation{Package: "baz", Channel: "stable"}, want: []string{"expected present true, got false"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.expectation.check(generateModel(t)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected failures %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
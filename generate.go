@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/operator-framework/operator-registry/alpha/model"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/yaml"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+// generatedConfig is the skeleton written by generate-config. It only holds the fields generate-config fills in,
+// so that the skeleton is not cluttered with every unset option.
+type generatedConfig struct {
+	APIVersion string             `json:"apiVersion"`
+	Kind       string             `json:"kind"`
+	Packages   []generatedPackage `json:"packages"`
+}
+
+type generatedPackage struct {
+	Name           string             `json:"name"`
+	DefaultChannel string             `json:"defaultChannel"`
+	Channels       []generatedChannel `json:"channels"`
+
+	heads map[string]string
+}
+
+type generatedChannel struct {
+	Name string `json:"name"`
+}
+
+func newGenerateConfigCmd() *cobra.Command {
+	var (
+		packages []string
+		output   string
+	)
+	cmd := &cobra.Command{
+		Use:   "generate-config <catalog>",
+		Short: "Write a filter configuration that lists every package and channel of a catalog",
+		Long: `Write a filter configuration that lists every package and channel of a catalog.
+
+The configuration keeps the catalog as it is and is meant as a starting point to edit. In YAML, each channel is
+preceded by a comment with the version of its head and a versionRange that would keep only the head.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := loggerFrom(cmd.Context())
+			if output != "yaml" && output != "json" {
+				logFatalf(logger, "invalid output %q: must be one of %q or %q", output, "yaml", "json")
+			}
+			m, err := renderAndFilter(cmd.Context(), args, "", nil)
+			if err != nil {
+				logFatalf(logger, "%v", err)
+			}
+			config, err := generateConfig(m, packages)
+			if err != nil {
+				logFatalf(logger, "%v", err)
+			}
+			if output == "json" {
+				err = writeGeneratedConfigJSON(os.Stdout, config)
+			} else {
+				err = writeGeneratedConfigYAML(os.Stdout, config)
+			}
+			if err != nil {
+				logFatalf(logger, "error writing configuration: %v", err)
+			}
+		},
+	}
+	cmd.Flags().StringSliceVar(&packages, "packages", nil, "Only list these packages (comma-separated or repeated)")
+	cmd.Flags().StringVarP(&output, "output", "o", "yaml", "Configuration format (yaml, json)")
+	return cmd
+}
+
+// generateConfig lists the packages of the model, or only the named ones, sorted by name, with all of their
+// channels.
+func generateConfig(m model.Model, packages []string) (generatedConfig, error) {
+	names := sets.List(sets.KeySet(m))
+	if len(packages) > 0 {
+		var missing []string
+		for _, name := range packages {
+			if _, ok := m[name]; !ok {
+				missing = append(missing, name)
+			}
+		}
+		if len(missing) > 0 {
+			return generatedConfig{}, fmt.Errorf("packages not found in catalog: %s", strings.Join(missing, ", "))
+		}
+		names = sets.List(sets.New(packages...))
+	}
+
+	config := generatedConfig{APIVersion: v1.APIVersion, Kind: v1.Kind, Packages: []generatedPackage{}}
+	for _, name := range names {
+		pkg := m[name]
+		p := generatedPackage{Name: name, Channels: []generatedChannel{}, heads: map[string]string{}}
+		if pkg.DefaultChannel != nil {
+			p.DefaultChannel = pkg.DefaultChannel.Name
+		}
+		for _, chName := range sets.List(sets.KeySet(pkg.Channels)) {
+			p.Channels = append(p.Channels, generatedChannel{Name: chName})
+			if head, err := pkg.Channels[chName].Head(); err == nil {
+				p.heads[chName] = head.Version.String()
+			}
+		}
+		config.Packages = append(config.Packages, p)
+	}
+	return config, nil
+}
+
+func writeGeneratedConfigJSON(w io.Writer, config generatedConfig) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	return enc.Encode(config)
+}
+
+// writeGeneratedConfigYAML writes the configuration by hand rather than with a YAML encoder, which cannot write
+// comments.
+func writeGeneratedConfigYAML(w io.Writer, config generatedConfig) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "apiVersion: %s\n", yamlScalar(config.APIVersion))
+	fmt.Fprintf(&buf, "kind: %s\n", yamlScalar(config.Kind))
+	if len(config.Packages) == 0 {
+		buf.WriteString("packages: []\n")
+	} else {
+		buf.WriteString("packages:\n")
+	}
+	for _, p := range config.Packages {
+		fmt.Fprintf(&buf, "- name: %s\n", yamlScalar(p.Name))
+		if p.DefaultChannel != "" {
+			fmt.Fprintf(&buf, "  defaultChannel: %s\n", yamlScalar(p.DefaultChannel))
+		}
+		buf.WriteString("  channels:\n")
+		for _, ch := range p.Channels {
+			if head, ok := p.heads[ch.Name]; ok {
+				fmt.Fprintf(&buf, "  # head %s; to keep only the head:\n", head)
+				fmt.Fprintf(&buf, "  # versionRange: %s\n", yamlScalar(">="+head))
+			} else {
+				buf.WriteString("  # this channel does not have a single head\n")
+			}
+			fmt.Fprintf(&buf, "  - name: %s\n", yamlScalar(ch.Name))
+		}
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// yamlScalar returns a string as a YAML scalar, quoted when it would otherwise be read as something else.
+func yamlScalar(s string) string {
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return fmt.Sprintf("%q", s)
+	}
+	return strings.TrimSuffix(string(data), "\n")
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/model"
+	"github.com/operator-framework/operator-registry/alpha/property"
+	"sigs.k8s.io/yaml"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+// generateModel has a package foo with a stable channel 1.0.0 -> 1.1.0 and a channel named 2.0 with 2.0.0, and a
+// package bar with a single bundle.
+func generateModel(t *testing.T) model.Model {
+	t.Helper()
+	bundle := func(pkg, version string) declcfg.Bundle {
+		return declcfg.Bundle{
+			Schema:     declcfg.SchemaBundle,
+			Name:       pkg + ".v" + version,
+			Package:    pkg,
+			Image:      "example.com/" + pkg + ":v" + version,
+			Properties: []property.Property{property.MustBuildPackage(pkg, version)},
+		}
+	}
+	m, err := declcfg.ConvertToModel(declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{
+			{Schema: declcfg.SchemaPackage, Name: "foo", DefaultChannel: "stable"},
+			{Schema: declcfg.SchemaPackage, Name: "bar", DefaultChannel: "alpha"},
+		},
+		Channels: []declcfg.Channel{
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "stable", Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v1.0.0"},
+				{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+			}},
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "2.0", Entries: []declcfg.ChannelEntry{{Name: "foo.v2.0.0"}}},
+			{Schema: declcfg.SchemaChannel, Package: "bar", Name: "alpha", Entries: []declcfg.ChannelEntry{{Name: "bar.v0.1.0"}}},
+		},
+		Bundles: []declcfg.Bundle{bundle("foo", "1.0.0"), bundle("foo", "1.1.0"), bundle("foo", "2.0.0"), bundle("bar", "0.1.0")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestGenerateConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		packages []string
+		want     []string
+		wantErr  string
+	}{
+		{name: "every package", want: []string{"bar", "foo"}},
+		{name: "listed packages", packages: []string{"foo", "foo"}, want: []string{"foo"}},
+		{name: "missing packages", packages: []string{"foo", "baz", "qux"}, wantErr: "packages not found in catalog: baz, qux"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := generateConfig(generateModel(t), tt.packages)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got []string
+			for _, p := range config.Packages {
+				got = append(got, p.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected packages %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWriteGeneratedConfig(t *testing.T) {
+	config, err := generateConfig(generateModel(t), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var yamlOut bytes.Buffer
+	if err := writeGeneratedConfigYAML(&yamlOut, config); err != nil {
+		t.Fatal(err)
+	}
+	wantYAML := `apiVersion: olm.operatorframework.io/v1
+kind: FilterConfiguration
+packages:
+- name: bar
+  defaultChannel: alpha
+  channels:
+  # head 0.1.0; to keep only the head:
+  # versionRange: '>=0.1.0'
+  - name: alpha
+- name: foo
+  defaultChannel: stable
+  channels:
+  # head 2.0.0; to keep only the head:
+  # versionRange: '>=2.0.0'
+  - name: "2.0"
+  # head 1.1.0; to keep only the head:
+  # versionRange: '>=1.1.0'
+  - name: stable
+`
+	if got := yamlOut.String(); got != wantYAML {
+		t.Errorf("expected YAML:\n%s\ngot:\n%s", wantYAML, got)
+	}
+
+	var jsonOut bytes.Buffer
+	if err := writeGeneratedConfigJSON(&jsonOut, config); err != nil {
+		t.Fatal(err)
+	}
+	var fromYAML, fromJSON v1.FilterConfiguration
+	if err := yaml.Unmarshal(yamlOut.Bytes(), &fromYAML); err != nil {
+		t.Fatalf("generated YAML does not parse: %v", err)
+	}
+	if err := yaml.Unmarshal(jsonOut.Bytes(), &fromJSON); err != nil {
+		t.Fatalf("generated JSON does not parse: %v", err)
+	}
+	if !reflect.DeepEqual(fromYAML, fromJSON) {
+		t.Errorf("expected the YAML and JSON configurations to match, got %+v and %+v", fromYAML, fromJSON)
+	}
+	if err := fromYAML.ValidateTypeMeta(); err != nil {
+		t.Errorf("unexpected type meta error: %v", err)
+	}
+	if err := fromYAML.Validate(); err != nil {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+	if got := fromYAML.Packages[1].Channels[0].Name; got != "2.0" {
+		t.Errorf("expected channel %q to stay a string, got %q", "2.0", got)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	blangsemver "github.com/blang/semver/v4"
+	"github.com/operator-framework/operator-registry/alpha/model"
+	"github.com/spf13/cobra"
+
+	"fbc-filter/pkg/filter"
+)
+
+const (
+	graphOutputText = "text"
+	graphOutputDOT  = "dot"
+)
+
+// graphEdge is an upgrade edge from a bundle to a bundle it can upgrade from.
+type graphEdge struct {
+	from, to string
+	kind     string
+}
+
+func newGraphCmd() *cobra.Command {
+	var (
+		pkgName    string
+		chName     string
+		configFile string
+		output     string
+	)
+	cmd := &cobra.Command{
+		Use:   "graph <catalog>",
+		Short: "Show the upgrade graph of a channel, optionally after filtering",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := loggerFrom(cmd.Context())
+			if output != graphOutputText && output != graphOutputDOT {
+				logFatalf(logger, "invalid output %q: must be one of %q or %q", output, graphOutputText, graphOutputDOT)
+			}
+
+			warnf := func(w filter.Warning) {
+				logger.Warn(w.Message, warningAttrs(w)...)
+			}
+			m, err := renderAndFilter(cmd.Context(), args, configFile, warnf)
+			if err != nil {
+				logFatalf(logger, "%v", err)
+			}
+
+			pkg, ok := m[pkgName]
+			if !ok {
+				logFatalf(logger, "package %q not found", pkgName)
+			}
+			ch, ok := pkg.Channels[chName]
+			if !ok {
+				logFatalf(logger, "channel %q not found in package %q", chName, pkgName)
+			}
+			if output == graphOutputDOT {
+				err = writeGraphDOT(ch, os.Stdout)
+			} else {
+				err = writeGraphText(ch, os.Stdout)
+			}
+			if err != nil {
+				logFatalf(logger, "error writing graph: %v", err)
+			}
+		},
+	}
+	cmd.Flags().StringVar(&pkgName, "package", "", "Package of the channel")
+	cmd.Flags().StringVar(&chName, "channel", "", "Channel whose upgrade graph to show")
+	cmd.Flags().StringVarP(&configFile, "config", "c", "", "Path or http(s) URL of a filter configuration to apply first")
+	cmd.Flags().StringVarP(&output, "output", "o", graphOutputText, "Graph format (text, dot)")
+	cmd.MarkFlagRequired("package")
+	cmd.MarkFlagRequired("channel")
+	return cmd
+}
+
+// graphBundles returns the bundles of the channel, highest version first with ties broken by name.
+func graphBundles(ch *model.Channel) []*model.Bundle {
+	bundles := make([]*model.Bundle, 0, len(ch.Bundles))
+	for _, b := range ch.Bundles {
+		bundles = append(bundles, b)
+	}
+	sort.Slice(bundles, func(i, j int) bool {
+		if c := bundles[i].Version.Compare(bundles[j].Version); c != 0 {
+			return c > 0
+		}
+		return bundles[i].Name < bundles[j].Name
+	})
+	return bundles
+}
+
+// graphEdges returns the replaces, skips, and skipRange edges of a bundle to the other bundles of its channel.
+func graphEdges(ch *model.Channel, b *model.Bundle) ([]graphEdge, error) {
+	var edges []graphEdge
+	if _, ok := ch.Bundles[b.Replaces]; ok {
+		edges = append(edges, graphEdge{from: b.Name, to: b.Replaces, kind: "replaces"})
+	}
+	for _, skip := range b.Skips {
+		if _, ok := ch.Bundles[skip]; ok {
+			edges = append(edges, graphEdge{from: b.Name, to: skip, kind: "skips"})
+		}
+	}
+	if b.SkipRange != "" {
+		skipRange, err := blangsemver.ParseRange(b.SkipRange)
+		if err != nil {
+			return nil, fmt.Errorf("invalid skipRange %q for bundle %q: %v", b.SkipRange, b.Name, err)
+		}
+		for _, other := range graphBundles(ch) {
+			if other != b && skipRange(other.Version) {
+				edges = append(edges, graphEdge{from: b.Name, to: other.Name, kind: "skipRange"})
+			}
+		}
+	}
+	return edges, nil
+}
+
+func writeGraphText(ch *model.Channel, w io.Writer) error {
+	head, err := ch.Head()
+	if err != nil {
+		return err
+	}
+	for _, b := range graphBundles(ch) {
+		label := ""
+		if b == head {
+			label = " (head)"
+		}
+		fmt.Fprintf(w, "%s %s%s\n", b.Name, b.Version, label)
+		edges, err := graphEdges(ch, b)
+		if err != nil {
+			return err
+		}
+		for _, e := range edges {
+			fmt.Fprintf(w, "  %s %s\n", e.kind, e.to)
+		}
+	}
+	return nil
+}
+
+func writeGraphDOT(ch *model.Channel, w io.Writer) error {
+	head, err := ch.Head()
+	if err != nil {
+		return err
+	}
+	styles := map[string]string{"replaces": "solid", "skips": "dashed", "skipRange": "dotted"}
+	fmt.Fprintf(w, "digraph %q {\n", ch.Package.Name+"/"+ch.Name)
+	for _, b := range graphBundles(ch) {
+		shape := "ellipse"
+		if b == head {
+			shape = "doubleoctagon"
+		}
+		fmt.Fprintf(w, "  %q [label=%q, shape=%s];\n", b.Name, b.Name+"\n"+b.Version.String(), shape)
+	}
+	for _, b := range graphBundles(ch) {
+		edges, err := graphEdges(ch, b)
+		if err != nil {
+			return err
+		}
+		for _, e := range edges {
+			fmt.Fprintf(w, "  %q -> %q [label=%q, style=%s];\n", e.from, e.to, e.kind, styles[e.kind])
+		}
+	}
+	fmt.Fprintln(w, "}")
+	return nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/model"
+	"github.com/operator-framework/operator-registry/alpha/property"
+)
+
+func TestWriteGraph(t *testing.T) {
+	// stable is 1.0.0 -> 1.1.0 -> 1.2.0, in which 1.2.0 also skips 1.0.0 and skips >=1.0.0 <1.2.0 by skipRange.
+	bundle := func(version string) declcfg.Bundle {
+		return declcfg.Bundle{
+			Schema:     declcfg.SchemaBundle,
+			Name:       "foo.v" + version,
+			Package:    "foo",
+			Image:      "example.com/foo:v" + version,
+			Properties: []property.Property{property.MustBuildPackage("foo", version)},
+		}
+	}
+	m, err := declcfg.ConvertToModel(declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{{Schema: declcfg.SchemaPackage, Name: "foo", DefaultChannel: "stable"}},
+		Channels: []declcfg.Channel{{Schema: declcfg.SchemaChannel, Package: "foo", Name: "stable", Entries: []declcfg.ChannelEntry{
+			{Name: "foo.v1.0.0"},
+			{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+			{Name: "foo.v1.2.0", Replaces: "foo.v1.1.0", Skips: []string{"foo.v1.0.0"}, SkipRange: ">=1.0.0 <1.2.0"},
+		}}},
+		Bundles: []declcfg.Bundle{bundle("1.0.0"), bundle("1.1.0"), bundle("1.2.0")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		write func(*model.Channel, io.Writer) error
+		want  string
+	}{
+		{
+			name:  "text",
+			write: writeGraphText,
+			want: `foo.v1.2.0 1.2.0 (head)
+  replaces foo.v1.1.0
+  skips foo.v1.0.0
+  skipRange foo.v1.1.0
+  skipRange foo.v1.0.0
+foo.v1.1.0 1.1.0
+  replaces foo.v1.0.0
+foo.v1.0.0 1.0.0
+`,
+		},
+		{
+			name:  "dot",
+			write: writeGraphDOT,
+			want: `digraph "foo/stable" {
+  "foo.v1.2.0" [label="foo.v1.2.0\n1.2.0", shape=doubleoctagon];
+  "foo.v1.1.0" [label="foo.v1.1.0\n1.1.0", shape=ellipse];
+  "foo.v1.0.0" [label="foo.v1.0.0\n1.0.0", shape=ellipse];
+  "foo.v1.2.0" -> "foo.v1.1.0" [label="replaces", style=solid];
+  "foo.v1.2.0" -> "foo.v1.0.0" [label="skips", style=dashed];
+  "foo.v1.2.0" -> "foo.v1.1.0" [label="skipRange", style=dotted];
+  "foo.v1.2.0" -> "foo.v1.0.0" [label="skipRange", style=dotted];
+  "foo.v1.1.0" -> "foo.v1.0.0" [label="replaces", style=solid];
+}
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.write(m["foo"].Channels["stable"], &buf); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.want, got)
+			}
+		})
+	}
+}
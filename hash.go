@@ -0,0 +1,18 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+)
+
+// catalogHash returns the SHA256 digest of the catalog's JSON encoding. The encoding orders packages, channels,
+// and bundles by name, so the digest only changes when the catalog's content does.
+func catalogHash(fbc declcfg.DeclarativeConfig) (string, error) {
+	h := sha256.New()
+	if err := declcfg.WriteJSON(fbc, h); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("sha256:%x", h.Sum(nil)), nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+)
+
+func TestCatalogHash(t *testing.T) {
+	catalog := func(bundles ...string) declcfg.DeclarativeConfig {
+		fbc := declcfg.DeclarativeConfig{Packages: []declcfg.Package{{Schema: declcfg.SchemaPackage, Name: "foo"}}}
+		for _, name := range bundles {
+			fbc.Bundles = append(fbc.Bundles, declcfg.Bundle{Schema: declcfg.SchemaBundle, Package: "foo", Name: name})
+		}
+		return fbc
+	}
+	hash := func(fbc declcfg.DeclarativeConfig) string {
+		t.Helper()
+		h, err := catalogHash(fbc)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return h
+	}
+
+	base := hash(catalog("foo.v1.0.0", "foo.v1.1.0"))
+	if !regexp.MustCompile(`^sha256:[0-9a-f]{64}$`).MatchString(base) {
+		t.Errorf("expected a sha256 digest, got %q", base)
+	}
+	tests := []struct {
+		name string
+		fbc  declcfg.DeclarativeConfig
+		same bool
+	}{
+		{name: "same content", fbc: catalog("foo.v1.0.0", "foo.v1.1.0"), same: true},
+		{name: "different order", fbc: catalog("foo.v1.1.0", "foo.v1.0.0"), same: true},
+		{name: "different content", fbc: catalog("foo.v1.0.0")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hash(tt.fbc); (got == base) != tt.same {
+				t.Errorf("expected the same digest %t, got %q and %q", tt.same, base, got)
+			}
+		})
+	}
+}
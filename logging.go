@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"fbc-filter/pkg/filter"
+)
+
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+var logLevels = map[string]slog.Level{
+	"debug": slog.LevelDebug,
+	"info":  slog.LevelInfo,
+	"warn":  slog.LevelWarn,
+	"error": slog.LevelError,
+}
+
+// newLogger returns a logger writing records of at least the named level to w, formatted as text or JSON.
+func newLogger(w io.Writer, level, format string) (*slog.Logger, error) {
+	l, ok := logLevels[level]
+	if !ok {
+		return nil, fmt.Errorf("invalid log level %q: must be one of debug, info, warn, error", level)
+	}
+	opts := &slog.HandlerOptions{Level: l}
+	switch format {
+	case logFormatText:
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case logFormatJSON:
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	}
+	return nil, fmt.Errorf("invalid log format %q: must be one of %s, %s", format, logFormatText, logFormatJSON)
+}
+
+type loggerKey struct{}
+
+// contextWithLogger returns a copy of ctx carrying the logger built from --log-level and --log-format.
+func contextWithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// loggerFrom returns the logger of ctx, or a text logger writing info records to stderr if the flags have not been
+// parsed yet.
+func loggerFrom(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.New(slog.NewTextHandler(os.Stderr, nil))
+}
+
+// logFatalf logs the formatted message as an error and exits non-zero.
+func logFatalf(logger *slog.Logger, format string, args ...interface{}) {
+	logger.Error(fmt.Sprintf(format, args...))
+	os.Exit(1)
+}
+
+// warningAttrs returns the code of a filter warning and the names of the objects it is about, as log attributes.
+func warningAttrs(w filter.Warning) []interface{} {
+	attrs := []interface{}{"code", w.Code}
+	for _, a := range []struct{ key, value string }{{"package", w.Package}, {"channel", w.Channel}, {"bundle", w.Bundle}} {
+		if a.value != "" {
+			attrs = append(attrs, a.key, a.value)
+		}
+	}
+	return attrs
+}
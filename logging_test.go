@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"fbc-filter/pkg/filter"
+)
+
+func TestNewLogger(t *testing.T) {
+	tests := []struct {
+		name    string
+		level   string
+		format  string
+		wantErr string
+	}{
+		{name: "text", level: "info", format: logFormatText},
+		{name: "json", level: "debug", format: logFormatJSON},
+		{name: "invalid level", level: "verbose", format: logFormatText, wantErr: `invalid log level "verbose"`},
+		{name: "invalid format", level: "info", format: "xml", wantErr: `invalid log format "xml"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newLogger(&bytes.Buffer{}, tt.level, tt.format)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestWarningJSONAttributes(t *testing.T) {
+	tests := []struct {
+		name    string
+		warning filter.Warning
+		want    map[string]string
+	}{
+		{
+			name:    "package",
+			warning: filter.Warning{Code: filter.WarningPackageNotFound, Package: "foo", Message: `package "foo" not found`},
+			want:    map[string]string{"level": "WARN", "msg": `package "foo" not found`, "code": string(filter.WarningPackageNotFound), "package": "foo"},
+		},
+		{
+			name:    "channel",
+			warning: filter.Warning{Code: filter.WarningChannelNotFound, Package: "foo", Channel: "stable", Message: `channel "stable" not found`},
+			want:    map[string]string{"level": "WARN", "msg": `channel "stable" not found`, "code": string(filter.WarningChannelNotFound), "package": "foo", "channel": "stable"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger, err := newLogger(&buf, "info", logFormatJSON)
+			if err != nil {
+				t.Fatal(err)
+			}
+			loggerFrom(contextWithLogger(context.Background(), logger)).Warn(tt.warning.Message, warningAttrs(tt.warning)...)
+
+			var record map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+				t.Fatalf("expected a JSON record, got %q: %v", buf.String(), err)
+			}
+			delete(record, "time")
+			got := map[string]string{}
+			for k, v := range record {
+				s, ok := v.(string)
+				if !ok {
+					t.Fatalf("attribute %q: expected a string, got %v", k, v)
+				}
+				got[k] = s
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("expected attributes %v, got %v", tt.want, got)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("attribute %q: expected %q, got %q", k, v, got[k])
+				}
+			}
+		})
+	}
+}
@@ -1,12 +1,14 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"regexp"
+	"runtime"
 	"strings"
+	"time"
 
-	mmsemver "github.com/Masterminds/semver/v3"
-	blangsemver "github.com/blang/semver/v4"
 	"github.com/operator-framework/operator-registry/alpha/action"
 	"github.com/operator-framework/operator-registry/alpha/declcfg"
 	"github.com/operator-framework/operator-registry/alpha/model"
@@ -15,279 +17,592 @@ import (
 	"sigs.k8s.io/yaml"
 
 	v1 "fbc-filter/api/config/v1"
+	"fbc-filter/pkg/filter"
 )
 
 func main() {
 	var (
-		configFile string
-		migrate    bool
-		output     string
+		configFile    string
+		configHeaders []string
+		allowInsecure bool
+		configTimeout time.Duration
+		selects       []string
+		migrate       bool
+		output        string
+		outputFiles   []string
+		outputIndent  int
+		outputDir     string
+		propertyStyle string
+		sortBundlesBy string
+		dumpModel     string
+		inputModel    string
+
+		normalizeVersions  bool
+		annotateDeprecated bool
+		inventoryFile      string
+		includeAllChannels bool
+
+		maxTotalBundles         int
+		maxTotalBundlesStrategy string
+
+		keepReferencedChannels  bool
+		requireFullReachability bool
+		headsUnchanged          bool
+		excludeDeprecatedArg    bool
+		explainHead             bool
+		matchOrder              string
+		provenanceFile          string
+		splitByMajor            bool
+		pullSecret              string
+		registryConfig          string
+		skipTLSVerify           bool
+		useHTTP                 bool
+		allowedRefTypes         []string
+		dropDanglingEdges       bool
+		stateFile               string
+		expectedHeadsFile       string
+		printHash               bool
+		allowedChannelsFile     string
+		deniedChannelsFile      string
+		monotonicChain          bool
+		keepPackageIcon         bool
+		annotateAttributionArg  bool
+		pushImage               string
+		strict                  bool
+		rollbackSafe            bool
+		dryRun                  bool
+		approvalsFile           string
+		approvalsWarnOnly       bool
+		bestEffort              bool
+		reportFile              string
+		mergeStrategy           string
+		workers                 int
+		logLevel                string
+		logFormat               string
 	)
 	cmd := &cobra.Command{
-		Use:  "fbc-filter --config <config> <catalogReference> [<flags>]",
-		Args: cobra.ExactArgs(1),
+		Use: "fbc-filter (--config <config> | --select <expr>) (<catalogReference>... | - | --input-model <file>) [<flags>]",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if inputModel != "" {
+				return cobra.NoArgs(cmd, args)
+			}
+			if len(args) > 1 && sets.New(args...).Has(stdinRef) {
+				return fmt.Errorf("%q cannot be combined with other catalog references", stdinRef)
+			}
+			return cobra.MinimumNArgs(1)(cmd, args)
+		},
+		// the logger is built once for every subcommand, which inherit --log-level and --log-format.
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			logger, err := newLogger(os.Stderr, logLevel, logFormat)
+			if err != nil {
+				return err
+			}
+			cmd.SetContext(contextWithLogger(cmd.Context(), logger))
+			return nil
+		},
+		SilenceErrors: true,
 		Run: func(cmd *cobra.Command, args []string) {
-			configData, err := os.ReadFile(configFile)
+			logger := loggerFrom(cmd.Context())
+			fatalf := func(format string, args ...interface{}) {
+				logFatalf(logger, format, args...)
+			}
+			var (
+				config v1.FilterConfiguration
+				err    error
+			)
+			if len(selects) > 0 {
+				config, err = parseSelectExpressions(selects)
+				if err != nil {
+					fatalf("%v", err)
+				}
+			} else {
+				configData, err := readConfigData(cmd.Context(), configFile, configHeaders, configTimeout, allowInsecure)
+				if err != nil {
+					fatalf("error reading configuration file: %v", err)
+				}
+				configData, err = expandConfigTemplate(configData)
+				if err != nil {
+					fatalf("error expanding configuration file: %v", err)
+				}
+				if err := yaml.Unmarshal(configData, &config); err != nil {
+					fatalf("error parsing configuration file: %v", err)
+				}
+			}
+			if err := config.ValidateTypeMeta(); err != nil {
+				fatalf("invalid configuration file: %v", err)
+			}
+			if err := config.Validate(); err != nil {
+				fatalf("invalid configuration file: %v", err)
+			}
+			// attributions refer to the packages as written, before nameRegex entries are expanded.
+			writtenPackages := append([]v1.Package(nil), config.Packages...)
+			if _, err := parseTargetOLMVersion(config.TargetOLMVersion); err != nil {
+				fatalf("invalid configuration file: %v", err)
+			}
+			if err := validatePropertyStyle(propertyStyle); err != nil {
+				fatalf("%v", err)
+			}
+			if err := validateSortBundlesBy(sortBundlesBy); err != nil {
+				fatalf("%v", err)
+			}
+			if err := filter.ValidateBundleBudgetStrategy(maxTotalBundlesStrategy); err != nil {
+				fatalf("%v", err)
+			}
+			if err := validateMergeStrategy(mergeStrategy); err != nil {
+				fatalf("%v", err)
+			}
+			if pullSecret != "" && registryConfig != "" {
+				fatalf("--pull-secret and --registry-config cannot both be set")
+			}
+			if useHTTP && (pullSecret != "" || registryConfig != "") && !allowInsecure {
+				fatalf("refusing to send the credentials of --pull-secret or --registry-config over plain HTTP with --use-http (use --allow-insecure-credentials to send them anyway)")
+			}
+			registryOpts := registryOptions{skipTLSVerify: skipTLSVerify, plainHTTP: useHTTP}
+			// check the credentials up front rather than after other inputs are rendered.
+			if registryConfig != "" {
+				if _, err := readRegistryConfig(registryConfig); err != nil {
+					fatalf("error loading registry credentials: %v", err)
+				}
+			}
+			if printHash && output != "" && outputDir == "" {
+				fatalf("--print-hash cannot be used with --output: both write to stdout")
+			}
+			var outputTargets []outputTarget
+			if !dryRun && (output != "" || len(outputFiles) > 0 || outputDir != "" || (!printHash && pushImage == "")) {
+				outputTargets, err = resolveOutputTargets(output, outputFiles, outputDir)
+				if err != nil {
+					fatalf("%v", err)
+				}
+			}
+			if splitByMajor && (output != "" || outputDir != "") {
+				fatalf("--split-by-major cannot be used with --output or --output-dir: the split catalogs can only be written to --output-file")
+			}
+			if cmd.Flags().Changed("output-indent") {
+				if outputIndent < 0 {
+					fatalf("--output-indent must not be negative")
+				}
+				for i := range outputTargets {
+					if outputTargets[i].format.name == "json" {
+						outputTargets[i].format.write = writeJSONIndented(outputIndent)
+					}
+				}
+			}
+			if workers < 0 {
+				fatalf("--workers must not be negative")
+			}
+			if workers == 0 {
+				workers = runtime.GOMAXPROCS(0)
+			}
+			if matchOrder != "" {
+				order, err := loadReferenceOrder(matchOrder)
+				if err != nil {
+					fatalf("error loading --match-order reference: %v", err)
+				}
+				for i := range outputTargets {
+					outputTargets[i].format = outputTargets[i].format.wrap(order.writeFunc)
+				}
+			}
+			allowedRefMask, err := parseAllowedRefTypes(allowedRefTypes)
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "error reading configuration file: %v\n", err)
-				os.Exit(1)
+				fatalf("%v", err)
 			}
-			var config v1.FilterConfiguration
-			if err := yaml.Unmarshal(configData, &config); err != nil {
-				fmt.Fprintf(os.Stderr, "error parsing configuration file: %v\n", err)
-				os.Exit(1)
+			var excludeChannels *regexp.Regexp
+			if !includeAllChannels {
+				excludeChannels, err = filter.DefaultExcludeChannelRegex(config)
+				if err != nil {
+					fatalf("invalid configuration file: %v", err)
+				}
 			}
-			if config.Kind != "FilterConfiguration" && config.APIVersion != "olm.operatorframework.io/v1" {
-				fmt.Fprintf(os.Stderr, "invalid configuration file: expected kind FilterConfiguration and APIVersion olm.operatorframework.io/v1, got %s/%s\n", config.Kind, config.APIVersion)
-				os.Exit(1)
+			var state runState
+			if stateFile != "" {
+				state, err = loadState(stateFile)
+				if err != nil {
+					fatalf("error loading state: %v", err)
+				}
 			}
-			r := action.Render{
-				Refs:           args,
-				Registry:       nil,
-				AllowedRefMask: action.RefDCDir | action.RefDCImage | action.RefSqliteFile | action.RefSqliteImage,
-				Migrate:        migrate,
+			var expectedHeads runState
+			if expectedHeadsFile != "" {
+				expectedHeads, err = loadExpectedHeads(expectedHeadsFile)
+				if err != nil {
+					fatalf("error loading expected heads: %v", err)
+				}
 			}
-			fbc, err := r.Run(cmd.Context())
+			policy, err := filter.LoadChannelPolicy(allowedChannelsFile, deniedChannelsFile)
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "error rendering input: %v\n", err)
-				os.Exit(1)
+				fatalf("error loading channel policy: %v", err)
 			}
-			if err := filterV1(fbc, config, func(format string, args ...interface{}) {
-				fmt.Fprintf(os.Stderr, format+"\n", args...)
-			}); err != nil {
-				fmt.Fprintf(os.Stderr, "error filtering input: %v\n", err)
-				os.Exit(1)
+			var approved sets.Set[string]
+			if approvalsFile != "" {
+				approved, err = loadApprovals(approvalsFile)
+				if err != nil {
+					fatalf("error loading approvals: %v", err)
+				}
 			}
-
-			var write declcfg.WriteFunc
-			switch output {
-			case "yaml":
-				write = declcfg.WriteYAML
-			case "json":
-				write = declcfg.WriteJSON
-			default:
-				fmt.Fprintf(os.Stderr, "invalid output format: %s\n", output)
-			}
-			if err := write(*fbc, os.Stdout); err != nil {
-				fmt.Fprintf(os.Stderr, "error writing output: %v\n", err)
-				os.Exit(1)
+			var inv filter.Inventory
+			if inventoryFile != "" {
+				inv, err = filter.LoadInventory(inventoryFile)
+				if err != nil {
+					fatalf("error loading inventory: %v", err)
+				}
 			}
-		},
-	}
-	cmd.Flags().BoolVar(&migrate, "migrate", false, "Migrate the input to the latest version")
-	cmd.Flags().StringVarP(&output, "output", "o", "", "Output format")
-	cmd.Flags().StringVarP(&configFile, "config", "c", "", "Path to the filter configuration file")
-	cmd.MarkFlagRequired("config")
-	if err := cmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "error executing command: %v\n", err)
-		os.Exit(1)
-	}
-}
-
-type logFunc func(string, ...interface{})
-
-func filterV1(fbc *declcfg.DeclarativeConfig, configuration v1.FilterConfiguration, warnf logFunc) error {
-	m, err := declcfg.ConvertToModel(*fbc)
-	if err != nil {
-		return err
-	}
-
-	// first filter out packages
-	filterPackages(m, configuration.Packages, warnf)
-
-	// then filter out channels
-	for _, p := range configuration.Packages {
-		pkgModel, ok := m[p.Name]
-		if !ok {
-			warnf("package %q not found in catalog", p.Name)
-			continue
-		}
 
-		if err := filterChannels(pkgModel, p, warnf); err != nil {
-			return fmt.Errorf("could not filter channels in package %q: %v", p.Name, err)
-		}
+			strictFailures := 0
+			report := func(message string, dataIssue bool, attrs ...interface{}) {
+				if strict && dataIssue {
+					strictFailures++
+					logger.Error(message, attrs...)
+					return
+				}
+				logger.Warn(message, attrs...)
+			}
+			warnf := func(w filter.Warning) {
+				report(w.Message, filter.IsDataWarning(w.Code), warningAttrs(w)...)
+			}
 
-		// for the remaining channels, filter out bundles that don't match the version range
-		for _, c := range p.Channels {
-			ch, ok := pkgModel.Channels[c.Name]
-			if !ok {
-				warnf("channel %q not found in package %q", c.Name, p.Name)
-				continue
+			var (
+				m            model.Model
+				deprecations []declcfg.Deprecation
+				others       []declcfg.Meta
+				sources      map[string]string
+			)
+			if inputModel != "" {
+				if config.PackageSelector != nil {
+					fatalf("invalid configuration file: packageSelector cannot be used with --input-model, which does not record package properties")
+				}
+				m, err = loadModelDump(inputModel)
+				if err != nil {
+					fatalf("error loading input model: %v", err)
+				}
+				if err := filter.ExpandNameRegexes(&config, sets.List(sets.KeySet(m))); err != nil {
+					fatalf("invalid configuration file: %v", err)
+				}
+			} else {
+				var fbc *declcfg.DeclarativeConfig
+				if args[0] == stdinRef {
+					fbc, err = declcfg.LoadReader(os.Stdin)
+					if err != nil {
+						fatalf("error reading input from stdin: %v", err)
+					}
+				} else {
+					cleanupRegistry := func() {}
+					r := action.Render{
+						Registry:       nil,
+						AllowedRefMask: allowedRefMask,
+						Migrate:        migrate && !hasMigrateOverrides(config),
+					}
+					if pullSecret != "" || registryConfig != "" || registryOpts.isSet() {
+						var authDir string
+						if pullSecret != "" || registryConfig != "" {
+							if authDir, err = writeAuthConfig(pullSecret, registryConfig); err != nil {
+								fatalf("error loading registry credentials: %v", err)
+							}
+						}
+						reg, err := newRegistry(authDir, registryOpts)
+						if err != nil {
+							os.RemoveAll(authDir)
+							fatalf("error creating registry: %v", err)
+						}
+						r.Registry = reg
+						cleanupRegistry = func() {
+							reg.Destroy()
+							os.RemoveAll(authDir)
+						}
+					}
+					// each reference is rendered on its own so that packages defined by more than one of them can
+					// be detected and resolved with --merge-strategy.
+					fbcs := make([]*declcfg.DeclarativeConfig, 0, len(args))
+					for _, ref := range args {
+						r.Refs = []string{ref}
+						logger.Info("rendering catalog", "ref", ref)
+						started := time.Now()
+						rendered, err := r.Run(cmd.Context())
+						if err != nil {
+							cleanupRegistry()
+							if errors.Is(err, action.ErrNotAllowed) {
+								fatalf("error rendering input %q: %v (allowed reference types: %s)", ref, err, strings.Join(allowedRefTypes, ", "))
+							}
+							fatalf("error rendering input %q: %v", ref, err)
+						}
+						logger.Info("rendered catalog", "ref", ref, "packages", len(rendered.Packages), "bundles", len(rendered.Bundles), "duration", time.Since(started))
+						fbcs = append(fbcs, rendered)
+					}
+					cleanupRegistry()
+					fbc, sources, err = mergeCatalogs(args, fbcs, mergeStrategy)
+					if err != nil {
+						fatalf("error merging inputs: %v", err)
+					}
+				}
+				// migrate before converting to the model, so that filtering and everything after it sees the
+				// migrated bundles. migrations never change bundle versions, so the kept bundles are the same as
+				// if the filtered catalog were migrated instead.
+				if err := filter.ExpandNameRegexes(&config, packageNames(fbc)); err != nil {
+					fatalf("invalid configuration file: %v", err)
+				}
+				if err := selectPackages(fbc, &config); err != nil {
+					fatalf("invalid configuration file: %v", err)
+				}
+				// a catalog read from stdin is not rendered, so it is migrated here in full.
+				if hasMigrateOverrides(config) || (migrate && args[0] == stdinRef) {
+					if err := migratePackages(fbc, config, migrate); err != nil {
+						fatalf("error migrating input: %v", err)
+					}
+				}
+				if err := checkBundleVersions(fbc, normalizeVersions, bestEffort, warnf); err != nil {
+					fatalf("error checking bundle versions: %v", err)
+				}
+				m, err = convertToModel(*fbc)
+				if err != nil {
+					fatalf("error converting input: %v", err)
+				}
+				deprecations, others = fbc.Deprecations, fbc.Others
+			}
+			// channel globs are expanded here, and not only by the filter, so that lastHead state and head
+			// explanations see the channels they match. the filter then has nothing left to expand, so the warnings
+			// of this pass are added to its result.
+			var globWarnings []filter.Warning
+			if err := filter.ExpandChannelGlobs(m, &config, func(w filter.Warning) {
+				globWarnings = append(globWarnings, w)
+				warnf(w)
+			}); err != nil {
+				fatalf("invalid configuration file: %v", err)
 			}
-			if c.VersionRange != "" {
-				if err := filterBundles(ch, c, warnf); err != nil {
-					return fmt.Errorf("could not filter bundles in package %q: %v", c.Name, err)
+			if stateFile != "" {
+				resolveLastHead(&config, state)
+			}
+			if dumpModel != "" {
+				if err := writeModelDump(m, dumpModel); err != nil {
+					fatalf("error dumping model: %v", err)
 				}
 			}
-		}
-	}
-	if err := m.Validate(); err != nil {
-		return fmt.Errorf("filtered model is invalid: %v", err)
-	}
-	*fbc = declcfg.ConvertFromModel(m)
-	return nil
-}
 
-func filterPackages(m model.Model, packageConfigs []v1.Package, warnf logFunc) {
-	// first filter out packages
-	packages := sets.New[string]()
-	for _, p := range packageConfigs {
-		packages.Insert(p.Name)
-	}
-	for _, pkg := range m {
-		if !packages.Has(pkg.Name) {
-			delete(m, pkg.Name)
-		}
-	}
-}
-
-func filterChannels(p *model.Package, pkgConfig v1.Package, warnf logFunc) error {
-	if len(pkgConfig.Channels) > 0 {
-		channels := sets.New[string]()
-		for _, c := range pkgConfig.Channels {
-			channels.Insert(c.Name)
-		}
-		for _, ch := range p.Channels {
-			if !channels.Has(ch.Name) {
-				delete(p.Channels, ch.Name)
-			}
-		}
-	}
-	if err := setDefaultChannel(p, pkgConfig, warnf); err != nil {
-		return fmt.Errorf("invalid default channel filter configuration: %v", err)
-	}
-	return nil
-}
-
-func setDefaultChannel(p *model.Package, pkgConfig v1.Package, warnf logFunc) error {
-	// lots of complexity here. let's enumerate the cases
-	// 1. when default channel is set in the package config
-	//    a. if the configured channel exists after filtering, update model's default channel
-	//    b. if the configured channel does not exist after filtering
-	//       i. does the original model's default channel exist after filtering?
-	//          - if yes: warn: specified default channel override does not exist, keeping original default channel from catalog
-	//          - if no: specified default channel override does not exist, and original default channel does not exist
-	// 2. when the default channel is not set in the package config
-	//    a. if the original model's default channel does not exist after filtering, error: "default channel must be configured"
-
-	_, defaultChannelStillExists := p.Channels[p.DefaultChannel.Name]
-	if pkgConfig.DefaultChannel != "" {
-		if configDefaultChannel, ok := p.Channels[pkgConfig.DefaultChannel]; ok {
-			p.DefaultChannel = configDefaultChannel
-		} else if defaultChannelStillExists {
-			warnf("specified default channel override %q does not exist, keeping original default channel from catalog", pkgConfig.DefaultChannel)
-		} else {
-			return fmt.Errorf("specified default channel override %q does not exist, and original default channel %q does not exist", pkgConfig.DefaultChannel, p.DefaultChannel.Name)
-		}
-		return nil
-	}
-	if !defaultChannelStillExists {
-		return fmt.Errorf("the default channel %q was filtered out, a new default channel must be configured in the FilterConfiguration for this package", p.DefaultChannel.Name)
-	}
-	return nil
-}
-
-func filterBundles(ch *model.Channel, channelConfig v1.Channel, warnf logFunc) error {
-	// we need to keep a single coherent channel head, which might mean including one extra bundle that falls outside
-	// the minVersion/maxVersion range. this case happens when a bundle on the replaces chain:
-	//   1. is not in the minVersion/maxVersion range
-	//   2. contains a bundle in its replaces chain that is in the minVersion/maxVersion range
-	//   3. contains a bundle in its skips list that is in the minVersion/maxVersion range
-	// if this happens, we will emit a warning and include the bundle as the new channel head.
-
-	cur, err := ch.Head()
-	if err != nil {
-		return fmt.Errorf("error getting head of channel %q: %v", ch.Name, err)
-	}
-
-	versionRange, err := mmsemver.NewConstraint(channelConfig.VersionRange)
-	if err != nil {
-		return fmt.Errorf("invalid version range %q for channel %q: %v", channelConfig.VersionRange, ch.Name, err)
-	}
-
-	var head *model.Bundle
-	for cur != nil && head == nil {
-		curVersion := blangToMM(cur.Version)
-		if versionRange.Check(curVersion) {
-			head = cur
-			break
-		}
-		for _, skip := range cur.Skips {
-			skipBundle, ok := ch.Bundles[skip]
-			if !ok {
-				continue
-			}
-			skipVersion := blangToMM(skipBundle.Version)
-			if versionRange.Check(skipVersion) {
-				head = cur
-				break
-			}
-		}
-		cur = ch.Bundles[cur.Replaces]
-	}
-	var tail *model.Bundle
-	for cur != nil {
-		if !isOrContainsBundleInVersionRange(cur, versionRange, ch) {
-			tail = cur
-			break
-		}
-		cur = ch.Bundles[cur.Replaces]
-	}
-
-	// we how have head and tail, let's traverse head to tail and build a list of bundles to keep
-	// warn if anything in the replaces chain is not in the version range
-	bundles := map[string]*model.Bundle{}
-	for cur = head; cur != tail; cur = ch.Bundles[cur.Replaces] {
-		curVersion := blangToMM(cur.Version)
-		if !versionRange.Check(curVersion) {
-			warnf("including bundle %q with version %q in channel %q for package %q: it falls outside the specified range of %q but is required to ensure inclusion of all bundles in the range", cur.Name, curVersion.String(), ch.Name, ch.Package.Name, channelConfig.VersionRange)
-		}
-		bundles[cur.Name] = cur
-		for _, skip := range cur.Skips {
-			if skipBundle, ok := ch.Bundles[skip]; ok {
-				skipVersion := blangToMM(skipBundle.Version)
-				if versionRange.Check(skipVersion) {
-					bundles[skipBundle.Name] = skipBundle
-				}
-			}
-		}
-	}
-	if len(bundles) == 0 {
-		return fmt.Errorf("invalid filter configuration: no bundles in channel %q for package %q matched the version range %q", ch.Name, ch.Package.Name, channelConfig.VersionRange)
-	}
-	ch.Bundles = bundles
-	return nil
-}
+			opts := []filter.Option{
+				filter.WithWarnings(warnf),
+				filter.WithLogger(logger),
+				filter.WithKeepReferencedChannels(keepReferencedChannels),
+				filter.WithRequireFullReachability(requireFullReachability),
+				filter.WithEnforceMonotonicChain(monotonicChain),
+				filter.WithInventory(inv),
+				filter.WithExcludeChannels(excludeChannels),
+				filter.WithChannelPolicy(policy),
+				filter.WithMaxTotalBundles(maxTotalBundles, maxTotalBundlesStrategy),
+				filter.WithAssertHeadsUnchanged(headsUnchanged),
+				filter.WithExcludeDeprecated(excludeDeprecatedArg),
+				filter.WithDropDanglingEdges(dropDanglingEdges),
+				filter.WithRollbackSafe(rollbackSafe),
+				filter.WithBestEffort(bestEffort),
+				filter.WithWorkers(workers),
+			}
+			if annotateAttributionArg {
+				opts = append(opts, filter.WithAttribution(writtenPackages))
+			}
+			var sourceHeads filter.ChannelHeads
+			if explainHead {
+				if sourceHeads, err = filter.RecordChannelHeads(m); err != nil {
+					fatalf("error recording channel heads: %v", err)
+				}
+			}
+			var source catalogSnapshot
+			if dryRun {
+				source = snapshotModel(m)
+			}
+			inputCounts := countModel(m)
+			result, err := filter.FilterModel(m, config, opts...)
+			if err != nil {
+				fatalf("error filtering input: %v", err)
+			}
+			result.Warnings = append(globWarnings, result.Warnings...)
+			// with --best-effort, the packages that could not be filtered are reported once the rest of the catalog
+			// has been written.
+			exitOnPackageErrors := func() {
+				if len(result.Errors) == 0 {
+					return
+				}
+				for _, e := range result.Errors {
+					logger.Error(e.Error(), "package", e.Package)
+				}
+				fatalf("error filtering input: %d packages could not be filtered and were left out of the output", len(result.Errors))
+			}
+			if strictFailures > 0 {
+				fatalf("error filtering input: %d warnings indicate data issues, which fail the filter in --strict mode", strictFailures)
+			}
+			if expectedHeads != nil {
+				if err := checkExpectedHeads(m, expectedHeads); err != nil {
+					fatalf("filtered catalog does not have the expected heads: %v", err)
+				}
+			}
+			if approved != nil {
+				if unapproved := unapprovedBundles(m, approved); len(unapproved) > 0 {
+					for _, u := range unapproved {
+						if approvalsWarnOnly {
+							logger.Warn(u)
+						} else {
+							logger.Error(u)
+						}
+					}
+					if !approvalsWarnOnly {
+						fatalf("error filtering input: %d kept bundles are not approved by %s", len(unapproved), approvalsFile)
+					}
+				}
+			}
+			if explainHead {
+				explanations, err := filter.ExplainHeads(m, sourceHeads, config, opts...)
+				if err != nil {
+					fatalf("error explaining channel heads: %v", err)
+				}
+				for _, e := range explanations {
+					logger.Info(e)
+				}
+			}
+			if !keepPackageIcon {
+				for _, pkg := range m {
+					pkg.Icon = nil
+				}
+			}
+			fbc := filteredCatalog(m, deprecations, others)
+			if annotateDeprecated {
+				annotateDeprecations(&fbc, m)
+			}
+			if annotateAttributionArg {
+				filter.AnnotateAttribution(&fbc, result.Attributions)
+			}
+			if err := normalizePropertyStyle(&fbc, propertyStyle, warnf); err != nil {
+				fatalf("error normalizing bundle properties: %v", err)
+			}
+			if err := sortChannelEntries(&fbc, sortBundlesBy); err != nil {
+				fatalf("error sorting channel entries: %v", err)
+			}
+			if err := checkOLMCompatibility(fbc, config.TargetOLMVersion, warnf); err != nil {
+				fatalf("error checking OLM compatibility: %v", err)
+			}
+			if reportFile != "" {
+				if err := writeFilterReport(newFilterReport(inputCounts, m, result), reportFile); err != nil {
+					fatalf("error writing report: %v", err)
+				}
+			}
+			if dryRun {
+				writeDryRunSummary(os.Stdout, source, m)
+				exitOnPackageErrors()
+				return
+			}
 
-func isOrContainsBundleInVersionRange(b *model.Bundle, versionRange *mmsemver.Constraints, ch *model.Channel) bool {
-	bVersion := blangToMM(b.Version)
-	if versionRange.Check(bVersion) {
-		return true
-	}
-	for _, skip := range b.Skips {
-		if skipBundle, ok := ch.Bundles[skip]; ok {
-			skipVersion := blangToMM(skipBundle.Version)
-			if versionRange.Check(skipVersion) {
-				return true
+			if splitByMajor {
+				splits, err := splitByMajorVersion(fbc)
+				if err != nil {
+					fatalf("error splitting output: %v", err)
+				}
+				if err := writeSplitOutputs(splits, outputTargets); err != nil {
+					fatalf("error writing output: %v", err)
+				}
+			} else if err := writeOutputs(fbc, outputTargets); err != nil {
+				fatalf("error writing output: %v", err)
 			}
-		}
-	}
-	if replacesBundle, ok := ch.Bundles[b.Replaces]; ok {
-		return isOrContainsBundleInVersionRange(replacesBundle, versionRange, ch)
+			if pushImage != "" {
+				var authDir string
+				if pullSecret != "" || registryConfig != "" {
+					if authDir, err = writeAuthConfig(pullSecret, registryConfig); err != nil {
+						fatalf("error loading registry credentials: %v", err)
+					}
+				}
+				err := pushCatalogImage(cmd.Context(), fbc, pushImage, authDir, registryOpts)
+				if authDir != "" {
+					os.RemoveAll(authDir)
+				}
+				if err != nil {
+					fatalf("error pushing catalog image: %v", err)
+				}
+			}
+			if printHash {
+				hash, err := catalogHash(fbc)
+				if err != nil {
+					fatalf("error hashing output: %v", err)
+				}
+				fmt.Println(hash)
+			}
+			if stateFile != "" {
+				if err := writeState(m, stateFile); err != nil {
+					fatalf("error writing state: %v", err)
+				}
+			}
+			if provenanceFile != "" {
+				sourceOf := func(pkg string) string {
+					if source, ok := sources[pkg]; ok {
+						return source
+					}
+					if inputModel != "" {
+						return inputModel
+					}
+					return args[0]
+				}
+				if err := writeProvenance(m, sourceOf, provenanceFile); err != nil {
+					fatalf("error writing provenance: %v", err)
+				}
+			}
+			exitOnPackageErrors()
+		},
 	}
-	return false
-}
-
-func blangToMM(in blangsemver.Version) *mmsemver.Version {
-	pres := make([]string, len(in.Pre))
-	for i, p := range in.Pre {
-		pres[i] = p.String()
+	cmd.Flags().BoolVar(&migrate, "migrate", false, "Migrate the input to the latest version (packages can override this with their migrate setting)")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Output format written to stdout (yaml, json, tar, tgz)")
+	cmd.Flags().StringArrayVarP(&outputFiles, "output-file", "f", nil, "Write the output to a file, in the format implied by its extension, replacing it only once the output is complete (can be repeated)")
+	cmd.Flags().IntVar(&outputIndent, "output-indent", 4, "Number of spaces to indent JSON output by; YAML output always uses two")
+	cmd.Flags().StringVar(&outputDir, "output-dir", "", "Write each package to <dir>/<package>/catalog.<ext>, and objects of no package to <dir>/others.<ext>, in the format selected by --output (default yaml); --output then no longer writes to stdout")
+	cmd.Flags().StringVar(&pushImage, "push", "", "Build a catalog image containing the filtered catalog and push it to this image reference")
+	cmd.Flags().BoolVar(&printHash, "print-hash", false, "Print a SHA256 digest of the filtered catalog's content to stdout; the catalog itself is only written to --output-file")
+	cmd.Flags().BoolVar(&splitByMajor, "split-by-major", false, "Write each package's bundles of each major version to separate files, named after each --output-file with -<package>-v<major> before the extension")
+	cmd.Flags().BoolVar(&keepPackageIcon, "keep-package-icon", false, "Keep each package's icon, which is stripped by default to reduce the output size")
+	cmd.Flags().StringVar(&propertyStyle, "property-style", propertyStyleKeepBoth, "Normalize kept bundles to a CSV property style (bundle-object, csv-metadata, keep-both)")
+	cmd.Flags().StringVar(&sortBundlesBy, "sort-bundles-by", sortBundlesByVersion, "Order of the bundles in each channel's entries (version: highest first, name: alphabetical)")
+	cmd.Flags().StringVar(&matchOrder, "match-order", "", "Order the output to follow an existing catalog file where possible, appending new packages, channels, bundles, and entries after the existing ones")
+	cmd.Flags().BoolVar(&strict, "strict", false, "Fail on warnings about data issues: missing packages or channels, channel patterns that match nothing, an ignored default channel override, bundles included outside their range, bundles without a minKubeVersion when filtering by kubeVersion, and normalized or missing bundle versions")
+	cmd.Flags().BoolVar(&keepReferencedChannels, "keep-referenced-channels", false, "Keep channels containing bundles referenced (via replaces, skips, or skipRange) by a kept channel")
+	cmd.Flags().BoolVar(&requireFullReachability, "require-full-reachability", false, "Fail if any kept bundle has no upgrade path to its channel head")
+	cmd.Flags().BoolVar(&monotonicChain, "enforce-monotonic-chain", false, "Fail if any kept bundle does not have a higher version than the bundles it replaces or skips")
+	cmd.Flags().BoolVar(&rollbackSafe, "rollback-safe", false, "Keep the bundle replaced by each kept bundle, except the oldest, so that every upgrade can be rolled back one step")
+	cmd.Flags().BoolVar(&normalizeVersions, "normalize-versions", false, "Rewrite bundle versions that are not valid semver (e.g. v1.2) to their semver equivalent instead of failing")
+	cmd.Flags().BoolVar(&annotateDeprecated, "annotate-deprecated", false, "Mark kept packages, channels, and bundles that are deprecated with a "+propertyTypeDeprecated+" property")
+	cmd.Flags().BoolVar(&annotateAttributionArg, "annotate-attribution", false, "Record the configuration rule that kept each bundle in a "+filter.PropertyTypeKeptBy+" property")
+	cmd.Flags().StringVar(&inventoryFile, "inventory", "", "Path to a list of exact package/version pairs to keep, used instead of the configured version ranges")
+	cmd.Flags().StringVar(&approvalsFile, "approvals", "", "Path to a list of approved bundle image digests; fail if any kept bundle's image is not pinned to one of them")
+	cmd.Flags().BoolVar(&approvalsWarnOnly, "approvals-warn-only", false, "Only warn about kept bundles that are not approved by --approvals instead of failing")
+	cmd.Flags().StringVar(&allowedChannelsFile, "allowed-channels-file", "", "Path to a list of the only channel names that may be kept in any package")
+	cmd.Flags().StringVar(&deniedChannelsFile, "denied-channels-file", "", "Path to a list of channel names that are never kept in any package")
+	cmd.Flags().BoolVar(&includeAllChannels, "include-all-channels", false, "Do not drop channels matching the defaultExcludeChannelRegex from packages that do not list their channels")
+	cmd.Flags().IntVar(&maxTotalBundles, "max-total-bundles", 0, "Maximum number of distinct bundles in the filtered catalog (0 means no limit)")
+	cmd.Flags().StringVar(&maxTotalBundlesStrategy, "max-total-bundles-strategy", filter.BundleBudgetStrategyError, "What to do when --max-total-bundles is exceeded (error, trim-oldest)")
+	cmd.Flags().BoolVar(&headsUnchanged, "assert-heads-unchanged", false, "Fail if a kept channel's head differs from the source catalog, unless the channel's versionRange, minVersion, maxVersion, or excludeVersions excludes the source head")
+	cmd.Flags().BoolVar(&excludeDeprecatedArg, "exclude-deprecated", false, "Remove deprecated packages, channels, and bundles, keeping deprecated bundles only where needed for channel coherence")
+	cmd.Flags().BoolVar(&dropDanglingEdges, "drop-dangling-edges", false, "Remove the replaces and skips of kept bundles that name filtered-out bundles; installations of those bundles can then no longer upgrade to the kept ones")
+	cmd.Flags().BoolVar(&bestEffort, "best-effort", false, "Leave out the packages that cannot be filtered instead of failing at the first one, write the rest, and then report every failed package and exit non-zero; bundles without a version are left out with a warning")
+	cmd.Flags().IntVar(&workers, "workers", 0, "Number of packages to filter at once (0 = number of CPUs)")
+	cmd.Flags().StringVar(&mergeStrategy, "merge-strategy", mergeStrategyError, "How to resolve a package defined by more than one catalog reference (error, prefer-first, prefer-last, prefer-higher-semver: the definition with the highest bundle version, the later one on ties)")
+	cmd.Flags().StringVar(&reportFile, "report", "", "Write a JSON report of the catalog's counts before and after filtering, the kept packages and channels, and the filter's warnings to this file")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print a summary of the packages, channels, and bundles that would be kept and dropped instead of writing, pushing, or hashing the filtered catalog and updating --state-file or --provenance-file")
+	cmd.Flags().BoolVar(&explainHead, "explain-head", false, "Log why each kept channel's head was chosen")
+	cmd.Flags().StringVar(&stateFile, "state-file", "", "File recording each channel's head after a run, which "+v1.LastHeadSentinel+" in a version range refers to on the next run")
+	cmd.Flags().StringVar(&expectedHeadsFile, "expected-heads", "", "Fail unless each channel listed in this file, which has the same format as --state-file, is kept with a head of the listed version")
+	cmd.Flags().StringVar(&provenanceFile, "provenance-file", "", "Write a JSON document mapping each kept bundle to its image digest and source catalog")
+	cmd.Flags().StringVar(&registryConfig, "registry-config", "", "Path to a docker config.json, as written by docker login or podman login --authfile, with credentials for pulling and pushing catalog images (default: the config.json in $DOCKER_CONFIG or ~/.docker)")
+	cmd.Flags().StringSliceVar(&allowedRefTypes, "allowed-ref-types", defaultAllowedRefTypes, "Catalog reference types that may be rendered (dc-dir, dc-image, sqlite-file, sqlite-image, bundle-image)")
+	cmd.Flags().BoolVar(&skipTLSVerify, "skip-tls-verify", false, "INSECURE: do not verify the TLS certificates of the registries that catalog images are pulled from and pushed to")
+	cmd.Flags().BoolVar(&useHTTP, "use-http", false, "INSECURE: connect to the registries that catalog images are pulled from and pushed to over plain HTTP")
+	cmd.Flags().StringVar(&pullSecret, "pull-secret", "", "Path to a Kubernetes pull secret or .dockerconfigjson file with credentials for pulling and pushing catalog images")
+	cmd.Flags().StringVar(&dumpModel, "dump-model", "", "Write the unfiltered catalog model to a file, for later use with --input-model")
+	cmd.Flags().StringVar(&inputModel, "input-model", "", "Filter a model previously written by --dump-model instead of rendering a catalog reference")
+	cmd.Flags().StringVarP(&configFile, "config", "c", "", "Path or http(s) URL of the filter configuration file, which may use the template functions env and default, e.g. {{ env \"MIN_VERSION\" }}")
+	cmd.Flags().StringArrayVar(&configHeaders, "config-header", nil, "HTTP header (\"Name: value\") to send when --config is a URL, e.g. for authentication (can be repeated)")
+	cmd.Flags().BoolVar(&allowInsecure, "allow-insecure-credentials", false, "INSECURE: send --config-header to a plain http:// --config URL, and --pull-secret or --registry-config credentials to registries with --use-http")
+	cmd.Flags().DurationVar(&configTimeout, "config-timeout", 30*time.Second, "Timeout for fetching --config when it is a URL")
+	cmd.Flags().StringArrayVar(&selects, "select", nil, "Select a package to keep without a configuration file, e.g. package=foo,channel=stable,version>=1.2.0 (can be repeated)")
+	cmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "Minimum level of the messages logged to stderr (debug, info, warn, error); debug also logs how each channel's head and tail were chosen")
+	cmd.PersistentFlags().StringVar(&logFormat, "log-format", logFormatText, "Format of the messages logged to stderr (text, json)")
+	cmd.MarkFlagsOneRequired("config", "select")
+	cmd.MarkFlagsMutuallyExclusive("config", "select")
+	cmd.AddCommand(newFormatsCmd())
+	cmd.AddCommand(newGraphCmd())
+	cmd.AddCommand(newTestCmd())
+	cmd.AddCommand(newServeCmd())
+	cmd.AddCommand(newValidateCmd())
+	cmd.AddCommand(newGenerateConfigCmd())
+	if executed, err := cmd.ExecuteC(); err != nil {
+		logFatalf(loggerFrom(executed.Context()), "error executing command: %v", err)
 	}
-	return mmsemver.New(
-		in.Major,
-		in.Minor,
-		in.Patch,
-		strings.Join(pres, "."),
-		strings.Join(in.Build, "."),
-	)
 }
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+
+	"fbc-filter/pkg/filter"
+)
+
+// runMainEnv, when set, makes the test binary run main instead of the tests, so that runMain can check the output and
+// exit code of a whole run.
+const runMainEnv = "FBC_FILTER_TEST_RUN_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runMainEnv) != "" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runMain runs fbc-filter with args in a subprocess, reading stdin, and returns what it wrote to stdout and stderr
+// along with its exit code.
+func runMain(t *testing.T, stdin string, args ...string) (string, string, int) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), runMainEnv+"=1")
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			t.Fatal(err)
+		}
+	}
+	return stdout.String(), stderr.String(), cmd.ProcessState.ExitCode()
+}
+
+// writeTestFile writes data to a file with the given name in a temporary directory and returns its path.
+func writeTestFile(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// catalogYAML returns the catalog as it would be read from a file.
+func catalogYAML(t *testing.T, fbc declcfg.DeclarativeConfig) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := declcfg.WriteYAML(fbc, &buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestBestEffortExit(t *testing.T) {
+	config := writeTestFile(t, "config.yaml", `apiVersion: olm.operatorframework.io/v1
+kind: FilterConfiguration
+packages:
+- name: foo
+  channels:
+  - name: stable
+    versionRange: ">=9.0.0"
+- name: bar
+`)
+	stdout, stderr, code := runMain(t, catalogYAML(t, outputCatalog()), "--config", config, "--best-effort", "-o", "yaml", "-")
+	if code == 0 {
+		t.Fatalf("expected a non-zero exit code, got 0; stderr:\n%s", stderr)
+	}
+	if !strings.Contains(stderr, "package=foo") || !strings.Contains(stderr, "1 packages could not be filtered") {
+		t.Errorf("expected the failed package foo to be reported, got:\n%s", stderr)
+	}
+	fbc, err := declcfg.LoadReader(strings.NewReader(stdout))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fbc.Packages) != 1 || fbc.Packages[0].Name != "bar" {
+		t.Errorf("expected only package bar to be written, got %+v", fbc.Packages)
+	}
+}
+
+func TestOutputIsDeterministic(t *testing.T) {
+	fbc := declcfg.DeclarativeConfig{}
+	for _, pkg := range []string{"foo", "bar", "baz", "qux"} {
+		fbc.Packages = append(fbc.Packages, declcfg.Package{Schema: declcfg.SchemaPackage, Name: pkg, DefaultChannel: "stable"})
+		stable := declcfg.Channel{Schema: declcfg.SchemaChannel, Package: pkg, Name: "stable"}
+		fast := declcfg.Channel{Schema: declcfg.SchemaChannel, Package: pkg, Name: "fast"}
+		for i, version := range []string{"1.0.0", "1.1.0", "1.2.0", "2.0.0"} {
+			name := pkg + ".v" + version
+			entry := declcfg.ChannelEntry{Name: name}
+			if i > 0 {
+				entry.Replaces = stable.Entries[i-1].Name
+			}
+			stable.Entries = append(stable.Entries, entry)
+			fast.Entries = append(fast.Entries, entry)
+			fbc.Bundles = append(fbc.Bundles, declcfg.Bundle{
+				Schema:     declcfg.SchemaBundle,
+				Name:       name,
+				Package:    pkg,
+				Image:      "example.com/" + pkg + ":v" + version,
+				Properties: []property.Property{property.MustBuildPackage(pkg, version)},
+			})
+		}
+		fbc.Channels = append(fbc.Channels, stable, fast)
+	}
+	config := writeTestFile(t, "config.yaml", `apiVersion: olm.operatorframework.io/v1
+kind: FilterConfiguration
+packages:
+- name: foo
+  channels:
+  - name: stable
+    versionRange: ">=1.1.0"
+  - name: fast
+- name: bar
+- name: baz
+  channels:
+  - name: fast
+    versionRange: "<2.0.0"
+  defaultChannel: fast
+- name: qux
+`)
+	input := catalogYAML(t, fbc)
+	var outputs []string
+	for i := 0; i < 2; i++ {
+		stdout, stderr, code := runMain(t, input, "--config", config, "--workers", "4", "-o", "json", "-")
+		if code != 0 {
+			t.Fatalf("unexpected exit code %d:\n%s", code, stderr)
+		}
+		outputs = append(outputs, stdout)
+	}
+	if !strings.Contains(outputs[0], `"qux.v2.0.0"`) {
+		t.Fatalf("expected the filtered catalog to be written, got:\n%s", outputs[0])
+	}
+	if outputs[0] != outputs[1] {
+		t.Errorf("expected both runs to write the same output, got\n%s\nand\n%s", outputs[0], outputs[1])
+	}
+}
+
+func TestChannelGlobWarningsReported(t *testing.T) {
+	config := writeTestFile(t, "config.yaml", `apiVersion: olm.operatorframework.io/v1
+kind: FilterConfiguration
+packages:
+- name: foo
+  channels:
+  - name: stable
+  - name: beta-*
+`)
+	reportFile := filepath.Join(t.TempDir(), "report.json")
+	_, stderr, code := runMain(t, catalogYAML(t, outputCatalog()), "--config", config, "--report", reportFile, "-o", "yaml", "-")
+	if code != 0 {
+		t.Fatalf("unexpected exit code %d:\n%s", code, stderr)
+	}
+	data, err := os.ReadFile(reportFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report filterReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Warnings) != 1 || report.Warnings[0].Code != filter.WarningChannelPatternNotMatched {
+		t.Errorf("expected a %s warning in the report, got %+v", filter.WarningChannelPatternNotMatched, report.Warnings)
+	}
+}
+
+func TestKeepPackageIcon(t *testing.T) {
+	fbc := outputCatalog()
+	fbc.Packages[0].Icon = &declcfg.Icon{Data: []byte("<svg/>"), MediaType: "image/svg+xml"}
+	input := catalogYAML(t, fbc)
+	tests := []struct {
+		name     string
+		args     []string
+		wantIcon bool
+	}{
+		{name: "stripped by default"},
+		{name: "kept", args: []string{"--keep-package-icon"}, wantIcon: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := writeTestFile(t, "config.yaml", testConfig)
+			stdout, stderr, code := runMain(t, input, append([]string{"--config", config, "-o", "yaml", "-"}, tt.args...)...)
+			if code != 0 {
+				t.Fatalf("unexpected exit code %d:\n%s", code, stderr)
+			}
+			out, err := declcfg.LoadReader(strings.NewReader(stdout))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(out.Packages) != 1 {
+				t.Fatalf("expected only package foo, got %+v", out.Packages)
+			}
+			if got := out.Packages[0].Icon != nil; got != tt.wantIcon {
+				t.Errorf("expected icon present to be %t, got %+v", tt.wantIcon, out.Packages[0].Icon)
+			}
+		})
+	}
+}
+
+func TestStrict(t *testing.T) {
+	// each case but the clean run causes warnings with the code it is named after.
+	tests := []struct {
+		name     string
+		packages string
+		args     []string
+		// catalog changes the catalog, which has package foo with a stable channel of 1.0.0 through 2.0.0.
+		catalog func(fbc *declcfg.DeclarativeConfig)
+	}{
+		{name: "clean", packages: "- name: foo\n  channels:\n  - name: stable\n    versionRange: \">=1.1.0\"\n"},
+		{name: string(filter.WarningPackageNotFound), packages: "- name: foo\n- name: missing\n"},
+		{name: string(filter.WarningChannelNotFound), packages: "- name: foo\n  channels:\n  - name: stable\n  - name: missing\n"},
+		{name: string(filter.WarningChannelPatternNotMatched), packages: "- name: foo\n  channels:\n  - name: stable\n  - name: beta-*\n"},
+		{name: string(filter.WarningDefaultChannelOverrideMissing), packages: "- name: foo\n  defaultChannel: missing\n"},
+		{name: string(filter.WarningOutOfRangeBundleIncluded), packages: "- name: foo\n  channels:\n  - name: stable\n    versionRange: \"<1.1.0 || >=1.2.0\"\n"},
+		{name: string(filter.WarningMinKubeVersionMissing), packages: "- name: foo\n  kubeVersion: 1.25.0\n"},
+		{
+			name:     string(filter.WarningVersionNormalized),
+			packages: "- name: foo\n",
+			args:     []string{"--normalize-versions"},
+			catalog: func(fbc *declcfg.DeclarativeConfig) {
+				fbc.Bundles[3].Properties = []property.Property{property.MustBuildPackage("foo", "v2.0.0")}
+			},
+		},
+		{
+			name:     string(filter.WarningBundleVersionUnknown),
+			packages: "- name: foo\n",
+			args:     []string{"--best-effort"},
+			catalog: func(fbc *declcfg.DeclarativeConfig) {
+				fbc.Bundles[0].Properties = nil
+			},
+		},
+	}
+	for _, tt := range tests {
+		fbc := declcfg.DeclarativeConfig{
+			Packages: []declcfg.Package{{Schema: declcfg.SchemaPackage, Name: "foo", DefaultChannel: "stable"}},
+			Channels: []declcfg.Channel{{Schema: declcfg.SchemaChannel, Package: "foo", Name: "stable"}},
+		}
+		for i, version := range []string{"1.0.0", "1.1.0", "1.2.0", "2.0.0"} {
+			entry := declcfg.ChannelEntry{Name: "foo.v" + version}
+			if i > 0 {
+				entry.Replaces = fbc.Channels[0].Entries[i-1].Name
+			}
+			fbc.Channels[0].Entries = append(fbc.Channels[0].Entries, entry)
+			fbc.Bundles = append(fbc.Bundles, declcfg.Bundle{
+				Schema:     declcfg.SchemaBundle,
+				Name:       "foo.v" + version,
+				Package:    "foo",
+				Image:      "example.com/foo:v" + version,
+				Properties: []property.Property{property.MustBuildPackage("foo", version)},
+			})
+		}
+		if tt.catalog != nil {
+			tt.catalog(&fbc)
+		}
+		input := catalogYAML(t, fbc)
+		for _, strict := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/strict=%t", tt.name, strict), func(t *testing.T) {
+				config := writeTestFile(t, "config.yaml", "apiVersion: olm.operatorframework.io/v1\nkind: FilterConfiguration\npackages:\n"+tt.packages)
+				args := append([]string{"--config", config, "-o", "yaml", "-"}, tt.args...)
+				if strict {
+					args = append(args, "--strict")
+				}
+				_, stderr, code := runMain(t, input, args...)
+				if tt.name == "clean" {
+					if code != 0 || strings.Contains(stderr, "code=") {
+						t.Errorf("expected a clean run to exit zero without warnings, got exit code %d:\n%s", code, stderr)
+					}
+					return
+				}
+				if !strings.Contains(stderr, "code="+tt.name) {
+					t.Errorf("expected a %s warning, got:\n%s", tt.name, stderr)
+				}
+				if !strict {
+					if code != 0 {
+						t.Errorf("expected exit code 0 without --strict, got %d:\n%s", code, stderr)
+					}
+					return
+				}
+				if code == 0 || !strings.Contains(stderr, "warnings indicate data issues") {
+					t.Errorf("expected --strict to fail the run, got exit code %d:\n%s", code, stderr)
+				}
+			})
+		}
+	}
+}
+
+func TestReadCatalogFromStdin(t *testing.T) {
+	const csvJSON = `{"kind":"ClusterServiceVersion","metadata":{"name":"foo.v1.0.0"},"spec":{"displayName":"Foo"}}`
+	fbc := outputCatalog()
+	fbc.Bundles[0].Properties = append(fbc.Bundles[0].Properties, property.MustBuildBundleObject([]byte(csvJSON)))
+	input := catalogYAML(t, fbc)
+	tests := []struct {
+		name string
+		args []string
+		// wantType is the type of the property that describes the CSV of the bundle after filtering.
+		wantType string
+	}{
+		{name: "as read", wantType: property.TypeBundleObject},
+		{name: "migrated", args: []string{"--migrate"}, wantType: property.TypeCSVMetadata},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := writeTestFile(t, "config.yaml", testConfig)
+			stdout, stderr, code := runMain(t, input, append([]string{"--config", config, "-o", "yaml", "-"}, tt.args...)...)
+			if code != 0 {
+				t.Fatalf("unexpected exit code %d:\n%s", code, stderr)
+			}
+			out, err := declcfg.LoadReader(strings.NewReader(stdout))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(out.Bundles) != 1 || out.Bundles[0].Name != "foo.v1.0.0" {
+				t.Fatalf("expected only bundle foo.v1.0.0, got %+v", out.Bundles)
+			}
+			var types []string
+			for _, p := range out.Bundles[0].Properties {
+				if p.Type == property.TypeBundleObject || p.Type == property.TypeCSVMetadata {
+					types = append(types, p.Type)
+				}
+			}
+			if want := []string{tt.wantType}; !reflect.DeepEqual(types, want) {
+				t.Errorf("expected CSV properties %v, got %v", want, types)
+			}
+		})
+	}
+}
+
+func TestWorkers(t *testing.T) {
+	tests := []struct {
+		name    string
+		workers string
+		wantErr string
+	}{
+		{name: "number of CPUs", workers: "0"},
+		{name: "fixed", workers: "2"},
+		{name: "negative", workers: "-1", wantErr: "--workers must not be negative"},
+	}
+	input := catalogYAML(t, outputCatalog())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := writeTestFile(t, "config.yaml", testConfig)
+			_, stderr, code := runMain(t, input, "--config", config, "--workers", tt.workers, "-o", "yaml", "-")
+			if tt.wantErr != "" {
+				if code == 0 || !strings.Contains(stderr, tt.wantErr) {
+					t.Fatalf("expected a failure containing %q, got exit code %d:\n%s", tt.wantErr, code, stderr)
+				}
+				return
+			}
+			if code != 0 {
+				t.Fatalf("unexpected exit code %d:\n%s", code, stderr)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	blangsemver "github.com/blang/semver/v4"
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// Merge strategies for packages that are defined by more than one catalog reference.
+const (
+	mergeStrategyError       = "error"
+	mergeStrategyPreferFirst = "prefer-first"
+	mergeStrategyPreferLast  = "prefer-last"
+	// mergeStrategyPreferHigherSemver keeps the definition whose highest bundle version, read from the olm.package
+	// property of the package's bundles in that catalog, is highest. A definition without bundles loses to one with
+	// bundles, ties go to the later reference, and a bundle version that does not parse as semver, even tolerantly,
+	// is an error.
+	mergeStrategyPreferHigherSemver = "prefer-higher-semver"
+)
+
+var mergeStrategies = []string{mergeStrategyError, mergeStrategyPreferFirst, mergeStrategyPreferLast, mergeStrategyPreferHigherSemver}
+
+func validateMergeStrategy(strategy string) error {
+	for _, s := range mergeStrategies {
+		if strategy == s {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid --merge-strategy %q: must be one of %s", strategy, strings.Join(mergeStrategies, ", "))
+}
+
+// mergeCatalogs merges the catalogs rendered from refs, in the same order. A package is taken whole from a single
+// catalog: with the error strategy a package defined by more than one catalog is an error, and otherwise the catalog
+// chosen by the strategy wins. Objects that do not belong to a package are kept from every catalog. The returned map
+// is the reference each package was taken from.
+func mergeCatalogs(refs []string, fbcs []*declcfg.DeclarativeConfig, strategy string) (*declcfg.DeclarativeConfig, map[string]string, error) {
+	owner := map[string]int{}
+	ownerVersion := map[string]*blangsemver.Version{}
+	var duplicates []string
+	for i, fbc := range fbcs {
+		for _, name := range sets.List(catalogPackages(fbc)) {
+			var version *blangsemver.Version
+			if strategy == mergeStrategyPreferHigherSemver {
+				v, err := highestBundleVersion(fbc, name)
+				if err != nil {
+					return nil, nil, fmt.Errorf("package %q in %q: %v", name, refs[i], err)
+				}
+				version = v
+			}
+			first, ok := owner[name]
+			switch {
+			case !ok, strategy == mergeStrategyPreferLast:
+				owner[name], ownerVersion[name] = i, version
+			case strategy == mergeStrategyPreferHigherSemver:
+				if prev := ownerVersion[name]; prev == nil || (version != nil && version.GE(*prev)) {
+					owner[name], ownerVersion[name] = i, version
+				}
+			case strategy == mergeStrategyError:
+				duplicates = append(duplicates, fmt.Sprintf("package %q is defined by both %q and %q", name, refs[first], refs[i]))
+			}
+		}
+	}
+	if len(duplicates) > 0 {
+		return nil, nil, fmt.Errorf("%s (use --merge-strategy to choose which definition to keep)", strings.Join(duplicates, "; "))
+	}
+
+	merged := &declcfg.DeclarativeConfig{}
+	for i, fbc := range fbcs {
+		keep := func(pkg string) bool { return pkg == "" || owner[pkg] == i }
+		for _, p := range fbc.Packages {
+			if keep(p.Name) {
+				merged.Packages = append(merged.Packages, p)
+			}
+		}
+		for _, ch := range fbc.Channels {
+			if keep(ch.Package) {
+				merged.Channels = append(merged.Channels, ch)
+			}
+		}
+		for _, b := range fbc.Bundles {
+			if keep(b.Package) {
+				merged.Bundles = append(merged.Bundles, b)
+			}
+		}
+		for _, d := range fbc.Deprecations {
+			if keep(d.Package) {
+				merged.Deprecations = append(merged.Deprecations, d)
+			}
+		}
+		for _, o := range fbc.Others {
+			if keep(o.Package) {
+				merged.Others = append(merged.Others, o)
+			}
+		}
+	}
+	sources := make(map[string]string, len(owner))
+	for name, i := range owner {
+		sources[name] = refs[i]
+	}
+	return merged, sources, nil
+}
+
+// highestBundleVersion returns the highest version of the package's bundles in the catalog, or nil if the catalog has
+// no bundles for the package.
+func highestBundleVersion(fbc *declcfg.DeclarativeConfig, pkg string) (*blangsemver.Version, error) {
+	var highest *blangsemver.Version
+	for _, b := range fbc.Bundles {
+		if b.Package != pkg {
+			continue
+		}
+		for _, p := range b.Properties {
+			if p.Type != property.TypePackage {
+				continue
+			}
+			var pp property.Package
+			if err := json.Unmarshal(p.Value, &pp); err != nil {
+				return nil, fmt.Errorf("bundle %q: invalid %s property: %v", b.Name, property.TypePackage, err)
+			}
+			v, err := blangsemver.ParseTolerant(pp.Version)
+			if err != nil {
+				return nil, fmt.Errorf("bundle %q: version %q is not valid semver: %v", b.Name, pp.Version, err)
+			}
+			if highest == nil || v.GT(*highest) {
+				highest = &v
+			}
+		}
+	}
+	return highest, nil
+}
+
+// catalogPackages returns the names of the packages that a catalog has any package, channel, or bundle objects for.
+func catalogPackages(fbc *declcfg.DeclarativeConfig) sets.Set[string] {
+	names := sets.New[string]()
+	for _, p := range fbc.Packages {
+		names.Insert(p.Name)
+	}
+	for _, ch := range fbc.Channels {
+		names.Insert(ch.Package)
+	}
+	for _, b := range fbc.Bundles {
+		names.Insert(b.Package)
+	}
+	return names
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+)
+
+func catalogWithVersions(pkg string, versions ...string) *declcfg.DeclarativeConfig {
+	fbc := &declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{{Schema: declcfg.SchemaPackage, Name: pkg}},
+	}
+	for _, v := range versions {
+		fbc.Bundles = append(fbc.Bundles, declcfg.Bundle{
+			Schema:     declcfg.SchemaBundle,
+			Name:       pkg + ".v" + v,
+			Package:    pkg,
+			Properties: []property.Property{property.MustBuildPackage(pkg, v)},
+		})
+	}
+	return fbc
+}
+
+func TestMergeCatalogsStrategies(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy string
+		first    *declcfg.DeclarativeConfig
+		second   *declcfg.DeclarativeConfig
+		want     string
+		wantErr  string
+	}{
+		{
+			name:     "error rejects the conflict",
+			strategy: mergeStrategyError,
+			first:    catalogWithVersions("foo", "1.0.0"),
+			second:   catalogWithVersions("foo", "2.0.0"),
+			wantErr:  `package "foo" is defined by both "a" and "b"`,
+		},
+		{
+			name:     "prefer-first",
+			strategy: mergeStrategyPreferFirst,
+			first:    catalogWithVersions("foo", "1.0.0"),
+			second:   catalogWithVersions("foo", "2.0.0"),
+			want:     "a",
+		},
+		{
+			name:     "prefer-last",
+			strategy: mergeStrategyPreferLast,
+			first:    catalogWithVersions("foo", "2.0.0"),
+			second:   catalogWithVersions("foo", "1.0.0"),
+			want:     "b",
+		},
+		{
+			name:     "prefer-higher-semver keeps the earlier higher version",
+			strategy: mergeStrategyPreferHigherSemver,
+			first:    catalogWithVersions("foo", "1.0.0", "2.1.0"),
+			second:   catalogWithVersions("foo", "2.0.0"),
+			want:     "a",
+		},
+		{
+			name:     "prefer-higher-semver keeps the later higher version",
+			strategy: mergeStrategyPreferHigherSemver,
+			first:    catalogWithVersions("foo", "1.2.0"),
+			second:   catalogWithVersions("foo", "1.10.0"),
+			want:     "b",
+		},
+		{
+			name:     "prefer-higher-semver ties go to the later reference",
+			strategy: mergeStrategyPreferHigherSemver,
+			first:    catalogWithVersions("foo", "1.0.0"),
+			second:   catalogWithVersions("foo", "1.0.0"),
+			want:     "b",
+		},
+		{
+			name:     "prefer-higher-semver prefers a definition with bundles",
+			strategy: mergeStrategyPreferHigherSemver,
+			first:    catalogWithVersions("foo", "1.0.0"),
+			second:   catalogWithVersions("foo"),
+			want:     "a",
+		},
+		{
+			name:     "prefer-higher-semver rejects an invalid version",
+			strategy: mergeStrategyPreferHigherSemver,
+			first:    catalogWithVersions("foo", "1.0.0"),
+			second:   catalogWithVersions("foo", "latest"),
+			wantErr:  `package "foo" in "b": bundle "foo.vlatest": version "latest" is not valid semver`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, sources, err := mergeCatalogs([]string{"a", "b"}, []*declcfg.DeclarativeConfig{tt.first, tt.second}, tt.strategy)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := sources["foo"]; got != tt.want {
+				t.Errorf("expected foo to come from %q, got %q", tt.want, got)
+			}
+			want := tt.first
+			if tt.want == "b" {
+				want = tt.second
+			}
+			if len(merged.Packages) != 1 || len(merged.Bundles) != len(want.Bundles) {
+				t.Errorf("expected 1 package and %d bundles, got %d packages and %d bundles", len(want.Bundles), len(merged.Packages), len(merged.Bundles))
+			}
+			for i := range merged.Bundles {
+				if merged.Bundles[i].Name != want.Bundles[i].Name {
+					t.Errorf("expected bundle %q, got %q", want.Bundles[i].Name, merged.Bundles[i].Name)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateMergeStrategy(t *testing.T) {
+	for _, s := range mergeStrategies {
+		if err := validateMergeStrategy(s); err != nil {
+			t.Errorf("expected %q to be valid, got %v", s, err)
+		}
+	}
+	if err := validateMergeStrategy("prefer-newest"); err == nil {
+		t.Error("expected an error for an unknown strategy")
+	}
+}
+
+func TestMergeCatalogs(t *testing.T) {
+	// each catalog defines its packages with a channel, a deprecation, and an object of an unknown schema, and has an
+	// object that belongs to no package.
+	catalog := func(ref string, pkgs ...string) *declcfg.DeclarativeConfig {
+		fbc := &declcfg.DeclarativeConfig{
+			Others: []declcfg.Meta{{Schema: "example.com/" + ref}},
+		}
+		for _, pkg := range pkgs {
+			fbc.Packages = append(fbc.Packages, declcfg.Package{Schema: declcfg.SchemaPackage, Name: pkg, DefaultChannel: "stable", Description: ref})
+			fbc.Channels = append(fbc.Channels, declcfg.Channel{Schema: declcfg.SchemaChannel, Package: pkg, Name: "stable", Entries: []declcfg.ChannelEntry{{Name: pkg + ".v1.0.0"}}})
+			fbc.Bundles = append(fbc.Bundles, declcfg.Bundle{
+				Schema:     declcfg.SchemaBundle,
+				Name:       pkg + ".v1.0.0",
+				Package:    pkg,
+				Image:      ref + "/" + pkg,
+				Properties: []property.Property{property.MustBuildPackage(pkg, "1.0.0")},
+			})
+			fbc.Deprecations = append(fbc.Deprecations, declcfg.Deprecation{Schema: declcfg.SchemaDeprecation, Package: pkg})
+			fbc.Others = append(fbc.Others, declcfg.Meta{Schema: "example.com/" + ref, Package: pkg})
+		}
+		return fbc
+	}
+
+	tests := []struct {
+		name     string
+		catalogs map[string][]string
+		strategy string
+		// want is the reference each package is taken from.
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name:     "disjoint catalogs",
+			catalogs: map[string][]string{"a": {"foo"}, "b": {"bar"}, "c": {"baz"}},
+			strategy: mergeStrategyError,
+			want:     map[string]string{"foo": "a", "bar": "b", "baz": "c"},
+		},
+		{
+			name:     "every duplicate is reported",
+			catalogs: map[string][]string{"a": {"foo", "bar"}, "b": {"foo"}, "c": {"bar", "baz"}},
+			strategy: mergeStrategyError,
+			wantErr:  `package "foo" is defined by both "a" and "b"; package "bar" is defined by both "a" and "c"`,
+		},
+		{
+			name:     "prefer-first across three catalogs",
+			catalogs: map[string][]string{"a": {"foo"}, "b": {"foo", "bar"}, "c": {"bar", "baz"}},
+			strategy: mergeStrategyPreferFirst,
+			want:     map[string]string{"foo": "a", "bar": "b", "baz": "c"},
+		},
+		{
+			name:     "prefer-last across three catalogs",
+			catalogs: map[string][]string{"a": {"foo", "bar"}, "b": {"foo"}, "c": {"bar", "baz"}},
+			strategy: mergeStrategyPreferLast,
+			want:     map[string]string{"foo": "b", "bar": "c", "baz": "c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refs := []string{"a", "b", "c"}
+			var fbcs []*declcfg.DeclarativeConfig
+			for _, ref := range refs {
+				fbcs = append(fbcs, catalog(ref, tt.catalogs[ref]...))
+			}
+			merged, sources, err := mergeCatalogs(refs, fbcs, tt.strategy)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(sources, tt.want) {
+				t.Errorf("expected sources %v, got %v", tt.want, sources)
+			}
+
+			// each package's objects come from its source alone.
+			for _, p := range merged.Packages {
+				if p.Description != tt.want[p.Name] {
+					t.Errorf("package %q: expected the definition of %q, got %q", p.Name, tt.want[p.Name], p.Description)
+				}
+			}
+			for _, b := range merged.Bundles {
+				if want := tt.want[b.Package] + "/" + b.Package; b.Image != want {
+					t.Errorf("bundle %q: expected image %q, got %q", b.Name, want, b.Image)
+				}
+			}
+			for _, o := range merged.Others {
+				if o.Package != "" && o.Schema != "example.com/"+tt.want[o.Package] {
+					t.Errorf("package %q: unexpected object of schema %q", o.Package, o.Schema)
+				}
+			}
+			wantCount := len(tt.want)
+			if len(merged.Packages) != wantCount || len(merged.Channels) != wantCount || len(merged.Bundles) != wantCount || len(merged.Deprecations) != wantCount {
+				t.Errorf("expected %d of each package object, got %d packages, %d channels, %d bundles, and %d deprecations", wantCount, len(merged.Packages), len(merged.Channels), len(merged.Bundles), len(merged.Deprecations))
+			}
+			// objects that belong to no package are kept from every catalog.
+			if got := len(merged.Others) - wantCount; got != len(refs) {
+				t.Errorf("expected %d objects without a package, got %d", len(refs), got)
+			}
+			if _, err := declcfg.ConvertToModel(*merged); err != nil {
+				t.Errorf("expected the merged catalog to be valid, got %v", err)
+			}
+		})
+	}
+}
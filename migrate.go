@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+// hasMigrateOverrides reports whether any package overrides the global --migrate setting.
+func hasMigrateOverrides(config v1.FilterConfiguration) bool {
+	for _, p := range config.Packages {
+		if p.Migrate != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// migratePackages migrates the bundles of each package whose migrate setting, or the global setting when the
+// package does not override it, is enabled. Migration replaces olm.bundle.object properties with a single
+// olm.csv.metadata property, like the catalog render performed with --migrate. Like that migration, it leaves the
+// olm.package property, and therefore the bundle's version, untouched.
+func migratePackages(fbc *declcfg.DeclarativeConfig, config v1.FilterConfiguration, migrate bool) error {
+	overrides := map[string]bool{}
+	for _, p := range config.Packages {
+		if p.Migrate != nil {
+			overrides[p.Name] = *p.Migrate
+		}
+	}
+	for i := range fbc.Bundles {
+		b := &fbc.Bundles[i]
+		migrateBundle, ok := overrides[b.Package]
+		if !ok {
+			migrateBundle = migrate
+		}
+		if !migrateBundle || b.Image == "" || b.CsvJSON == "" {
+			continue
+		}
+		if err := convertToCSVMetadata(b); err != nil {
+			return fmt.Errorf("could not migrate bundle %q to %s properties: %v", b.Name, property.TypeCSVMetadata, err)
+		}
+	}
+	return nil
+}
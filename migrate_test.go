@@ -0,0 +1,108 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+func TestMigratePackages(t *testing.T) {
+	const csvJSON = `{"kind":"ClusterServiceVersion","spec":{"displayName":"Foo"}}`
+	enabled, disabled := true, false
+	// catalog has a bundle with olm.bundle.object properties in each of the packages foo, bar, and baz.
+	catalog := func() *declcfg.DeclarativeConfig {
+		fbc := &declcfg.DeclarativeConfig{}
+		for _, pkg := range []string{"foo", "bar", "baz"} {
+			fbc.Bundles = append(fbc.Bundles, declcfg.Bundle{
+				Schema:  declcfg.SchemaBundle,
+				Name:    pkg + ".v1.0.0",
+				Package: pkg,
+				Image:   "example.com/" + pkg + ":v1.0.0",
+				CsvJSON: csvJSON,
+				Properties: []property.Property{
+					property.MustBuildPackage(pkg, "1.0.0"),
+					property.MustBuildBundleObject([]byte(csvJSON)),
+				},
+			})
+		}
+		return fbc
+	}
+	// foo always migrates and bar never does; baz follows the global setting.
+	config := v1.FilterConfiguration{Packages: []v1.Package{
+		{Name: "foo", Migrate: &enabled},
+		{Name: "bar", Migrate: &disabled},
+		{Name: "baz"},
+	}}
+	tests := []struct {
+		name    string
+		migrate bool
+		modify  func(*declcfg.DeclarativeConfig)
+		want    []string
+		wantErr string
+	}{
+		{name: "global migrate disabled", want: []string{"foo.v1.0.0"}},
+		{name: "global migrate enabled", migrate: true, want: []string{"foo.v1.0.0", "baz.v1.0.0"}},
+		{
+			name:   "bundle without an image",
+			modify: func(fbc *declcfg.DeclarativeConfig) { fbc.Bundles[0].Image = "" },
+		},
+		{
+			name:    "invalid CSV",
+			modify:  func(fbc *declcfg.DeclarativeConfig) { fbc.Bundles[0].CsvJSON = "{" },
+			wantErr: `could not migrate bundle "foo.v1.0.0" to olm.csv.metadata properties`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fbc := catalog()
+			if tt.modify != nil {
+				tt.modify(fbc)
+			}
+			err := migratePackages(fbc, config, tt.migrate)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got []string
+			for _, b := range fbc.Bundles {
+				if hasPropertyType(b.Properties, property.TypeCSVMetadata) {
+					if hasPropertyType(b.Properties, property.TypeBundleObject) {
+						t.Errorf("%s: expected %s properties to be removed", b.Name, property.TypeBundleObject)
+					}
+					got = append(got, b.Name)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected migrated bundles %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestHasMigrateOverrides(t *testing.T) {
+	enabled := true
+	tests := []struct {
+		name     string
+		packages []v1.Package
+		want     bool
+	}{
+		{name: "no packages"},
+		{name: "no overrides", packages: []v1.Package{{Name: "foo"}}},
+		{name: "override", packages: []v1.Package{{Name: "foo"}, {Name: "bar", Migrate: &enabled}}, want: true},
+	}
+	for _, tt := range tests {
+		if got := hasMigrateOverrides(v1.FilterConfiguration{Packages: tt.packages}); got != tt.want {
+			t.Errorf("%s: expected %t, got %t", tt.name, tt.want, got)
+		}
+	}
+}
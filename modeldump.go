@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	blangsemver "github.com/blang/semver/v4"
+	"github.com/operator-framework/operator-registry/alpha/model"
+	"github.com/operator-framework/operator-registry/alpha/property"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"fbc-filter/pkg/filter"
+)
+
+// modelDump is the serialized form of a model.Model written by --dump-model and read by --input-model. The model
+// itself can't be serialized directly because its packages, channels, and bundles point back at each other, so the
+// dump nests them instead and the links are restored when it is loaded.
+type modelDump struct {
+	Packages []packageDump `json:"packages"`
+}
+
+type packageDump struct {
+	Name           string             `json:"name"`
+	Description    string             `json:"description,omitempty"`
+	Icon           *model.Icon        `json:"icon,omitempty"`
+	DefaultChannel string             `json:"defaultChannel"`
+	Deprecation    *model.Deprecation `json:"deprecation,omitempty"`
+	Channels       []channelDump      `json:"channels"`
+}
+
+type channelDump struct {
+	Name        string              `json:"name"`
+	Deprecation *model.Deprecation  `json:"deprecation,omitempty"`
+	Properties  []property.Property `json:"properties,omitempty"`
+	Bundles     []bundleDump        `json:"bundles"`
+}
+
+type bundleDump struct {
+	Name          string               `json:"name"`
+	Image         string               `json:"image"`
+	Version       string               `json:"version"`
+	Replaces      string               `json:"replaces,omitempty"`
+	Skips         []string             `json:"skips,omitempty"`
+	SkipRange     string               `json:"skipRange,omitempty"`
+	Properties    []property.Property  `json:"properties,omitempty"`
+	RelatedImages []model.RelatedImage `json:"relatedImages,omitempty"`
+	Deprecation   *model.Deprecation   `json:"deprecation,omitempty"`
+	Objects       []string             `json:"objects,omitempty"`
+	CsvJSON       string               `json:"csvJson,omitempty"`
+}
+
+func writeModelDump(m model.Model, path string) error {
+	var dump modelDump
+	for _, pkgName := range sets.List(sets.KeySet(m)) {
+		pkg := m[pkgName]
+		pd := packageDump{
+			Name:        pkg.Name,
+			Description: pkg.Description,
+			Icon:        pkg.Icon,
+			Deprecation: pkg.Deprecation,
+		}
+		if pkg.DefaultChannel != nil {
+			pd.DefaultChannel = pkg.DefaultChannel.Name
+		}
+		for _, chName := range sets.List(sets.KeySet(pkg.Channels)) {
+			ch := pkg.Channels[chName]
+			cd := channelDump{
+				Name:        ch.Name,
+				Deprecation: ch.Deprecation,
+				Properties:  ch.Properties,
+			}
+			for _, bName := range sets.List(sets.KeySet(ch.Bundles)) {
+				b := ch.Bundles[bName]
+				cd.Bundles = append(cd.Bundles, bundleDump{
+					Name:          b.Name,
+					Image:         b.Image,
+					Version:       b.Version.String(),
+					Replaces:      b.Replaces,
+					Skips:         b.Skips,
+					SkipRange:     b.SkipRange,
+					Properties:    b.Properties,
+					RelatedImages: b.RelatedImages,
+					Deprecation:   b.Deprecation,
+					Objects:       b.Objects,
+					CsvJSON:       b.CsvJSON,
+				})
+			}
+			pd.Channels = append(pd.Channels, cd)
+		}
+		dump.Packages = append(dump.Packages, pd)
+	}
+
+	data, err := json.MarshalIndent(dump, "", "    ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func loadModelDump(path string) (model.Model, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var dump modelDump
+	if err := json.Unmarshal(data, &dump); err != nil {
+		return nil, fmt.Errorf("parse model dump: %v", err)
+	}
+
+	m := model.Model{}
+	for _, pd := range dump.Packages {
+		if _, ok := m[pd.Name]; ok {
+			return nil, fmt.Errorf("duplicate package %q", pd.Name)
+		}
+		pkg := &model.Package{
+			Name:        pd.Name,
+			Description: pd.Description,
+			Icon:        pd.Icon,
+			Deprecation: pd.Deprecation,
+			Channels:    map[string]*model.Channel{},
+		}
+		for _, cd := range pd.Channels {
+			ch := &model.Channel{
+				Package:     pkg,
+				Name:        cd.Name,
+				Deprecation: cd.Deprecation,
+				Properties:  cd.Properties,
+				Bundles:     map[string]*model.Bundle{},
+			}
+			for _, bd := range cd.Bundles {
+				version, err := blangsemver.Parse(bd.Version)
+				if err != nil {
+					return nil, fmt.Errorf("error parsing bundle %q version %q: %v", bd.Name, bd.Version, err)
+				}
+				props, err := property.Parse(bd.Properties)
+				if err != nil {
+					return nil, fmt.Errorf("parse properties for bundle %q: %v", bd.Name, err)
+				}
+				ch.Bundles[bd.Name] = &model.Bundle{
+					Package:       pkg,
+					Channel:       ch,
+					Name:          bd.Name,
+					Image:         bd.Image,
+					Replaces:      bd.Replaces,
+					Skips:         bd.Skips,
+					SkipRange:     bd.SkipRange,
+					Properties:    bd.Properties,
+					RelatedImages: bd.RelatedImages,
+					Deprecation:   bd.Deprecation,
+					Objects:       bd.Objects,
+					CsvJSON:       bd.CsvJSON,
+					PropertiesP:   props,
+					Version:       version,
+				}
+			}
+			pkg.Channels[ch.Name] = ch
+		}
+		pkg.DefaultChannel = pkg.Channels[pd.DefaultChannel]
+		m[pkg.Name] = pkg
+	}
+	if err := filter.ValidateModel(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
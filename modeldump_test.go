@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestModelDumpRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "first.json"), filepath.Join(dir, "second.json")
+	if err := writeModelDump(generateModel(t), first); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m, err := loadModelDump(first)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, pkg := range m {
+		if pkg.DefaultChannel == nil || pkg.DefaultChannel.Package != pkg {
+			t.Errorf("package %q: expected its default channel to be linked", pkg.Name)
+		}
+		for _, ch := range pkg.Channels {
+			for _, b := range ch.Bundles {
+				if b.Package != pkg || b.Channel != ch {
+					t.Errorf("bundle %q: expected it to be linked to its package and channel", b.Name)
+				}
+			}
+		}
+	}
+	if err := writeModelDump(m, second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want, err := os.ReadFile(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("expected the loaded model to dump the same\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestLoadModelDump(t *testing.T) {
+	const bundle = `{"name": "foo.v1.0.0", "image": "example.com/foo:v1.0.0", "version": "%s", "properties": [{"type": "olm.package", "value": {"packageName": "foo", "version": "1.0.0"}}]}`
+	pkg := func(defaultChannel, version string) string {
+		return `{"name": "foo", "defaultChannel": "` + defaultChannel + `", "channels": [{"name": "stable", "bundles": [` + fmt.Sprintf(bundle, version) + `]}]}`
+	}
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{name: "valid", data: `{"packages": [` + pkg("stable", "1.0.0") + `]}`},
+		{name: "not a dump", data: `[]`, wantErr: "parse model dump"},
+		{name: "duplicate package", data: `{"packages": [` + pkg("stable", "1.0.0") + `, ` + pkg("stable", "1.0.0") + `]}`, wantErr: `duplicate package "foo"`},
+		{name: "invalid version", data: `{"packages": [` + pkg("stable", "one") + `]}`, wantErr: `error parsing bundle "foo.v1.0.0" version "one"`},
+		{name: "invalid model", data: `{"packages": [` + pkg("fast", "1.0.0") + `]}`, wantErr: `invalid package "foo"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "model.json")
+			if err := os.WriteFile(path, []byte(tt.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := loadModelDump(path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"io"
+	"os"
+	"sort"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+)
+
+// referenceOrder is the order in which packages, channels, bundles, and channel entries appear in an existing
+// catalog file.
+type referenceOrder struct {
+	packages map[string]int
+	channels map[string]map[string]int
+	bundles  map[string]map[string]int
+	entries  map[string]map[string]map[string]int
+}
+
+func loadReferenceOrder(path string) (*referenceOrder, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fbc, err := declcfg.LoadReader(f)
+	if err != nil {
+		return nil, err
+	}
+
+	order := &referenceOrder{
+		packages: map[string]int{},
+		channels: map[string]map[string]int{},
+		bundles:  map[string]map[string]int{},
+		entries:  map[string]map[string]map[string]int{},
+	}
+	addPackage := func(name string) {
+		if _, ok := order.packages[name]; !ok {
+			order.packages[name] = len(order.packages)
+		}
+	}
+	for _, p := range fbc.Packages {
+		addPackage(p.Name)
+	}
+	for _, c := range fbc.Channels {
+		addPackage(c.Package)
+		if order.channels[c.Package] == nil {
+			order.channels[c.Package] = map[string]int{}
+			order.entries[c.Package] = map[string]map[string]int{}
+		}
+		order.channels[c.Package][c.Name] = len(order.channels[c.Package])
+		entries := map[string]int{}
+		for i, e := range c.Entries {
+			entries[e.Name] = i
+		}
+		order.entries[c.Package][c.Name] = entries
+	}
+	for _, b := range fbc.Bundles {
+		addPackage(b.Package)
+		if order.bundles[b.Package] == nil {
+			order.bundles[b.Package] = map[string]int{}
+		}
+		order.bundles[b.Package][b.Name] = len(order.bundles[b.Package])
+	}
+	return order, nil
+}
+
+// sortByReference stably sorts names so that those present in the reference come first, in reference order. The
+// remaining names keep their relative order after them.
+func sortByReference(names []string, reference map[string]int) {
+	sort.SliceStable(names, func(i, j int) bool {
+		ri, iok := reference[names[i]]
+		rj, jok := reference[names[j]]
+		if iok && jok {
+			return ri < rj
+		}
+		return iok && !jok
+	})
+}
+
+// writeFunc wraps write so that the catalog is written in reference order. Items that are not in the reference are
+// appended after the referenced items of the same kind, in the order the wrapped writer would use: packages,
+// channels, and bundles by name, and channel entries in their existing order. Within a package, the package, its
+// channels, its bundles, and then its other blobs are written as groups, just like the wrapped writer does.
+func (o *referenceOrder) writeFunc(write declcfg.WriteFunc) declcfg.WriteFunc {
+	return func(fbc declcfg.DeclarativeConfig, w io.Writer) error {
+		byPackage := map[string]*declcfg.DeclarativeConfig{}
+		forPackage := func(name string) *declcfg.DeclarativeConfig {
+			if byPackage[name] == nil {
+				byPackage[name] = &declcfg.DeclarativeConfig{}
+			}
+			return byPackage[name]
+		}
+		for _, p := range fbc.Packages {
+			forPackage(p.Name).Packages = append(forPackage(p.Name).Packages, p)
+		}
+		for _, c := range fbc.Channels {
+			forPackage(c.Package).Channels = append(forPackage(c.Package).Channels, c)
+		}
+		for _, b := range fbc.Bundles {
+			forPackage(b.Package).Bundles = append(forPackage(b.Package).Bundles, b)
+		}
+		for _, m := range fbc.Others {
+			forPackage(m.Package).Others = append(forPackage(m.Package).Others, m)
+		}
+		for _, d := range fbc.Deprecations {
+			forPackage(d.Package).Deprecations = append(forPackage(d.Package).Deprecations, d)
+		}
+
+		pkgNames := make([]string, 0, len(byPackage))
+		for name := range byPackage {
+			pkgNames = append(pkgNames, name)
+		}
+		sort.Strings(pkgNames)
+		sortByReference(pkgNames, o.packages)
+
+		for _, pkgName := range pkgNames {
+			pkg := byPackage[pkgName]
+			if len(pkg.Packages) > 0 {
+				if err := write(declcfg.DeclarativeConfig{Packages: pkg.Packages}, w); err != nil {
+					return err
+				}
+			}
+
+			channels := map[string]declcfg.Channel{}
+			chNames := []string{}
+			for _, c := range pkg.Channels {
+				channels[c.Name] = c
+				chNames = append(chNames, c.Name)
+			}
+			sort.Strings(chNames)
+			sortByReference(chNames, o.channels[pkgName])
+			for _, chName := range chNames {
+				c := channels[chName]
+				c.Entries = o.sortEntries(pkgName, c)
+				if err := write(declcfg.DeclarativeConfig{Channels: []declcfg.Channel{c}}, w); err != nil {
+					return err
+				}
+			}
+
+			bundles := map[string]declcfg.Bundle{}
+			bundleNames := []string{}
+			for _, b := range pkg.Bundles {
+				bundles[b.Name] = b
+				bundleNames = append(bundleNames, b.Name)
+			}
+			sort.Strings(bundleNames)
+			sortByReference(bundleNames, o.bundles[pkgName])
+			for _, bundleName := range bundleNames {
+				if err := write(declcfg.DeclarativeConfig{Bundles: []declcfg.Bundle{bundles[bundleName]}}, w); err != nil {
+					return err
+				}
+			}
+
+			if len(pkg.Others) > 0 || len(pkg.Deprecations) > 0 {
+				if err := write(declcfg.DeclarativeConfig{Others: pkg.Others, Deprecations: pkg.Deprecations}, w); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+}
+
+func (o *referenceOrder) sortEntries(pkgName string, c declcfg.Channel) []declcfg.ChannelEntry {
+	entries := make([]declcfg.ChannelEntry, len(c.Entries))
+	copy(entries, c.Entries)
+	reference := o.entries[pkgName][c.Name]
+	sort.SliceStable(entries, func(i, j int) bool {
+		ri, iok := reference[entries[i].Name]
+		rj, jok := reference[entries[j].Name]
+		if iok && jok {
+			return ri < rj
+		}
+		return iok && !jok
+	})
+	return entries
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+)
+
+func TestReferenceOrder(t *testing.T) {
+	// the reference lists foo before bar, the candidate channel before stable, and the stable entries and foo
+	// bundles in descending order.
+	reference := `---
+schema: olm.package
+name: foo
+---
+schema: olm.channel
+package: foo
+name: candidate
+entries:
+- name: foo.v2.0.0
+---
+schema: olm.channel
+package: foo
+name: stable
+entries:
+- name: foo.v1.1.0
+- name: foo.v1.0.0
+---
+schema: olm.bundle
+package: foo
+name: foo.v1.1.0
+image: example.com/foo:v1.1.0
+---
+schema: olm.bundle
+package: foo
+name: foo.v1.0.0
+image: example.com/foo:v1.0.0
+---
+schema: olm.package
+name: bar
+`
+	path := filepath.Join(t.TempDir(), "reference.yaml")
+	if err := os.WriteFile(path, []byte(reference), 0644); err != nil {
+		t.Fatal(err)
+	}
+	order, err := loadReferenceOrder(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the catalog has a package baz, a channel fast, an entry foo.v1.2.0, and a bundle foo.v0.9.0 that are not in
+	// the reference.
+	fbc := declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{
+			{Schema: declcfg.SchemaPackage, Name: "bar"},
+			{Schema: declcfg.SchemaPackage, Name: "baz"},
+			{Schema: declcfg.SchemaPackage, Name: "foo"},
+		},
+		Channels: []declcfg.Channel{
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "fast"},
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "stable", Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v1.0.0"},
+				{Name: "foo.v1.2.0"},
+				{Name: "foo.v1.1.0"},
+			}},
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "candidate"},
+		},
+		Bundles: []declcfg.Bundle{
+			{Schema: declcfg.SchemaBundle, Package: "foo", Name: "foo.v0.9.0"},
+			{Schema: declcfg.SchemaBundle, Package: "foo", Name: "foo.v1.0.0"},
+			{Schema: declcfg.SchemaBundle, Package: "foo", Name: "foo.v1.1.0"},
+		},
+	}
+
+	var got []string
+	record := func(fbc declcfg.DeclarativeConfig, _ io.Writer) error {
+		for _, p := range fbc.Packages {
+			got = append(got, "package "+p.Name)
+		}
+		for _, c := range fbc.Channels {
+			got = append(got, "channel "+c.Name)
+			for _, e := range c.Entries {
+				got = append(got, "entry "+e.Name)
+			}
+		}
+		for _, b := range fbc.Bundles {
+			got = append(got, "bundle "+b.Name)
+		}
+		return nil
+	}
+	if err := order.writeFunc(record)(fbc, io.Discard); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{
+		"package foo",
+		"channel candidate",
+		"channel stable",
+		"entry foo.v1.1.0",
+		"entry foo.v1.0.0",
+		"entry foo.v1.2.0",
+		"channel fast",
+		"bundle foo.v1.1.0",
+		"bundle foo.v1.0.0",
+		"bundle foo.v0.9.0",
+		"package bar",
+		"package baz",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected order %v, got %v", want, got)
+	}
+}
+
+func TestLoadReferenceOrderErrors(t *testing.T) {
+	if _, err := loadReferenceOrder(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+	path := filepath.Join(t.TempDir(), "invalid.yaml")
+	if err := os.WriteFile(path, []byte("schema: olm.package\nname: [foo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadReferenceOrder(path); err == nil {
+		t.Error("expected an error for an invalid catalog")
+	}
+}
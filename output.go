@@ -0,0 +1,350 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// outputFormat is an encoding that the filtered catalog can be written in. Archive formats set tarEntries instead of
+// write: the catalog is written as a tar archive of per-package files encoded by tarEntries.
+type outputFormat struct {
+	name        string
+	description string
+	extensions  []string
+	write       declcfg.WriteFunc
+	tarEntries  declcfg.WriteFunc
+	gzip        bool
+}
+
+var outputFormats = []outputFormat{
+	{
+		name:        "yaml",
+		description: "YAML documents separated by ---",
+		extensions:  []string{".yaml", ".yml"},
+		write:       declcfg.WriteYAML,
+	},
+	{
+		name:        "json",
+		description: "A stream of indented JSON objects",
+		extensions:  []string{".json"},
+		write:       declcfg.WriteJSON,
+	},
+	{
+		name:        "tar",
+		description: "A tar archive with one <package>/catalog.yaml file per package",
+		extensions:  []string{".tar"},
+		tarEntries:  declcfg.WriteYAML,
+	},
+	{
+		name:        "tgz",
+		description: "A gzipped tar archive with one <package>/catalog.yaml file per package",
+		extensions:  []string{".tar.gz", ".tgz"},
+		tarEntries:  declcfg.WriteYAML,
+		gzip:        true,
+	},
+}
+
+// writeFunc returns the function that writes a whole catalog in this format.
+func (f outputFormat) writeFunc() declcfg.WriteFunc {
+	if f.tarEntries != nil {
+		return writeTar(f.tarEntries, f.gzip)
+	}
+	return f.write
+}
+
+// wrap replaces the function that encodes catalog objects with fn applied to it. For archive formats it wraps the
+// encoding of each package file.
+func (f outputFormat) wrap(fn func(declcfg.WriteFunc) declcfg.WriteFunc) outputFormat {
+	if f.tarEntries != nil {
+		f.tarEntries = fn(f.tarEntries)
+	} else {
+		f.write = fn(f.write)
+	}
+	return f
+}
+
+// extensionOf returns the extension of this format that path ends with, or the empty string.
+func (f outputFormat) extensionOf(path string) string {
+	for _, e := range f.extensions {
+		if strings.HasSuffix(path, e) {
+			return e
+		}
+	}
+	return ""
+}
+
+func outputFormatNames() []string {
+	names := make([]string, 0, len(outputFormats))
+	for _, f := range outputFormats {
+		names = append(names, f.name)
+	}
+	return names
+}
+
+func lookupOutputFormat(name string) (outputFormat, error) {
+	for _, f := range outputFormats {
+		if f.name == name {
+			return f, nil
+		}
+	}
+	return outputFormat{}, fmt.Errorf("invalid output format %q: must be one of %s", name, strings.Join(outputFormatNames(), ", "))
+}
+
+func outputFormatForFile(path string) (outputFormat, error) {
+	for _, f := range outputFormats {
+		if f.extensionOf(path) != "" {
+			return f, nil
+		}
+	}
+	return outputFormat{}, fmt.Errorf("cannot determine output format of file %q from its extension %q", path, filepath.Ext(path))
+}
+
+// outputTarget is a destination for the filtered catalog: a file, or a directory with one file per package. An
+// empty path and dir means stdout.
+type outputTarget struct {
+	path   string
+	dir    string
+	format outputFormat
+}
+
+// resolveOutputTargets determines where the filtered catalog should be written. Each output file is written in the
+// format implied by its extension. With an output directory, --output selects the format of the package files,
+// defaulting to yaml, and nothing is written to stdout. Otherwise stdout is written in the format given by --output,
+// which is only required when no output files are given.
+func resolveOutputTargets(output string, outputFiles []string, outputDir string) ([]outputTarget, error) {
+	var targets []outputTarget
+	if outputDir != "" {
+		name := output
+		if name == "" {
+			name = "yaml"
+		}
+		format, err := lookupOutputFormat(name)
+		if err != nil {
+			return nil, err
+		}
+		if format.tarEntries != nil {
+			return nil, fmt.Errorf("output format %q cannot be written to --output-dir: write the archive with --output-file instead", name)
+		}
+		targets = append(targets, outputTarget{dir: outputDir, format: format})
+	} else if output != "" || len(outputFiles) == 0 {
+		format, err := lookupOutputFormat(output)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, outputTarget{format: format})
+	}
+	for _, path := range outputFiles {
+		format, err := outputFormatForFile(path)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, outputTarget{path: path, format: format})
+	}
+	return targets, nil
+}
+
+// writeJSONIndented returns a WriteFunc that writes the same stream of JSON objects as declcfg.WriteJSON, indented
+// by the given number of spaces instead of four.
+func writeJSONIndented(indent int) declcfg.WriteFunc {
+	prefix := strings.Repeat(" ", indent)
+	return func(fbc declcfg.DeclarativeConfig, w io.Writer) error {
+		var buf bytes.Buffer
+		if err := declcfg.WriteJSON(fbc, &buf); err != nil {
+			return err
+		}
+		dec := json.NewDecoder(&buf)
+		for {
+			var obj json.RawMessage
+			if err := dec.Decode(&obj); err == io.EOF {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			var out bytes.Buffer
+			if err := json.Indent(&out, obj, "", prefix); err != nil {
+				return err
+			}
+			out.WriteByte('\n')
+			if _, err := out.WriteTo(w); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func writeOutputs(fbc declcfg.DeclarativeConfig, targets []outputTarget) error {
+	for _, t := range targets {
+		if t.dir != "" {
+			// like opm, each package is written to <dir>/<package>/catalog.<ext>.
+			if err := writeOutputDir(fbc, t); err != nil {
+				return fmt.Errorf("write %q: %v", t.dir, err)
+			}
+			continue
+		}
+		if t.path == "" {
+			if err := t.format.writeFunc()(fbc, os.Stdout); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := writeOutputFile(fbc, t); err != nil {
+			return fmt.Errorf("write %q: %v", t.path, err)
+		}
+	}
+	return nil
+}
+
+// writeOutputFile writes the catalog to a temporary file next to the target and renames it into place once it is
+// complete, so that a failed write never leaves a partial file at the target path.
+func writeOutputFile(fbc declcfg.DeclarativeConfig, t outputTarget) error {
+	f, err := os.CreateTemp(filepath.Dir(t.path), "."+filepath.Base(t.path)+".*")
+	if err != nil {
+		return err
+	}
+	err = closeAfter(f, func(w io.Writer) error {
+		return t.format.writeFunc()(fbc, w)
+	})
+	if err == nil {
+		// CreateTemp creates the file readable only by its owner.
+		err = os.Chmod(f.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), t.path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
+
+func writeOutputDir(fbc declcfg.DeclarativeConfig, t outputTarget) error {
+	return writePackageFiles(fbc, t.format.write, t.format.extensions[0], func(name string, data []byte) error {
+		path := filepath.Join(t.dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			return err
+		}
+		return os.WriteFile(path, data, 0666)
+	})
+}
+
+// writeTar returns a WriteFunc that writes the catalog as a tar archive, optionally gzipped, laid out the same way
+// as --output-dir. Entries carry no timestamps so that the same catalog always produces the same archive.
+func writeTar(entries declcfg.WriteFunc, compress bool) declcfg.WriteFunc {
+	return func(fbc declcfg.DeclarativeConfig, w io.Writer) error {
+		if compress {
+			zw := gzip.NewWriter(w)
+			return closeAfter(zw, func(w io.Writer) error {
+				return writeTar(entries, false)(fbc, w)
+			})
+		}
+		tw := tar.NewWriter(w)
+		dirs := sets.New[string]()
+		err := writePackageFiles(fbc, entries, ".yaml", func(name string, data []byte) error {
+			if dir := path.Dir(name); dir != "." && !dirs.Has(dir) {
+				dirs.Insert(dir)
+				if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: dir + "/", Mode: 0755, ModTime: time.Unix(0, 0)}); err != nil {
+					return err
+				}
+			}
+			if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0644, Size: int64(len(data)), ModTime: time.Unix(0, 0)}); err != nil {
+				return err
+			}
+			_, err := tw.Write(data)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		return tw.Close()
+	}
+}
+
+// writePackageFiles encodes each package of the catalog, along with its channels, bundles, deprecations and other
+// objects, and passes it to writeFile as <package>/catalog<ext>. Other objects that belong to no package are passed
+// as others<ext>.
+func writePackageFiles(fbc declcfg.DeclarativeConfig, write declcfg.WriteFunc, ext string, writeFile func(name string, data []byte) error) error {
+	byPackage := map[string]*declcfg.DeclarativeConfig{}
+	for _, p := range fbc.Packages {
+		byPackage[p.Name] = &declcfg.DeclarativeConfig{Packages: []declcfg.Package{p}}
+	}
+	for _, c := range fbc.Channels {
+		if pkg := byPackage[c.Package]; pkg != nil {
+			pkg.Channels = append(pkg.Channels, c)
+		}
+	}
+	for _, b := range fbc.Bundles {
+		if pkg := byPackage[b.Package]; pkg != nil {
+			pkg.Bundles = append(pkg.Bundles, b)
+		}
+	}
+	for _, d := range fbc.Deprecations {
+		if pkg := byPackage[d.Package]; pkg != nil {
+			pkg.Deprecations = append(pkg.Deprecations, d)
+		}
+	}
+	var global declcfg.DeclarativeConfig
+	for _, o := range fbc.Others {
+		if o.Package == "" {
+			global.Others = append(global.Others, o)
+		} else if pkg := byPackage[o.Package]; pkg != nil {
+			pkg.Others = append(pkg.Others, o)
+		}
+	}
+	for _, p := range fbc.Packages {
+		var buf bytes.Buffer
+		if err := write(*byPackage[p.Name], &buf); err != nil {
+			return err
+		}
+		if err := writeFile(path.Join(p.Name, "catalog"+ext), buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	if len(global.Others) > 0 {
+		var buf bytes.Buffer
+		if err := write(global, &buf); err != nil {
+			return err
+		}
+		if err := writeFile("others"+ext, buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func closeAfter(f io.WriteCloser, fn func(io.Writer) error) error {
+	if err := fn(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func newFormatsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "formats",
+		Short: "List the supported output formats",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			for _, f := range outputFormats {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", f.name, strings.Join(f.extensions, ","), f.description)
+			}
+			w.Flush()
+		},
+	}
+}
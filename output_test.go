@@ -0,0 +1,312 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+)
+
+// outputCatalog has packages foo and bar with one channel and bundle each.
+func outputCatalog() declcfg.DeclarativeConfig {
+	fbc := declcfg.DeclarativeConfig{}
+	for _, pkg := range []string{"foo", "bar"} {
+		fbc.Packages = append(fbc.Packages, declcfg.Package{Schema: declcfg.SchemaPackage, Name: pkg, DefaultChannel: "stable"})
+		fbc.Channels = append(fbc.Channels, declcfg.Channel{Schema: declcfg.SchemaChannel, Package: pkg, Name: "stable", Entries: []declcfg.ChannelEntry{{Name: pkg + ".v1.0.0"}}})
+		fbc.Bundles = append(fbc.Bundles, declcfg.Bundle{
+			Schema:     declcfg.SchemaBundle,
+			Name:       pkg + ".v1.0.0",
+			Package:    pkg,
+			Image:      "example.com/" + pkg + ":v1.0.0",
+			Properties: []property.Property{property.MustBuildPackage(pkg, "1.0.0")},
+		})
+	}
+	return fbc
+}
+
+// globalOther returns an object that belongs to no package.
+func globalOther() declcfg.Meta {
+	return declcfg.Meta{Schema: "custom.global", Blob: json.RawMessage(`{"schema":"custom.global","value":"shared"}`)}
+}
+
+func TestResolveOutputTargets(t *testing.T) {
+	tests := []struct {
+		name        string
+		output      string
+		outputFiles []string
+		outputDir   string
+		// want describes each target as "<format> <path>", with "-" for stdout and "<dir>/" for a directory.
+		want    []string
+		wantErr string
+	}{
+		{name: "stdout", output: "json", want: []string{"json -"}},
+		{name: "files", outputFiles: []string{"catalog.yml", "catalog.json"}, want: []string{"yaml catalog.yml", "json catalog.json"}},
+		{name: "stdout and files", output: "yaml", outputFiles: []string{"catalog.json"}, want: []string{"yaml -", "json catalog.json"}},
+		{name: "directory", outputDir: "catalog", want: []string{"yaml catalog/"}},
+		{name: "directory and files", output: "json", outputFiles: []string{"catalog.yaml"}, outputDir: "catalog", want: []string{"json catalog/", "yaml catalog.yaml"}},
+		{name: "no output", wantErr: `invalid output format ""`},
+		{name: "invalid format", output: "xml", wantErr: `invalid output format "xml": must be one of yaml, json`},
+		{name: "archive directory", output: "tar", outputDir: "catalog", wantErr: `output format "tar" cannot be written to --output-dir`},
+		{name: "unknown extension", outputFiles: []string{"catalog.txt"}, wantErr: `cannot determine output format of file "catalog.txt" from its extension ".txt"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targets, err := resolveOutputTargets(tt.output, tt.outputFiles, tt.outputDir)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got []string
+			for _, target := range targets {
+				path := target.path
+				switch {
+				case target.dir != "":
+					path = target.dir + "/"
+				case path == "":
+					path = "-"
+				}
+				got = append(got, target.format.name+" "+path)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected targets %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWriteOutputs(t *testing.T) {
+	dir := t.TempDir()
+	targets, err := resolveOutputTargets("", []string{filepath.Join(dir, "catalog.yaml"), filepath.Join(dir, "catalog.json")}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeOutputs(outputCatalog(), targets); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, target := range targets {
+		f, err := os.Open(target.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0644 {
+			t.Errorf("%s: expected mode 0644, got %v", target.path, info.Mode().Perm())
+		}
+		fbc, err := declcfg.LoadReader(f)
+		if err != nil {
+			t.Fatalf("%s: %v", target.path, err)
+		}
+		if len(fbc.Packages) != 2 || len(fbc.Bundles) != 2 {
+			t.Errorf("%s: expected 2 packages and 2 bundles, got %d and %d", target.path, len(fbc.Packages), len(fbc.Bundles))
+		}
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, ".*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 0 {
+		t.Errorf("expected no temporary files to be left behind, got %v", matches)
+	}
+}
+
+func TestWriteOutputDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "catalog")
+	targets, err := resolveOutputTargets("json", nil, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fbc := outputCatalog()
+	fbc.Others = append(fbc.Others, globalOther())
+	if err := writeOutputs(fbc, targets); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, pkg := range []string{"foo", "bar"} {
+		fbc, err := declcfg.LoadFS(context.Background(), os.DirFS(filepath.Join(dir, pkg)))
+		if err != nil {
+			t.Fatalf("%s: %v", pkg, err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, pkg, "catalog.json")); err != nil {
+			t.Errorf("%s: %v", pkg, err)
+		}
+		if len(fbc.Packages) != 1 || fbc.Packages[0].Name != pkg || len(fbc.Channels) != 1 || len(fbc.Bundles) != 1 {
+			t.Errorf("%s: expected only the package, its channel, and its bundle, got %+v", pkg, fbc)
+		}
+	}
+	others, err := os.ReadFile(filepath.Join(dir, "others.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(others), `"custom.global"`) {
+		t.Errorf("expected others.json to hold the object of no package, got %s", others)
+	}
+}
+
+func TestWriteJSONIndented(t *testing.T) {
+	fbc := declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{{Schema: declcfg.SchemaPackage, Name: "foo", DefaultChannel: "stable"}},
+		Channels: []declcfg.Channel{{Schema: declcfg.SchemaChannel, Package: "foo", Name: "stable", Entries: []declcfg.ChannelEntry{{Name: "foo.v1.0.0"}}}},
+	}
+	tests := []struct {
+		name   string
+		indent int
+		want   string
+	}{
+		{
+			name:   "two spaces",
+			indent: 2,
+			want: `{
+  "schema": "olm.package",
+  "name": "foo",
+  "defaultChannel": "stable"
+}
+{
+  "schema": "olm.channel",
+  "name": "stable",
+  "package": "foo",
+  "entries": [
+    {
+      "name": "foo.v1.0.0"
+    }
+  ]
+}
+`,
+		},
+		{
+			name:   "no indentation",
+			indent: 0,
+			want: `{
+"schema": "olm.package",
+"name": "foo",
+"defaultChannel": "stable"
+}
+{
+"schema": "olm.channel",
+"name": "stable",
+"package": "foo",
+"entries": [
+{
+"name": "foo.v1.0.0"
+}
+]
+}
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeJSONIndented(tt.indent)(fbc, &buf); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("expected\n%s\ngot\n%s", tt.want, buf.String())
+			}
+		})
+	}
+}
+
+func TestWriteTar(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+	}{
+		{name: "tar", format: "tar"},
+		{name: "gzipped tar", format: "tgz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, err := lookupOutputFormat(tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fbc := outputCatalog()
+			fbc.Others = append(fbc.Others, globalOther())
+			var first, second bytes.Buffer
+			for _, buf := range []*bytes.Buffer{&first, &second} {
+				if err := format.writeFunc()(fbc, buf); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			if !bytes.Equal(first.Bytes(), second.Bytes()) {
+				t.Error("expected writing the same catalog twice to produce the same archive")
+			}
+
+			var r io.Reader = &first
+			if format.gzip {
+				zr, err := gzip.NewReader(r)
+				if err != nil {
+					t.Fatal(err)
+				}
+				r = zr
+			}
+			tr := tar.NewReader(r)
+			var got []string
+			for {
+				h, err := tr.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if h.ModTime.Unix() != 0 {
+					t.Errorf("%s: expected no timestamp, got %v", h.Name, h.ModTime)
+				}
+				got = append(got, h.Name)
+				if h.Typeflag != tar.TypeReg {
+					continue
+				}
+				fbc, err := declcfg.LoadReader(tr)
+				if err != nil {
+					t.Fatalf("%s: %v", h.Name, err)
+				}
+				if h.Name == "others.yaml" {
+					if len(fbc.Packages) != 0 || len(fbc.Others) != 1 || fbc.Others[0].Schema != "custom.global" {
+						t.Errorf("%s: expected only the object of no package, got %+v", h.Name, fbc)
+					}
+					continue
+				}
+				if len(fbc.Packages) != 1 || fbc.Packages[0].Name+"/catalog.yaml" != h.Name {
+					t.Errorf("%s: expected only its own package, got %+v", h.Name, fbc.Packages)
+				}
+			}
+			want := []string{"foo/", "foo/catalog.yaml", "bar/", "bar/catalog.yaml", "others.yaml"}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("expected entries %v, got %v", want, got)
+			}
+		})
+	}
+}
+
+func TestFormatsCmd(t *testing.T) {
+	stdout, stderr, code := runMain(t, "", "formats")
+	if code != 0 {
+		t.Fatalf("unexpected exit code %d:\n%s", code, stderr)
+	}
+	var got []string
+	for _, line := range strings.Split(strings.TrimSpace(stdout), "\n") {
+		got = append(got, strings.Fields(line)[0])
+	}
+	if want := []string{"yaml", "json", "tar", "tgz"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected formats %v, got %v in:\n%s", want, got, stdout)
+	}
+}
@@ -0,0 +1,235 @@
+package filter
+
+import (
+	"regexp"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/model"
+	"github.com/operator-framework/operator-registry/alpha/property"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+// PropertyTypeKeptBy is the type of the property added by AnnotateAttribution to record which configuration
+// rule kept a bundle. A bundle in several channels gets one property per channel.
+const PropertyTypeKeptBy = "fbc-filter.kept-by"
+
+// The rules that can keep a bundle in a channel.
+const (
+	keptByVersionRange      = "versionRange"
+	keptByWithinOfHead      = "withinOfHead"
+	keptByKeepLatest        = "keepLatest"
+	keptByCoversVersion     = "coversVersion"
+	keptByInventory         = "inventory"
+	keptByChannel           = "channel"
+	keptByPackage           = "package"
+	keptByReferencedChannel = "referencedChannel"
+	keptByKeepHeadAlways    = "keepHeadAlways"
+	keptByRecommended       = "recommendedVersion"
+	keptByIncludeVersions   = "includeVersions"
+	keptByReleases          = "excludePrereleases"
+	keptByChainCoherence    = "chainCoherence"
+	keptByHeadOnly          = "headOnly"
+	keptByExpression        = "expression"
+	keptByMatchSkipRange    = "matchSkipRange"
+	keptByKubeVersion       = "kubeVersion"
+	keptByProvidedAPIs      = "providedAPIs"
+	keptByRollbackSafe      = "rollbackSafe"
+)
+
+// KeptBy identifies the configuration entry, by its index in the configuration's packages and its channel,
+// and the rule that kept a bundle. PackageIndex is -1 for a package that no entry configures, such as one added by a
+// packageSelector.
+type KeptBy struct {
+	PackageIndex int    `json:"packageIndex"`
+	Channel      string `json:"channel"`
+	Rule         string `json:"rule"`
+}
+
+func init() {
+	property.AddToScheme(PropertyTypeKeptBy, &KeptBy{})
+}
+
+// fixedRule returns a matcher rule that attributes every matched bundle to rule.
+func fixedRule(rule string) func(*model.Bundle) string {
+	return func(*model.Bundle) string { return rule }
+}
+
+// extendedRule returns the rule of a matcher that extends matcher to match more bundles: the bundles matcher matches
+// keep its rule, and the others are attributed to rule.
+func extendedRule(matcher bundleMatcher, rule string) func(*model.Bundle) string {
+	return func(b *model.Bundle) string {
+		if matcher.matches(b) {
+			return matcher.ruleOf(b)
+		}
+		return rule
+	}
+}
+
+// bundleRules records the rule that kept each bundle of a package while it is filtered, keyed by channel name and
+// then bundle name. A nil bundleRules records nothing, which is how filtering runs without WithAttribution.
+type bundleRules map[string]map[string]string
+
+// record attributes the bundles of a channel that was just filtered with the matcher: the bundles it matches to its
+// rule, and the others, which filtering kept to keep the channel coherent, to chainCoherence.
+func (r bundleRules) record(ch *model.Channel, matcher bundleMatcher) {
+	if r == nil {
+		return
+	}
+	rules := make(map[string]string, len(ch.Bundles))
+	for name, b := range ch.Bundles {
+		if !matcher.matches(b) {
+			rules[name] = keptByChainCoherence
+		} else if rule := matcher.ruleOf(b); rule != "" {
+			rules[name] = rule
+		}
+	}
+	r[ch.Name] = rules
+}
+
+// narrow updates the attribution of a channel that a later pass, such as kubeVersion, filtered again with the
+// matcher. The bundles it matches keep their rule, or get the matcher's rule if they had none, and the ones it kept
+// only for coherence are attributed to chainCoherence.
+func (r bundleRules) narrow(ch *model.Channel, matcher bundleMatcher) {
+	if r == nil {
+		return
+	}
+	rules := r[ch.Name]
+	if rules == nil {
+		rules = map[string]string{}
+		r[ch.Name] = rules
+	}
+	for name, b := range ch.Bundles {
+		if !matcher.matches(b) {
+			rules[name] = keptByChainCoherence
+		} else if _, ok := rules[name]; !ok {
+			if rule := matcher.ruleOf(b); rule != "" {
+				rules[name] = rule
+			}
+		}
+	}
+}
+
+// set attributes a bundle of a channel to rule.
+func (r bundleRules) set(channel, bundle, rule string) {
+	if r == nil {
+		return
+	}
+	if r[channel] == nil {
+		r[channel] = map[string]string{}
+	}
+	r[channel][bundle] = rule
+}
+
+// merge moves the attribution of the channels that the package's channel merges combine to the channels they are
+// merged into. A bundle in more than one of them keeps its attribution from the first, as it keeps its edges. It must
+// be called before the channels are merged.
+func (r bundleRules) merge(p *model.Package, merges []v1.ChannelMerge) {
+	if r == nil {
+		return
+	}
+	for _, mc := range merges {
+		merged := map[string]string{}
+		for _, name := range append([]string{mc.Into}, mc.From...) {
+			if _, ok := p.Channels[name]; !ok {
+				continue
+			}
+			for bundle, rule := range r[name] {
+				if _, ok := merged[bundle]; !ok {
+					merged[bundle] = rule
+				}
+			}
+			delete(r, name)
+		}
+		if len(merged) > 0 {
+			r[mc.Into] = merged
+		}
+	}
+}
+
+// complete returns the rule of every bundle of every channel of the filtered package. Bundles no rule was recorded
+// for were kept by their channel's configuration, or by the package's when it does not list channels, or because
+// their channel is referenced by a kept one.
+func (r bundleRules) complete(p *model.Package, pkgConfig v1.Package) map[string]map[string]string {
+	configured := sets.New[string]()
+	for _, c := range pkgConfig.Channels {
+		configured.Insert(c.Name)
+	}
+	for _, mc := range pkgConfig.MergeChannels {
+		configured.Insert(mc.Into)
+	}
+	complete := make(map[string]map[string]string, len(p.Channels))
+	for chName, ch := range p.Channels {
+		fallback := keptByChannel
+		switch {
+		case len(pkgConfig.Channels) == 0:
+			fallback = keptByPackage
+		case !configured.Has(chName):
+			fallback = keptByReferencedChannel
+		}
+		rules := make(map[string]string, len(ch.Bundles))
+		for name := range ch.Bundles {
+			rule, ok := r[chName][name]
+			if !ok {
+				rule = fallback
+			}
+			rules[name] = rule
+		}
+		complete[chName] = rules
+	}
+	return complete
+}
+
+// ConfigurationIndex returns the index of the entry that configures a package in a configuration's packages as
+// written: the first entry with its name or, failing that, the first whose NameRegex matches it, as when NameRegex
+// entries are expanded. It returns -1 for packages that no entry configures, such as those added by a
+// packageSelector.
+func ConfigurationIndex(packages []v1.Package, name string) int {
+	for i, p := range packages {
+		if p.NameRegex == "" && p.Name == name {
+			return i
+		}
+	}
+	for i, p := range packages {
+		if p.NameRegex == "" {
+			continue
+		}
+		if re, err := regexp.Compile(p.NameRegex); err == nil && re.MatchString(name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// attributeBundles returns the rules recorded while filtering each package of the filtered model, keyed by package
+// name and then bundle name, with one entry for each channel of the bundle in channel name order.
+func attributeBundles(m model.Model, outcomes map[string]packageOutcome, packages []v1.Package) map[string]map[string][]KeptBy {
+	attributions := map[string]map[string][]KeptBy{}
+	for _, pkgName := range sets.List(sets.KeySet(outcomes)) {
+		pkg, ok := m[pkgName]
+		if !ok {
+			continue
+		}
+		index := ConfigurationIndex(packages, pkgName)
+		attributions[pkgName] = map[string][]KeptBy{}
+		keptBy := outcomes[pkgName].keptBy
+		for _, chName := range sets.List(sets.KeySet(pkg.Channels)) {
+			for _, bName := range sets.List(sets.KeySet(pkg.Channels[chName].Bundles)) {
+				// the passes after the packages are filtered only remove bundles, so every bundle has a rule.
+				rule := keptBy[chName][bName]
+				attributions[pkgName][bName] = append(attributions[pkgName][bName], KeptBy{PackageIndex: index, Channel: chName, Rule: rule})
+			}
+		}
+	}
+	return attributions
+}
+
+// AnnotateAttribution adds the kept-by properties of each bundle to it.
+func AnnotateAttribution(fbc *declcfg.DeclarativeConfig, attributions map[string]map[string][]KeptBy) {
+	for i, b := range fbc.Bundles {
+		for _, a := range attributions[b.Package][b.Name] {
+			fbc.Bundles[i].Properties = append(fbc.Bundles[i].Properties, property.MustBuild(&a))
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package filter
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+// attributionCatalog has a package foo with a stable channel 1.0.0 -> 1.1.0 -> 1.2.0 -> 2.0.0, in which 1.2.0 also
+// skips 1.1.0 by skipRange and 1.1.0 needs Kubernetes 1.26, and a fast channel with only 2.1.0, which replaces the
+// stable head; and a package bar with a single bundle.
+func attributionCatalog() *declcfg.DeclarativeConfig {
+	bundle := func(pkg, version, minKubeVersion string) declcfg.Bundle {
+		return declcfg.Bundle{
+			Schema:  declcfg.SchemaBundle,
+			Name:    pkg + ".v" + version,
+			Package: pkg,
+			Image:   "example.com/" + pkg + ":v" + version,
+			Properties: []property.Property{
+				property.MustBuildPackage(pkg, version),
+				property.MustBuild(&property.CSVMetadata{MinKubeVersion: minKubeVersion}),
+			},
+		}
+	}
+	return &declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{
+			{Schema: declcfg.SchemaPackage, Name: "foo", DefaultChannel: "stable"},
+			{Schema: declcfg.SchemaPackage, Name: "bar", DefaultChannel: "alpha"},
+		},
+		Channels: []declcfg.Channel{
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "stable", Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v1.0.0"},
+				{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+				{Name: "foo.v1.2.0", Replaces: "foo.v1.1.0", SkipRange: ">=1.1.0 <1.2.0"},
+				{Name: "foo.v2.0.0", Replaces: "foo.v1.2.0"},
+			}},
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "fast", Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v2.1.0", Replaces: "foo.v2.0.0"},
+			}},
+			{Schema: declcfg.SchemaChannel, Package: "bar", Name: "alpha", Entries: []declcfg.ChannelEntry{
+				{Name: "bar.v0.1.0"},
+			}},
+		},
+		Bundles: []declcfg.Bundle{
+			bundle("foo", "1.0.0", "1.20.0"),
+			bundle("foo", "1.1.0", "1.26.0"),
+			bundle("foo", "1.2.0", "1.20.0"),
+			bundle("foo", "2.0.0", "1.20.0"),
+			bundle("foo", "2.1.0", "1.20.0"),
+			bundle("bar", "0.1.0", "1.20.0"),
+		},
+	}
+}
+
+func TestAttribution(t *testing.T) {
+	tests := []struct {
+		name string
+		foo  v1.Package
+		opts []Option
+		// want is the rule of each kept bundle of foo, as "<channel>/<bundle>".
+		want map[string]string
+	}{
+		{
+			name: "versionRange with keepHeadAlways",
+			foo:  v1.Package{Name: "foo", Channels: []v1.Channel{{Name: "stable", VersionRange: "<1.2.0", KeepHeadAlways: true}}},
+			want: map[string]string{
+				"stable/foo.v1.0.0": keptByVersionRange,
+				"stable/foo.v1.1.0": keptByVersionRange,
+				"stable/foo.v1.2.0": keptByChainCoherence,
+				"stable/foo.v2.0.0": keptByKeepHeadAlways,
+			},
+		},
+		{
+			name: "matchSkipRange",
+			foo:  v1.Package{Name: "foo", Channels: []v1.Channel{{Name: "stable", VersionRange: "<1.2.0", MatchSkipRange: true}}},
+			want: map[string]string{
+				"stable/foo.v1.0.0": keptByVersionRange,
+				"stable/foo.v1.1.0": keptByVersionRange,
+				"stable/foo.v1.2.0": keptByMatchSkipRange,
+			},
+		},
+		{
+			name: "keepLatest is not recomputed from the filtered head",
+			foo:  v1.Package{Name: "foo", Channels: []v1.Channel{{Name: "stable", KeepLatest: 2, ExcludeVersions: []string{"2.0.0"}}}},
+			want: map[string]string{
+				"stable/foo.v1.2.0": keptByKeepLatest,
+			},
+		},
+		{
+			name: "headOnly",
+			foo:  v1.Package{Name: "foo", Channels: []v1.Channel{{Name: "stable", HeadOnly: true}}},
+			want: map[string]string{
+				"stable/foo.v2.0.0": keptByHeadOnly,
+			},
+		},
+		{
+			name: "expression with includeVersions",
+			foo:  v1.Package{Name: "foo", Channels: []v1.Channel{{Name: "stable", Expression: `bundle.version == "2.0.0"`, IncludeVersions: []string{"1.1.0"}}}},
+			want: map[string]string{
+				"stable/foo.v1.1.0": keptByIncludeVersions,
+				"stable/foo.v1.2.0": keptByChainCoherence,
+				"stable/foo.v2.0.0": keptByExpression,
+			},
+		},
+		{
+			name: "kubeVersion on a channel without a selector",
+			foo:  v1.Package{Name: "foo", KubeVersion: "1.22.0", Channels: []v1.Channel{{Name: "stable"}}},
+			want: map[string]string{
+				"stable/foo.v1.0.0": keptByKubeVersion,
+				"stable/foo.v1.1.0": keptByChainCoherence,
+				"stable/foo.v1.2.0": keptByKubeVersion,
+				"stable/foo.v2.0.0": keptByKubeVersion,
+			},
+		},
+		{
+			name: "kubeVersion keeps a selected bundle for coherence",
+			foo:  v1.Package{Name: "foo", KubeVersion: "1.22.0", Channels: []v1.Channel{{Name: "stable", MinVersion: "1.0.0"}}},
+			want: map[string]string{
+				"stable/foo.v1.0.0": keptByVersionRange,
+				"stable/foo.v1.1.0": keptByChainCoherence,
+				"stable/foo.v1.2.0": keptByVersionRange,
+				"stable/foo.v2.0.0": keptByVersionRange,
+			},
+		},
+		{
+			name: "channels of a package that lists none",
+			foo:  v1.Package{Name: "foo"},
+			want: map[string]string{
+				"stable/foo.v1.0.0": keptByPackage,
+				"stable/foo.v1.1.0": keptByPackage,
+				"stable/foo.v1.2.0": keptByPackage,
+				"stable/foo.v2.0.0": keptByPackage,
+				"fast/foo.v2.1.0":   keptByPackage,
+			},
+		},
+		{
+			name: "coversVersion and a referenced channel",
+			foo:  v1.Package{Name: "foo", Channels: []v1.Channel{{Name: "fast", CoversVersion: "2.1.0"}}, DefaultChannel: "fast"},
+			opts: []Option{WithKeepReferencedChannels(true)},
+			want: map[string]string{
+				"fast/foo.v2.1.0":   keptByCoversVersion,
+				"stable/foo.v1.0.0": keptByReferencedChannel,
+				"stable/foo.v1.1.0": keptByReferencedChannel,
+				"stable/foo.v1.2.0": keptByReferencedChannel,
+				"stable/foo.v2.0.0": keptByReferencedChannel,
+			},
+		},
+		{
+			name: "inventory",
+			foo:  v1.Package{Name: "foo", Channels: []v1.Channel{{Name: "stable", HeadOnly: true}}},
+			opts: []Option{WithInventory(Inventory{"foo": {"1.1.0": {}}, "bar": {"0.1.0": {}}})},
+			want: map[string]string{
+				"stable/foo.v1.1.0": keptByInventory,
+			},
+		},
+		{
+			name: "merged channels keep the rules of their sources",
+			foo: v1.Package{
+				Name:          "foo",
+				Channels:      []v1.Channel{{Name: "stable", MinVersion: "1.2.0"}, {Name: "fast", HeadOnly: true}},
+				MergeChannels: []v1.ChannelMerge{{Into: "stable", From: []string{"fast"}}},
+			},
+			want: map[string]string{
+				"stable/foo.v1.2.0": keptByVersionRange,
+				"stable/foo.v2.0.0": keptByVersionRange,
+				"stable/foo.v2.1.0": keptByHeadOnly,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			written := []v1.Package{tt.foo, {NameRegex: "^ba"}}
+			config := v1.FilterConfiguration{
+				TypeMeta: metav1.TypeMeta{Kind: v1.Kind, APIVersion: v1.APIVersion},
+				Packages: written,
+			}
+			m, err := declcfg.ConvertToModel(*attributionCatalog())
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := FilterModel(m, config, append(tt.opts, WithAttribution(written))...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := map[string]string{}
+			for bundle, keptBy := range result.Attributions["foo"] {
+				for _, k := range keptBy {
+					if k.PackageIndex != 0 {
+						t.Errorf("%s: expected package index 0, got %d", bundle, k.PackageIndex)
+					}
+					got[fmt.Sprintf("%s/%s", k.Channel, bundle)] = k.Rule
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected rules %v, got %v", tt.want, got)
+			}
+			if bar := result.Attributions["bar"]["bar.v0.1.0"]; len(bar) != 1 || bar[0].PackageIndex != 1 {
+				t.Errorf("expected bar to be attributed to package entry 1, got %v", bar)
+			}
+		})
+	}
+}
+
+func TestConfigurationIndex(t *testing.T) {
+	packages := []v1.Package{{NameRegex: "^foo"}, {Name: "foo-operator"}, {NameRegex: "operator$"}}
+	tests := []struct {
+		name string
+		want int
+	}{
+		{name: "foo-operator", want: 1},
+		{name: "foo", want: 0},
+		{name: "bar-operator", want: 2},
+		{name: "bar", want: -1},
+	}
+	for _, tt := range tests {
+		if got := ConfigurationIndex(packages, tt.name); got != tt.want {
+			t.Errorf("%s: expected index %d, got %d", tt.name, tt.want, got)
+		}
+	}
+}
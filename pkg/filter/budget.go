@@ -0,0 +1,111 @@
+package filter
+
+import (
+	"fmt"
+
+	"github.com/operator-framework/operator-registry/alpha/model"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// The strategies for enforcing WithMaxTotalBundles: fail the filter, or trim the oldest bundles until the catalog is
+// within the limit.
+const (
+	BundleBudgetStrategyError      = "error"
+	BundleBudgetStrategyTrimOldest = "trim-oldest"
+)
+
+// ValidateBundleBudgetStrategy reports whether strategy is one of the bundle budget strategies.
+func ValidateBundleBudgetStrategy(strategy string) error {
+	switch strategy {
+	case BundleBudgetStrategyError, BundleBudgetStrategyTrimOldest:
+		return nil
+	}
+	return fmt.Errorf("invalid max total bundles strategy %q: must be one of %q or %q", strategy, BundleBudgetStrategyError, BundleBudgetStrategyTrimOldest)
+}
+
+// countBundles returns the number of distinct bundles in the model. A bundle that is a member of several channels is
+// only counted once.
+func countBundles(m model.Model) int {
+	total := 0
+	for _, pkg := range m {
+		bundles := sets.New[string]()
+		for _, ch := range pkg.Channels {
+			bundles = bundles.Union(sets.KeySet(ch.Bundles))
+		}
+		total += bundles.Len()
+	}
+	return total
+}
+
+// enforceBundleBudget ensures the model contains at most max distinct bundles. With the error strategy, exceeding the
+// budget is an error. With the trim-oldest strategy, bundles are removed one at a time from whichever channel
+// currently has the most bundles, so that channels shrink proportionally. From that channel, the lowest version
+// bundle that can be removed without stranding another bundle is dropped: a bundle is removable if it is not the
+// channel head and does not replace another bundle in the channel. Channel heads are never trimmed.
+func enforceBundleBudget(m model.Model, max int, strategy string, warnf logFunc) error {
+	total := countBundles(m)
+	if total <= max {
+		return nil
+	}
+	if strategy != BundleBudgetStrategyTrimOldest {
+		return fmt.Errorf("filtered catalog contains %d bundles, which exceeds the maximum of %d", total, max)
+	}
+
+	for total > max {
+		ch, b, err := nextBundleToTrim(m)
+		if err != nil {
+			return err
+		}
+		if b == nil {
+			return fmt.Errorf("filtered catalog contains %d bundles, which exceeds the maximum of %d, and no more bundles can be trimmed without removing a channel head", total, max)
+		}
+		warnf(Warning{Code: WarningBundleTrimmed, Package: ch.Package.Name, Channel: ch.Name, Bundle: b.Name}.withMessage("trimming bundle %q with version %q from channel %q in package %q to stay within the maximum of %d total bundles", b.Name, b.Version, ch.Name, ch.Package.Name, max))
+		delete(ch.Bundles, b.Name)
+		total = countBundles(m)
+	}
+	return nil
+}
+
+func nextBundleToTrim(m model.Model) (*model.Channel, *model.Bundle, error) {
+	var (
+		trimChannel *model.Channel
+		trimBundle  *model.Bundle
+	)
+	for _, pkgName := range sets.List(sets.KeySet(m)) {
+		pkg := m[pkgName]
+		for _, chName := range sets.List(sets.KeySet(pkg.Channels)) {
+			ch := pkg.Channels[chName]
+			if trimChannel != nil && len(ch.Bundles) <= len(trimChannel.Bundles) {
+				continue
+			}
+			b, err := oldestRemovableBundle(ch)
+			if err != nil {
+				return nil, nil, fmt.Errorf("package %q, channel %q: %v", pkgName, chName, err)
+			}
+			if b != nil {
+				trimChannel, trimBundle = ch, b
+			}
+		}
+	}
+	return trimChannel, trimBundle, nil
+}
+
+func oldestRemovableBundle(ch *model.Channel) (*model.Bundle, error) {
+	head, err := ch.Head()
+	if err != nil {
+		return nil, err
+	}
+	var oldest *model.Bundle
+	for _, b := range ch.Bundles {
+		if b == head {
+			continue
+		}
+		if _, ok := ch.Bundles[b.Replaces]; ok {
+			continue
+		}
+		if oldest == nil || b.Version.LT(oldest.Version) || (b.Version.EQ(oldest.Version) && b.Name < oldest.Name) {
+			oldest = b
+		}
+	}
+	return oldest, nil
+}
@@ -0,0 +1,130 @@
+package filter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+func TestEnforceBundleBudget(t *testing.T) {
+	// foo has five distinct bundles: a stable channel 1.0.0 -> 1.1.0 -> 1.2.0 -> 2.0.0 and a candidate channel
+	// 2.0.0 -> 2.1.0. bar has one.
+	bundle := func(pkg, version string) declcfg.Bundle {
+		return declcfg.Bundle{
+			Schema:     declcfg.SchemaBundle,
+			Name:       pkg + ".v" + version,
+			Package:    pkg,
+			Image:      "example.com/" + pkg + ":v" + version,
+			Properties: []property.Property{property.MustBuildPackage(pkg, version)},
+		}
+	}
+	fbc := declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{
+			{Schema: declcfg.SchemaPackage, Name: "foo", DefaultChannel: "stable"},
+			{Schema: declcfg.SchemaPackage, Name: "bar", DefaultChannel: "alpha"},
+		},
+		Channels: []declcfg.Channel{
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "stable", Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v1.0.0"},
+				{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+				{Name: "foo.v1.2.0", Replaces: "foo.v1.1.0"},
+				{Name: "foo.v2.0.0", Replaces: "foo.v1.2.0"},
+			}},
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "candidate", Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v2.0.0"},
+				{Name: "foo.v2.1.0", Replaces: "foo.v2.0.0"},
+			}},
+			{Schema: declcfg.SchemaChannel, Package: "bar", Name: "alpha", Entries: []declcfg.ChannelEntry{{Name: "bar.v0.1.0"}}},
+		},
+		Bundles: []declcfg.Bundle{
+			bundle("foo", "1.0.0"), bundle("foo", "1.1.0"), bundle("foo", "1.2.0"), bundle("foo", "2.0.0"), bundle("foo", "2.1.0"),
+			bundle("bar", "0.1.0"),
+		},
+	}
+
+	tests := []struct {
+		name        string
+		max         int
+		strategy    string
+		want        map[string][]string
+		wantTrimmed []string
+		wantErr     string
+	}{
+		{
+			name:     "within the budget",
+			max:      6,
+			strategy: BundleBudgetStrategyError,
+			want:     map[string][]string{"stable": {"1.0.0", "1.1.0", "1.2.0", "2.0.0"}, "candidate": {"2.0.0", "2.1.0"}},
+		},
+		{
+			name:     "over the budget",
+			max:      5,
+			strategy: BundleBudgetStrategyError,
+			wantErr:  "filtered catalog contains 6 bundles, which exceeds the maximum of 5",
+		},
+		{
+			name:        "trim the oldest bundles of the largest channel",
+			max:         4,
+			strategy:    BundleBudgetStrategyTrimOldest,
+			want:        map[string][]string{"stable": {"1.2.0", "2.0.0"}, "candidate": {"2.0.0", "2.1.0"}},
+			wantTrimmed: []string{"stable/foo.v1.0.0", "stable/foo.v1.1.0"},
+		},
+		{
+			name:     "heads are never trimmed",
+			max:      2,
+			strategy: BundleBudgetStrategyTrimOldest,
+			wantErr:  "filtered catalog contains 3 bundles, which exceeds the maximum of 2, and no more bundles can be trimmed without removing a channel head",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := declcfg.ConvertToModel(fbc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			config := v1.FilterConfiguration{
+				TypeMeta: metav1.TypeMeta{Kind: v1.Kind, APIVersion: v1.APIVersion},
+				Packages: []v1.Package{{Name: "foo"}, {Name: "bar"}},
+			}
+			result, err := FilterModel(m, config, WithMaxTotalBundles(tt.max, tt.strategy))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := keptVersions(m, "foo"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected channels %v, got %v", tt.want, got)
+			}
+			var trimmed []string
+			for _, w := range result.Warnings {
+				if w.Code == WarningBundleTrimmed {
+					trimmed = append(trimmed, w.Channel+"/"+w.Bundle)
+				}
+			}
+			if !reflect.DeepEqual(trimmed, tt.wantTrimmed) {
+				t.Errorf("expected trimmed bundles %v, got %v", tt.wantTrimmed, trimmed)
+			}
+		})
+	}
+}
+
+func TestValidateBundleBudgetStrategy(t *testing.T) {
+	for _, strategy := range []string{BundleBudgetStrategyError, BundleBudgetStrategyTrimOldest} {
+		if err := ValidateBundleBudgetStrategy(strategy); err != nil {
+			t.Errorf("expected %q to be valid, got %v", strategy, err)
+		}
+	}
+	if err := ValidateBundleBudgetStrategy("trim-newest"); err == nil {
+		t.Error("expected an error for an unknown strategy")
+	}
+}
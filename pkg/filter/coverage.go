@@ -0,0 +1,61 @@
+package filter
+
+import (
+	"fmt"
+
+	blangsemver "github.com/blang/semver/v4"
+	"github.com/operator-framework/operator-registry/alpha/model"
+)
+
+// filterCoverage keeps the smallest set of bundles that still lets version coversVersion upgrade to the channel
+// head. Starting at the head, the replaces chain is walked until the first bundle that covers the version: a bundle
+// covers it if it has that version, if its skipRange includes it, or if it skips a bundle with that version. The
+// bundles from the head down to and including the covering bundle are kept, and everything else is removed.
+func filterCoverage(ch *model.Channel, coversVersion string) error {
+	target, err := blangsemver.Parse(coversVersion)
+	if err != nil {
+		return fmt.Errorf("invalid coversVersion %q for channel %q: %v", coversVersion, ch.Name, err)
+	}
+	head, err := ch.Head()
+	if err != nil {
+		return fmt.Errorf("error getting head of channel %q: %v", ch.Name, err)
+	}
+
+	bundles := map[string]*model.Bundle{}
+	for cur := head; cur != nil; cur = ch.Bundles[cur.Replaces] {
+		if _, ok := bundles[cur.Name]; ok {
+			return fmt.Errorf("detected cycle in replaces chain of channel %q at bundle %q", ch.Name, cur.Name)
+		}
+		bundles[cur.Name] = cur
+		covers, err := coversBundleVersion(ch, cur, target)
+		if err != nil {
+			return err
+		}
+		if covers {
+			ch.Bundles = bundles
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid filter configuration: no bundle in channel %q for package %q provides an upgrade from version %q", ch.Name, ch.Package.Name, coversVersion)
+}
+
+func coversBundleVersion(ch *model.Channel, b *model.Bundle, v blangsemver.Version) (bool, error) {
+	if b.Version.EQ(v) {
+		return true, nil
+	}
+	if b.SkipRange != "" {
+		skipRange, err := blangsemver.ParseRange(b.SkipRange)
+		if err != nil {
+			return false, fmt.Errorf("invalid skipRange %q for bundle %q in channel %q: %v", b.SkipRange, b.Name, ch.Name, err)
+		}
+		if skipRange(v) {
+			return true, nil
+		}
+	}
+	for _, skip := range b.Skips {
+		if skipBundle, ok := ch.Bundles[skip]; ok && skipBundle.Version.EQ(v) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
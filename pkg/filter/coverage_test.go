@@ -0,0 +1,67 @@
+package filter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+func TestFilterCoverage(t *testing.T) {
+	// stable is 1.0.0 -> 1.1.0 -> 1.2.0 -> 2.0.0, in which 1.2.0 also skips 1.1.0 and 2.0.0 skips >=1.2.0 <2.0.0.
+	bundle := func(version string) declcfg.Bundle {
+		return declcfg.Bundle{
+			Schema:     declcfg.SchemaBundle,
+			Name:       "foo.v" + version,
+			Package:    "foo",
+			Image:      "example.com/foo:v" + version,
+			Properties: []property.Property{property.MustBuildPackage("foo", version)},
+		}
+	}
+	fbc := &declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{{Schema: declcfg.SchemaPackage, Name: "foo", DefaultChannel: "stable"}},
+		Channels: []declcfg.Channel{{Schema: declcfg.SchemaChannel, Package: "foo", Name: "stable", Entries: []declcfg.ChannelEntry{
+			{Name: "foo.v1.0.0"},
+			{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+			{Name: "foo.v1.2.0", Replaces: "foo.v1.1.0", Skips: []string{"foo.v1.1.0"}},
+			{Name: "foo.v2.0.0", Replaces: "foo.v1.2.0", SkipRange: ">=1.2.0 <2.0.0"},
+		}}},
+		Bundles: []declcfg.Bundle{bundle("1.0.0"), bundle("1.1.0"), bundle("1.2.0"), bundle("2.0.0")},
+	}
+
+	tests := []struct {
+		name          string
+		coversVersion string
+		want          []string
+		wantErr       string
+	}{
+		{name: "the head", coversVersion: "2.0.0", want: []string{"2.0.0"}},
+		{name: "covered by skipRange", coversVersion: "1.2.0", want: []string{"2.0.0"}},
+		{name: "version without a bundle covered by skipRange", coversVersion: "1.2.5", want: []string{"2.0.0"}},
+		{name: "covered by skips", coversVersion: "1.1.0", want: []string{"1.2.0", "2.0.0"}},
+		{name: "covered by the tail", coversVersion: "1.0.0", want: []string{"1.0.0", "1.1.0", "1.2.0", "2.0.0"}},
+		{name: "not covered", coversVersion: "0.9.0", wantErr: `no bundle in channel "stable" for package "foo" provides an upgrade from version "0.9.0"`},
+		{name: "invalid version", coversVersion: "one", wantErr: `invalid coversVersion "one"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := filterChannel(t, fbc, v1.Channel{Name: "stable", CoversVersion: tt.coversVersion})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected versions %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
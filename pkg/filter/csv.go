@@ -0,0 +1,31 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-registry/alpha/model"
+	"github.com/operator-framework/operator-registry/alpha/property"
+)
+
+// bundleCSVMetadata returns the CSV metadata of a bundle, either from its olm.csv.metadata property or, for bundles
+// that carry olm.bundle.object properties instead, from its CSV. It returns nil if the bundle has neither.
+func bundleCSVMetadata(b *model.Bundle) (*property.CSVMetadata, error) {
+	if b.PropertiesP != nil && len(b.PropertiesP.CSVMetadatas) > 0 {
+		return &b.PropertiesP.CSVMetadatas[0], nil
+	}
+	if b.CsvJSON == "" {
+		return nil, nil
+	}
+	var csv v1alpha1.ClusterServiceVersion
+	if err := json.Unmarshal([]byte(b.CsvJSON), &csv); err != nil {
+		return nil, fmt.Errorf("parse CSV of bundle %q: %v", b.Name, err)
+	}
+	p := property.MustBuildCSVMetadata(csv)
+	var md property.CSVMetadata
+	if err := json.Unmarshal(p.Value, &md); err != nil {
+		return nil, err
+	}
+	return &md, nil
+}
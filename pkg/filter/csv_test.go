@@ -0,0 +1,53 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/model"
+	"github.com/operator-framework/operator-registry/alpha/property"
+)
+
+func TestBundleCSVMetadata(t *testing.T) {
+	tests := []struct {
+		name    string
+		bundle  model.Bundle
+		want    string
+		wantErr string
+	}{
+		{
+			name: "csv metadata property",
+			bundle: model.Bundle{
+				PropertiesP: &property.Properties{CSVMetadatas: []property.CSVMetadata{{DisplayName: "From property"}}},
+				CsvJSON:     `{"spec": {"displayName": "From CSV"}}`,
+			},
+			want: "From property",
+		},
+		{name: "csv", bundle: model.Bundle{CsvJSON: `{"spec": {"displayName": "From CSV"}}`}, want: "From CSV"},
+		{name: "neither", bundle: model.Bundle{PropertiesP: &property.Properties{}}},
+		{name: "invalid csv", bundle: model.Bundle{Name: "foo.v1.0.0", CsvJSON: "{"}, wantErr: `parse CSV of bundle "foo.v1.0.0"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md, err := bundleCSVMetadata(&tt.bundle)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.want == "" {
+				if md != nil {
+					t.Errorf("expected no CSV metadata, got %+v", md)
+				}
+				return
+			}
+			if md == nil || md.DisplayName != tt.want {
+				t.Errorf("expected display name %q, got %+v", tt.want, md)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/operator-framework/operator-registry/alpha/model"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// dropDanglingEdges removes the skips and replaces of each kept bundle that name bundles no longer in its channel.
+// Filtering only removes bundles below the kept ones, and a bundle that loses its replaces edge is still on its
+// channel's replaces chain from the head, so every kept bundle stays connected to the head.
+func dropDanglingEdges(m model.Model, warnf logFunc) {
+	for _, pkgName := range sets.List(sets.KeySet(m)) {
+		pkg := m[pkgName]
+		for _, chName := range sets.List(sets.KeySet(pkg.Channels)) {
+			ch := pkg.Channels[chName]
+			var dropped []string
+			for _, bName := range sets.List(sets.KeySet(ch.Bundles)) {
+				b := ch.Bundles[bName]
+				if _, ok := ch.Bundles[b.Replaces]; b.Replaces != "" && !ok {
+					dropped = append(dropped, fmt.Sprintf("%s replaces %s", b.Name, b.Replaces))
+					b.Replaces = ""
+				}
+				skips := b.Skips[:0:0]
+				for _, skip := range b.Skips {
+					if _, ok := ch.Bundles[skip]; ok {
+						skips = append(skips, skip)
+						continue
+					}
+					dropped = append(dropped, fmt.Sprintf("%s skips %s", b.Name, skip))
+				}
+				if len(skips) != len(b.Skips) {
+					b.Skips = skips
+				}
+			}
+			if len(dropped) > 0 {
+				warnf(Warning{Code: WarningDanglingEdgesDropped, Package: pkgName, Channel: chName}.withMessage("dropping upgrade edges to bundles no longer in channel %q for package %q: %s", chName, pkgName, strings.Join(dropped, ", ")))
+			}
+		}
+	}
+}
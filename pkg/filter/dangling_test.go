@@ -0,0 +1,109 @@
+package filter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/model"
+	"github.com/operator-framework/operator-registry/alpha/property"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+func TestDropDanglingEdges(t *testing.T) {
+	// stable is 1.0.0 -> 1.1.0 -> 1.2.0, in which 1.2.0 also skips 1.0.0.
+	bundle := func(version string) declcfg.Bundle {
+		return declcfg.Bundle{
+			Schema:     declcfg.SchemaBundle,
+			Name:       "foo.v" + version,
+			Package:    "foo",
+			Image:      "example.com/foo:v" + version,
+			Properties: []property.Property{property.MustBuildPackage("foo", version)},
+		}
+	}
+	fbc := &declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{{Schema: declcfg.SchemaPackage, Name: "foo", DefaultChannel: "stable"}},
+		Channels: []declcfg.Channel{{Schema: declcfg.SchemaChannel, Package: "foo", Name: "stable", Entries: []declcfg.ChannelEntry{
+			{Name: "foo.v1.0.0"},
+			{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+			{Name: "foo.v1.2.0", Replaces: "foo.v1.1.0", Skips: []string{"foo.v1.0.0"}},
+		}}},
+		Bundles: []declcfg.Bundle{bundle("1.0.0"), bundle("1.1.0"), bundle("1.2.0")},
+	}
+
+	// edges lists the replaces and skips of each bundle kept in stable.
+	edges := func(m model.Model) map[string][]string {
+		got := map[string][]string{}
+		for name, b := range m["foo"].Channels["stable"].Bundles {
+			got[name] = []string{}
+			if b.Replaces != "" {
+				got[name] = append(got[name], "replaces "+b.Replaces)
+			}
+			for _, skip := range b.Skips {
+				got[name] = append(got[name], "skips "+skip)
+			}
+		}
+		return got
+	}
+
+	tests := []struct {
+		name         string
+		minVersion   string
+		drop         bool
+		want         map[string][]string
+		wantWarnings []WarningCode
+	}{
+		{
+			name:       "edges kept without the option",
+			minVersion: "1.1.0",
+			want: map[string][]string{
+				"foo.v1.1.0": {"replaces foo.v1.0.0"},
+				"foo.v1.2.0": {"replaces foo.v1.1.0", "skips foo.v1.0.0"},
+			},
+		},
+		{
+			name:       "edges to filtered bundles dropped",
+			minVersion: "1.1.0",
+			drop:       true,
+			want: map[string][]string{
+				"foo.v1.1.0": {},
+				"foo.v1.2.0": {"replaces foo.v1.1.0"},
+			},
+			wantWarnings: []WarningCode{WarningDanglingEdgesDropped},
+		},
+		{
+			name:       "nothing to drop",
+			minVersion: "1.0.0",
+			drop:       true,
+			want: map[string][]string{
+				"foo.v1.0.0": {},
+				"foo.v1.1.0": {"replaces foo.v1.0.0"},
+				"foo.v1.2.0": {"replaces foo.v1.1.0", "skips foo.v1.0.0"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := declcfg.ConvertToModel(*fbc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			config := v1.FilterConfiguration{
+				TypeMeta: metav1.TypeMeta{Kind: v1.Kind, APIVersion: v1.APIVersion},
+				Packages: []v1.Package{{Name: "foo", Channels: []v1.Channel{{Name: "stable", MinVersion: tt.minVersion}}}},
+			}
+			result, err := FilterModel(m, config, WithDropDanglingEdges(tt.drop))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := edges(m); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected edges %v, got %v", tt.want, got)
+			}
+			if got := warningCodes(result.Warnings); !reflect.DeepEqual(got, tt.wantWarnings) {
+				t.Errorf("expected warnings %v, got %v", tt.wantWarnings, got)
+			}
+		})
+	}
+}
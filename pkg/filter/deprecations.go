@@ -0,0 +1,104 @@
+package filter
+
+import (
+	"log/slog"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/model"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+func deprecatedBundleMatcher() bundleMatcher {
+	return bundleMatcher{
+		description: "set of non-deprecated bundles",
+		matches: func(b *model.Bundle) bool {
+			return b.Deprecation == nil
+		},
+	}
+}
+
+// excludeDeprecated removes deprecated objects from a package. It reports whether the package itself should be
+// removed, which is the case if the package is deprecated or if all of its channels are removed.
+//
+// Deprecated channels are removed entirely. In the remaining channels, deprecated bundles are removed using the same
+// coherence rules as version range filtering: a deprecated bundle is kept (with a warning) if it is needed to keep
+// non-deprecated bundles in the channel. In particular, when a channel head is deprecated, the newest non-deprecated
+// bundle in its replaces chain becomes the new head, unless the deprecated head is the only way to reach a skipped
+// non-deprecated bundle. Channels that contain only deprecated bundles are removed. If the default channel is
+// removed, a new one is chosen in the same way as when the default channel is filtered out by the configuration.
+func excludeDeprecated(p *model.Package, pkgConfig v1.Package, rules bundleRules, logger *slog.Logger, warnf logFunc) (bool, error) {
+	if p.Deprecation != nil {
+		warnf(Warning{Code: WarningDeprecatedExcluded, Package: p.Name}.withMessage("excluding deprecated package %q", p.Name))
+		return true, nil
+	}
+
+	matcher := deprecatedBundleMatcher()
+	for _, name := range sets.List(sets.KeySet(p.Channels)) {
+		ch := p.Channels[name]
+		if ch.Deprecation != nil {
+			warnf(Warning{Code: WarningDeprecatedExcluded, Package: p.Name, Channel: name}.withMessage("excluding deprecated channel %q from package %q", name, p.Name))
+			delete(p.Channels, name)
+			continue
+		}
+		found := false
+		for _, b := range ch.Bundles {
+			if matcher.matches(b) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			warnf(Warning{Code: WarningDeprecatedExcluded, Package: p.Name, Channel: name}.withMessage("excluding channel %q from package %q: all of its bundles are deprecated", name, p.Name))
+			delete(p.Channels, name)
+			continue
+		}
+		if err := filterBundles(ch, matcher, logger, warnf); err != nil {
+			return false, err
+		}
+		rules.narrow(ch, matcher)
+	}
+	if len(p.Channels) == 0 {
+		warnf(Warning{Code: WarningDeprecatedExcluded, Package: p.Name}.withMessage("excluding package %q: all of its channels were excluded as deprecated", p.Name))
+		return true, nil
+	}
+	return false, setDefaultChannel(p, pkgConfig, warnf)
+}
+
+// RetainedDeprecations returns the deprecations of the packages that were kept, with only the entries whose
+// referenced package, channel, or bundle is still in the filtered model. Deprecation objects left without entries
+// are dropped.
+func RetainedDeprecations(deprecations []declcfg.Deprecation, m model.Model) []declcfg.Deprecation {
+	var retained []declcfg.Deprecation
+	for _, d := range deprecations {
+		pkg, ok := m[d.Package]
+		if !ok {
+			continue
+		}
+		bundles := sets.New[string]()
+		for _, ch := range pkg.Channels {
+			bundles.Insert(sets.List(sets.KeySet(ch.Bundles))...)
+		}
+		var entries []declcfg.DeprecationEntry
+		for _, e := range d.Entries {
+			switch e.Reference.Schema {
+			case declcfg.SchemaChannel:
+				if _, ok := pkg.Channels[e.Reference.Name]; !ok {
+					continue
+				}
+			case declcfg.SchemaBundle:
+				if !bundles.Has(e.Reference.Name) {
+					continue
+				}
+			}
+			entries = append(entries, e)
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		d.Entries = entries
+		retained = append(retained, d)
+	}
+	return retained
+}
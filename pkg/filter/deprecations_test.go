@@ -0,0 +1,192 @@
+package filter
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+// deprecationsCatalog has a package foo whose default channel legacy is deprecated and whose stable channel is
+// 1.0.0 -> 1.1.0 -> 1.2.0 with its tail and head deprecated, a deprecated package bar, and a package baz whose only
+// bundle is deprecated.
+func deprecationsCatalog() *declcfg.DeclarativeConfig {
+	bundle := func(pkg, version string) declcfg.Bundle {
+		return declcfg.Bundle{
+			Schema:     declcfg.SchemaBundle,
+			Name:       pkg + ".v" + version,
+			Package:    pkg,
+			Image:      "example.com/" + pkg + ":v" + version,
+			Properties: []property.Property{property.MustBuildPackage(pkg, version)},
+		}
+	}
+	deprecated := func(schema, name string) declcfg.DeprecationEntry {
+		return declcfg.DeprecationEntry{Reference: declcfg.PackageScopedReference{Schema: schema, Name: name}, Message: name + " is deprecated"}
+	}
+	return &declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{
+			{Schema: declcfg.SchemaPackage, Name: "foo", DefaultChannel: "legacy"},
+			{Schema: declcfg.SchemaPackage, Name: "bar", DefaultChannel: "alpha"},
+			{Schema: declcfg.SchemaPackage, Name: "baz", DefaultChannel: "alpha"},
+		},
+		Channels: []declcfg.Channel{
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "legacy", Entries: []declcfg.ChannelEntry{{Name: "foo.v0.9.0"}}},
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "stable", Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v1.0.0"},
+				{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+				{Name: "foo.v1.2.0", Replaces: "foo.v1.1.0"},
+			}},
+			{Schema: declcfg.SchemaChannel, Package: "bar", Name: "alpha", Entries: []declcfg.ChannelEntry{{Name: "bar.v0.1.0"}}},
+			{Schema: declcfg.SchemaChannel, Package: "baz", Name: "alpha", Entries: []declcfg.ChannelEntry{{Name: "baz.v0.1.0"}}},
+		},
+		Bundles: []declcfg.Bundle{
+			bundle("foo", "0.9.0"), bundle("foo", "1.0.0"), bundle("foo", "1.1.0"), bundle("foo", "1.2.0"),
+			bundle("bar", "0.1.0"),
+			bundle("baz", "0.1.0"),
+		},
+		Deprecations: []declcfg.Deprecation{
+			{Schema: declcfg.SchemaDeprecation, Package: "foo", Entries: []declcfg.DeprecationEntry{
+				deprecated(declcfg.SchemaChannel, "legacy"),
+				deprecated(declcfg.SchemaBundle, "foo.v1.0.0"),
+				deprecated(declcfg.SchemaBundle, "foo.v1.2.0"),
+			}},
+			{Schema: declcfg.SchemaDeprecation, Package: "bar", Entries: []declcfg.DeprecationEntry{deprecated(declcfg.SchemaPackage, "")}},
+			{Schema: declcfg.SchemaDeprecation, Package: "baz", Entries: []declcfg.DeprecationEntry{deprecated(declcfg.SchemaBundle, "baz.v0.1.0")}},
+		},
+	}
+}
+
+func TestExcludeDeprecated(t *testing.T) {
+	tests := []struct {
+		name               string
+		foo                v1.Package
+		exclude            bool
+		wantPackages       []string
+		wantFoo            map[string][]string
+		wantDefaultChannel string
+		wantWarnings       []WarningCode
+		wantErr            string
+	}{
+		{
+			name:               "deprecations kept without the option",
+			foo:                v1.Package{Name: "foo"},
+			wantPackages:       []string{"bar", "baz", "foo"},
+			wantFoo:            map[string][]string{"legacy": {"0.9.0"}, "stable": {"1.0.0", "1.1.0", "1.2.0"}},
+			wantDefaultChannel: "legacy",
+		},
+		{
+			name:               "deprecated packages, channels, and bundles excluded",
+			foo:                v1.Package{Name: "foo", DefaultChannel: "stable"},
+			exclude:            true,
+			wantPackages:       []string{"foo"},
+			wantFoo:            map[string][]string{"stable": {"1.1.0"}},
+			wantDefaultChannel: "stable",
+			wantWarnings:       []WarningCode{WarningDeprecatedExcluded, WarningDeprecatedExcluded, WarningDeprecatedExcluded, WarningDeprecatedExcluded},
+		},
+		{
+			name:    "deprecated default channel needs a new one",
+			foo:     v1.Package{Name: "foo"},
+			exclude: true,
+			wantErr: `the default channel "legacy" was filtered out, a new default channel must be configured`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := declcfg.ConvertToModel(*deprecationsCatalog())
+			if err != nil {
+				t.Fatal(err)
+			}
+			config := v1.FilterConfiguration{
+				TypeMeta: metav1.TypeMeta{Kind: v1.Kind, APIVersion: v1.APIVersion},
+				Packages: []v1.Package{tt.foo, {Name: "bar"}, {Name: "baz"}},
+			}
+			result, err := FilterModel(m, config, WithExcludeDeprecated(tt.exclude))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var packages []string
+			for name := range m {
+				packages = append(packages, name)
+			}
+			sort.Strings(packages)
+			if !reflect.DeepEqual(packages, tt.wantPackages) {
+				t.Errorf("expected packages %v, got %v", tt.wantPackages, packages)
+			}
+			if got := keptVersions(m, "foo"); !reflect.DeepEqual(got, tt.wantFoo) {
+				t.Errorf("expected channels %v, got %v", tt.wantFoo, got)
+			}
+			if got := m["foo"].DefaultChannel.Name; got != tt.wantDefaultChannel {
+				t.Errorf("expected default channel %q, got %q", tt.wantDefaultChannel, got)
+			}
+			if got := warningCodes(result.Warnings); !reflect.DeepEqual(got, tt.wantWarnings) {
+				t.Errorf("expected warnings %v, got %v", tt.wantWarnings, got)
+			}
+		})
+	}
+}
+
+func TestRetainedDeprecations(t *testing.T) {
+	tests := []struct {
+		name     string
+		packages []v1.Package
+		// want lists the references of the retained deprecation entries of each package, as "<schema>/<name>".
+		want map[string][]string
+	}{
+		{
+			name:     "everything kept",
+			packages: []v1.Package{{Name: "foo"}, {Name: "bar"}, {Name: "baz"}},
+			want: map[string][]string{
+				"foo": {"olm.channel/legacy", "olm.bundle/foo.v1.0.0", "olm.bundle/foo.v1.2.0"},
+				"bar": {"olm.package/"},
+				"baz": {"olm.bundle/baz.v0.1.0"},
+			},
+		},
+		{
+			name:     "entries of filtered channels and bundles dropped",
+			packages: []v1.Package{{Name: "foo", DefaultChannel: "stable", Channels: []v1.Channel{{Name: "stable", MinVersion: "1.1.0"}}}},
+			want: map[string][]string{
+				"foo": {"olm.bundle/foo.v1.2.0"},
+			},
+		},
+		{
+			name:     "deprecations left without entries dropped",
+			packages: []v1.Package{{Name: "foo", DefaultChannel: "stable", Channels: []v1.Channel{{Name: "stable", MinVersion: "1.1.0", MaxVersion: "1.1.0"}}}, {Name: "bar"}},
+			want: map[string][]string{
+				"bar": {"olm.package/"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fbc := deprecationsCatalog()
+			config := v1.FilterConfiguration{
+				TypeMeta: metav1.TypeMeta{Kind: v1.Kind, APIVersion: v1.APIVersion},
+				Packages: tt.packages,
+			}
+			if _, err := Filter(fbc, config); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := map[string][]string{}
+			for _, d := range fbc.Deprecations {
+				for _, e := range d.Entries {
+					got[d.Package] = append(got[d.Package], e.Reference.Schema+"/"+e.Reference.Name)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected deprecation entries %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/operator-framework/operator-registry/alpha/model"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+// expressionMatcher narrows a matcher to the bundles of the channel for which the expression is true. A nil matcher
+// selects every bundle. The expression is evaluated for every bundle up front, so that an evaluation error fails the
+// filter rather than deselecting the bundle.
+func expressionMatcher(ch *model.Channel, expression string, matcher *bundleMatcher) (bundleMatcher, error) {
+	prg, err := v1.CompileExpression(expression)
+	if err != nil {
+		return bundleMatcher{}, fmt.Errorf("invalid expression %q for channel %q: %v", expression, ch.Name, err)
+	}
+	selected := map[string]bool{}
+	for _, b := range ch.Bundles {
+		if selected[b.Name], err = evalExpression(prg, b); err != nil {
+			return bundleMatcher{}, fmt.Errorf("evaluating expression %q for bundle %q in channel %q: %v", expression, b.Name, ch.Name, err)
+		}
+	}
+	description := fmt.Sprintf("expression %q", expression)
+	if matcher == nil {
+		return bundleMatcher{
+			description: description,
+			matches:     func(b *model.Bundle) bool { return selected[b.Name] },
+			rule:        fixedRule(keptByExpression),
+		}, nil
+	}
+	base := *matcher
+	return bundleMatcher{
+		description: fmt.Sprintf("%s and %s", base.description, description),
+		matches:     func(b *model.Bundle) bool { return selected[b.Name] && base.matches(b) },
+		rule:        base.rule,
+	}, nil
+}
+
+// evalExpression evaluates a compiled expression for a bundle, which must be true or false.
+func evalExpression(prg cel.Program, b *model.Bundle) (bool, error) {
+	properties := make([]interface{}, 0, len(b.Properties))
+	for _, p := range b.Properties {
+		var value interface{}
+		if err := json.Unmarshal(p.Value, &value); err != nil {
+			return false, fmt.Errorf("parse %s property: %v", p.Type, err)
+		}
+		properties = append(properties, map[string]interface{}{"type": p.Type, "value": value})
+	}
+	out, _, err := prg.Eval(map[string]interface{}{
+		v1.ExpressionVariable: map[string]interface{}{
+			"name":       b.Name,
+			"package":    b.Package.Name,
+			"version":    b.Version.String(),
+			"image":      b.Image,
+			"properties": properties,
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+	result, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("expression evaluated to %v, not a bool", out.Value())
+	}
+	return result, nil
+}
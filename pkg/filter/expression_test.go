@@ -0,0 +1,77 @@
+package filter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+func TestExpression(t *testing.T) {
+	// stable is 1.0.0 -> 1.1.0 -> 1.2.0 -> 2.0.0, in which 1.0.0 and 1.2.0 have the gold tier.
+	bundle := func(version string, props ...property.Property) declcfg.Bundle {
+		return declcfg.Bundle{
+			Schema:     declcfg.SchemaBundle,
+			Name:       "foo.v" + version,
+			Package:    "foo",
+			Image:      "example.com/foo:v" + version,
+			Properties: append([]property.Property{property.MustBuildPackage("foo", version)}, props...),
+		}
+	}
+	gold := property.Property{Type: "example.com/tier", Value: []byte(`"gold"`)}
+	fbc := &declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{{Schema: declcfg.SchemaPackage, Name: "foo", DefaultChannel: "stable"}},
+		Channels: []declcfg.Channel{{Schema: declcfg.SchemaChannel, Package: "foo", Name: "stable", Entries: []declcfg.ChannelEntry{
+			{Name: "foo.v1.0.0"},
+			{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+			{Name: "foo.v1.2.0", Replaces: "foo.v1.1.0"},
+			{Name: "foo.v2.0.0", Replaces: "foo.v1.2.0"},
+		}}},
+		Bundles: []declcfg.Bundle{bundle("1.0.0", gold), bundle("1.1.0"), bundle("1.2.0", gold), bundle("2.0.0")},
+	}
+
+	tests := []struct {
+		name    string
+		channel v1.Channel
+		want    []string
+		wantErr string
+	}{
+		{name: "version", channel: v1.Channel{Name: "stable", Expression: `bundle.version.startsWith("1.")`}, want: []string{"1.0.0", "1.1.0", "1.2.0"}},
+		{name: "name", channel: v1.Channel{Name: "stable", Expression: `bundle.name == "foo.v2.0.0"`}, want: []string{"2.0.0"}},
+		{name: "image", channel: v1.Channel{Name: "stable", Expression: `bundle.image.endsWith(":v1.2.0")`}, want: []string{"1.2.0"}},
+		{
+			name:    "properties",
+			channel: v1.Channel{Name: "stable", Expression: `bundle.properties.exists(p, p.type == "example.com/tier" && p.value == "gold")`},
+			want:    []string{"1.0.0", "1.1.0", "1.2.0"},
+		},
+		{
+			name:    "narrows minVersion",
+			channel: v1.Channel{Name: "stable", MinVersion: "1.1.0", Expression: `bundle.properties.exists(p, p.type == "example.com/tier")`},
+			want:    []string{"1.2.0"},
+		},
+		{name: "not a bool", channel: v1.Channel{Name: "stable", Expression: `bundle.name`}, wantErr: "not a bool"},
+		{name: "evaluation error", channel: v1.Channel{Name: "stable", Expression: `bundle.missing == "x"`}, wantErr: `evaluating expression "bundle.missing == \"x\"" for bundle`},
+		{name: "invalid", channel: v1.Channel{Name: "stable", Expression: `bundle.version ==`}, wantErr: `invalid expression "bundle.version ==" for channel "stable"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := filterChannel(t, fbc, tt.channel)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected versions %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
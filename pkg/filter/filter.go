@@ -0,0 +1,1102 @@
+// Package filter applies a FilterConfiguration to a file-based catalog.
+package filter
+
+import (
+	"fmt"
+	"log/slog"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	mmsemver "github.com/Masterminds/semver/v3"
+	blangsemver "github.com/blang/semver/v4"
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/model"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+// FilterResult describes how a configuration was applied.
+type FilterResult struct {
+	// Warnings are in the order they were reported.
+	Warnings []Warning `json:"warnings"`
+
+	// Errors are the packages that could not be filtered with WithBestEffort, in the order of the configuration.
+	// These packages are removed from the filtered catalog.
+	Errors []PackageError `json:"errors,omitempty"`
+
+	// Attributions are the rules that kept each bundle, keyed by package name and then bundle name, with one entry
+	// for each of the bundle's channels. They are only recorded with WithAttribution.
+	Attributions map[string]map[string][]KeptBy `json:"attributions,omitempty"`
+}
+
+// DataWarnings returns the warnings about data issues, which fail the filter in the CLI's --strict mode, in the order
+// they were reported.
+func (r FilterResult) DataWarnings() []Warning {
+	var warnings []Warning
+	for _, w := range r.Warnings {
+		if IsDataWarning(w.Code) {
+			warnings = append(warnings, w)
+		}
+	}
+	return warnings
+}
+
+// PackageError is the reason a package could not be filtered.
+type PackageError struct {
+	Package string `json:"package"`
+	Err     error  `json:"-"`
+}
+
+func (e PackageError) Error() string {
+	return e.Err.Error()
+}
+
+// Filter applies the configuration to the catalog, replacing its packages, channels, and bundles with the ones that
+// are kept. Deprecations and objects with other schemas are kept for the packages that remain, with only the
+// deprecation entries that still refer to something kept. The result is returned even if filtering fails, with the
+// warnings reported until then.
+func Filter(fbc *declcfg.DeclarativeConfig, configuration v1.FilterConfiguration, opts ...Option) (*FilterResult, error) {
+	m, err := declcfg.ConvertToModel(*fbc)
+	if err != nil {
+		return &FilterResult{}, fmt.Errorf("error converting input: %v", err)
+	}
+	result, err := FilterModel(m, configuration, opts...)
+	if err != nil {
+		return result, err
+	}
+	deprecations, others := fbc.Deprecations, fbc.Others
+	*fbc = declcfg.ConvertFromModel(m)
+	fbc.Deprecations = RetainedDeprecations(deprecations, m)
+	fbc.Others = RetainedOthers(others, m)
+	return result, nil
+}
+
+// FilterModel applies the configuration to the model in place. The result is returned even if filtering fails, with
+// the warnings reported until then.
+func FilterModel(m model.Model, configuration v1.FilterConfiguration, opts ...Option) (*FilterResult, error) {
+	o := newFilterOptions(opts)
+	result := &FilterResult{}
+	warnf := func(w Warning) {
+		result.Warnings = append(result.Warnings, w)
+		if o.warnf != nil {
+			o.warnf(w)
+		}
+	}
+	return result, filterV1(m, configuration, o, result, warnf)
+}
+
+type logFunc func(Warning)
+
+// filterOptions holds the settings that change how a FilterConfiguration is applied.
+type filterOptions struct {
+	warnf logFunc
+
+	// logger receives debug messages about how filtering proceeds. Packages filtered at once log concurrently.
+	logger *slog.Logger
+
+	keepReferencedChannels  bool
+	requireFullReachability bool
+	enforceMonotonicChain   bool
+
+	// inventory, when set, replaces the configured version ranges with an exact set of bundle versions to keep.
+	inventory Inventory
+
+	// excludeChannels, when set, matches channels to drop from packages that do not list their channels.
+	excludeChannels *regexp.Regexp
+
+	// channelPolicy, when set, drops the channels it does not allow from every package.
+	channelPolicy *ChannelPolicy
+
+	// maxTotalBundles, when positive, limits the number of distinct bundles in the filtered catalog, which is
+	// enforced according to maxTotalBundlesStrategy.
+	maxTotalBundles         int
+	maxTotalBundlesStrategy string
+
+	// assertHeadsUnchanged fails filtering if a channel's head changes although its version constraint and
+	// excludeVersions match the source head.
+	assertHeadsUnchanged bool
+
+	// excludeDeprecated removes deprecated packages, channels, and bundles after the configuration is applied.
+	excludeDeprecated bool
+
+	// rollbackSafe re-includes the bundles replaced by kept bundles after the configuration is applied, so that each
+	// upgrade can be rolled back. The re-included bundles count towards maxTotalBundles; trimming the oldest bundles
+	// keeps the guarantee, since only bundles whose replaced bundle is gone can be trimmed.
+	rollbackSafe bool
+
+	// pruneEmpty removes channels without bundles and packages without channels before the result is validated.
+	pruneEmpty bool
+
+	// bestEffort removes the packages that cannot be filtered and collects their errors, instead of failing.
+	bestEffort bool
+
+	// workers is the number of packages filtered at once.
+	workers int
+
+	// dropDanglingEdges removes the replaces and skips of kept bundles that name bundles that were filtered out.
+	dropDanglingEdges bool
+
+	// attribution records the rule that kept each bundle in the FilterResult, identifying configuration entries by
+	// their index in attributionPackages, or in the filtered configuration's packages when it is nil.
+	attribution         bool
+	attributionPackages []v1.Package
+}
+
+// DefaultExcludeChannelRegex returns the pattern of the channels that are dropped from packages that do not list any
+// channels, which is the configuration's DefaultExcludeChannelRegex or, when it is empty, the built-in pattern.
+func DefaultExcludeChannelRegex(configuration v1.FilterConfiguration) (*regexp.Regexp, error) {
+	expr := configuration.DefaultExcludeChannelRegex
+	if expr == "" {
+		expr = v1.DefaultExcludeChannelRegexBuiltIn
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid defaultExcludeChannelRegex %q: %v", expr, err)
+	}
+	return re, nil
+}
+
+func filterV1(m model.Model, configuration v1.FilterConfiguration, opts filterOptions, result *FilterResult, warnf logFunc) error {
+	var sourceHeads ChannelHeads
+	if opts.assertHeadsUnchanged {
+		var err error
+		if sourceHeads, err = RecordChannelHeads(m); err != nil {
+			return err
+		}
+	}
+
+	// first filter out packages
+	if err := filterPackages(m, &configuration, warnf); err != nil {
+		return err
+	}
+	if err := ExpandChannelGlobs(m, &configuration, warnf); err != nil {
+		return err
+	}
+
+	// then filter out channels
+	outcomes, err := filterPackagesConcurrently(m, configuration.Packages, opts, result, warnf)
+	if err != nil {
+		return err
+	}
+	if opts.inventory != nil {
+		warnMissingInventory(m, opts.inventory, warnf)
+	}
+	if opts.maxTotalBundles > 0 {
+		if err := enforceBundleBudget(m, opts.maxTotalBundles, opts.maxTotalBundlesStrategy, warnf); err != nil {
+			return err
+		}
+	}
+	if opts.pruneEmpty {
+		if err := pruneEmpty(m, configuration, warnf); err != nil {
+			return err
+		}
+	}
+	if opts.dropDanglingEdges {
+		dropDanglingEdges(m, warnf)
+	}
+	if err := ValidateModel(m); err != nil {
+		return fmt.Errorf("filtered model is invalid: %v", err)
+	}
+	if opts.requireFullReachability {
+		if err := validateFullReachability(m); err != nil {
+			return err
+		}
+	}
+	if opts.enforceMonotonicChain {
+		if err := validateMonotonicChains(m); err != nil {
+			return err
+		}
+	}
+	if opts.assertHeadsUnchanged {
+		if err := assertHeadsUnchanged(m, sourceHeads, outcomes); err != nil {
+			return err
+		}
+	}
+	if opts.attribution {
+		packages := opts.attributionPackages
+		if packages == nil {
+			packages = configuration.Packages
+		}
+		result.Attributions = attributeBundles(m, outcomes, packages)
+	}
+	return nil
+}
+
+// packageOutcome is what filtering a package reports besides the changes to its model.
+type packageOutcome struct {
+	// removed reports whether the package should be removed from the model.
+	removed bool
+
+	// headsExcluded holds the channels whose configuration excludes their source head. It is only recorded with
+	// assertHeadsUnchanged.
+	headsExcluded sets.Set[string]
+
+	// keptBy holds the rule that kept each bundle, keyed by channel name and then bundle name. It is only recorded
+	// with WithAttribution.
+	keptBy map[string]map[string]string
+}
+
+// filterPackage applies the package's configuration to its model: its channels, then the bundles of each channel, and
+// then the options that act on the filtered bundles. It may be called for several packages at once, so it must only
+// modify pkgModel.
+func filterPackage(pkgModel *model.Package, p v1.Package, opts filterOptions, warnf logFunc) (packageOutcome, error) {
+	var outcome packageOutcome
+	if opts.assertHeadsUnchanged {
+		var err error
+		if outcome.headsExcluded, err = excludedHeads(p, channelHeads(pkgModel), opts); err != nil {
+			return outcome, fmt.Errorf("could not filter bundles in package %q: %v", p.Name, err)
+		}
+	}
+	var rules bundleRules
+	if opts.attribution {
+		rules = bundleRules{}
+	}
+
+	err := filterChannels(pkgModel, p, opts, warnf)
+	if err != nil {
+		return outcome, fmt.Errorf("could not filter channels in package %q: %v", p.Name, err)
+	}
+
+	var sourceBundles map[string]map[string]*model.Bundle
+	if opts.rollbackSafe {
+		sourceBundles = sourceChannelBundles(pkgModel)
+	}
+
+	if opts.inventory != nil {
+		err = filterInventory(pkgModel, p, opts.inventory, rules, opts.logger, warnf)
+	} else {
+		err = filterChannelBundles(pkgModel, p, rules, opts.logger, warnf)
+	}
+	if err == nil {
+		err = filterKubeVersion(pkgModel, p, rules, opts.logger, warnf)
+	}
+	if err == nil {
+		err = filterProvidedAPIs(pkgModel, p, rules, opts.logger, warnf)
+	}
+	if err != nil {
+		return outcome, fmt.Errorf("could not filter bundles in package %q: %v", p.Name, err)
+	}
+	if opts.rollbackSafe {
+		ensureRollbackSafe(pkgModel, sourceBundles, rules, warnf)
+	}
+
+	if opts.excludeDeprecated {
+		removePackage, err := excludeDeprecated(pkgModel, p, rules, opts.logger, warnf)
+		if err != nil {
+			return outcome, fmt.Errorf("could not exclude deprecated bundles in package %q: %v", p.Name, err)
+		}
+		if removePackage {
+			outcome.removed = true
+			return outcome, nil
+		}
+	}
+
+	// the recommended bundles are checked once every option that removes bundles has been applied.
+	if err := recordRecommendedVersions(pkgModel, p); err != nil {
+		return outcome, fmt.Errorf("could not filter bundles in package %q: %v", p.Name, err)
+	}
+
+	rules.merge(pkgModel, p.MergeChannels)
+	if err := mergeChannels(pkgModel, p.MergeChannels); err != nil {
+		return outcome, fmt.Errorf("could not merge channels in package %q: %v", p.Name, err)
+	}
+	if rules != nil {
+		outcome.keptBy = rules.complete(pkgModel, p)
+	}
+
+	if p.SkipRanges != "" {
+		if err := rewriteSkipRanges(pkgModel, p.SkipRanges, warnf); err != nil {
+			return outcome, fmt.Errorf("could not rewrite skipRanges in package %q: %v", p.Name, err)
+		}
+	}
+
+	for _, c := range p.Channels {
+		ch, ok := pkgModel.Channels[c.Name]
+		if !ok || c.SkipRangeOverride == "" {
+			continue
+		}
+		if err := overrideHeadSkipRange(ch, c.SkipRangeOverride, warnf); err != nil {
+			return outcome, fmt.Errorf("could not override skipRange in package %q: %v", p.Name, err)
+		}
+	}
+	return outcome, nil
+}
+
+// ValidateModel validates each package of the model in name order. Unlike model.Model.Validate, which visits packages
+// in map order, this reports problems in the same order on every run.
+func ValidateModel(m model.Model) error {
+	var errs []error
+	for _, name := range sets.List(sets.KeySet(m)) {
+		pkg := m[name]
+		if name != pkg.Name {
+			errs = append(errs, fmt.Errorf("package key %q does not match package name %q", name, pkg.Name))
+		}
+		if err := pkg.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// filterChannelBundles filters the bundles of each configured channel of a package by the channel's version range.
+// If the package sets DropChannelsWithNoMatches, channels in which nothing matches are removed instead of failing
+// the filter, as long as at least one channel and a default channel remain.
+func filterChannelBundles(p *model.Package, pkgConfig v1.Package, rules bundleRules, logger *slog.Logger, warnf logFunc) error {
+	var dropped []string
+	filter := func(ch *model.Channel, c v1.Channel, matcher bundleMatcher) error {
+		if c.Expression != "" {
+			var err error
+			if matcher, err = expressionMatcher(ch, c.Expression, &matcher); err != nil {
+				return err
+			}
+		}
+		if c.ExcludePrereleases {
+			matcher = releasesMatcher(&matcher)
+		}
+		if len(c.IncludeVersions) > 0 {
+			var err error
+			if matcher, err = includeVersionsMatcher(ch, c.IncludeVersions, matcher); err != nil {
+				return err
+			}
+		}
+		if c.MatchSkipRange {
+			var err error
+			if matcher, err = skipRangeOverlapMatcher(ch, matcher); err != nil {
+				return err
+			}
+		}
+		if c.KeepHeadAlways {
+			var err error
+			if matcher, err = keepHeadMatcher(ch, matcher, warnf); err != nil {
+				return err
+			}
+		}
+		if c.RecommendedVersion != "" {
+			var err error
+			if matcher, err = recommendedVersionMatcher(ch, c.RecommendedVersion, matcher, warnf); err != nil {
+				return err
+			}
+		}
+		if pkgConfig.DropChannelsWithNoMatches && !channelHasMatches(ch, matcher) {
+			dropped = append(dropped, ch.Name)
+			delete(p.Channels, ch.Name)
+			return nil
+		}
+		if err := filterBundles(ch, matcher, logger, warnf); err != nil {
+			return err
+		}
+		rules.record(ch, matcher)
+		return nil
+	}
+
+	// for the remaining channels, filter out bundles that don't match the version range
+	for _, c := range pkgConfig.Channels {
+		ch, ok := p.Channels[c.Name]
+		if !ok {
+			// filterChannels already warned about configured channels that are not in the package
+			continue
+		}
+		switch {
+		case c.CoversVersion != "":
+			if err := filterCoverage(ch, c.CoversVersion); err != nil {
+				return err
+			}
+			for name := range ch.Bundles {
+				rules.set(ch.Name, name, keptByCoversVersion)
+			}
+		case c.HeadOnly:
+			matcher, err := headOnlyMatcher(ch)
+			if err != nil {
+				return err
+			}
+			if err := filter(ch, c, matcher); err != nil {
+				return err
+			}
+		case c.WithinOfHead != "":
+			matcher, err := withinOfHeadMatcher(ch, c.WithinOfHead)
+			if err != nil {
+				return fmt.Errorf("invalid withinOfHead for channel %q: %v", ch.Name, err)
+			}
+			if err := filter(ch, c, matcher); err != nil {
+				return err
+			}
+		case c.KeepLatest > 0:
+			matcher, err := keepLatestMatcher(ch, c.KeepLatest)
+			if err != nil {
+				return err
+			}
+			if err := filter(ch, c, matcher); err != nil {
+				return err
+			}
+		case c.VersionConstraint() != "":
+			matcher, err := versionRangeMatcher(c.VersionConstraint(), c.IncludePrereleases)
+			if err != nil {
+				return fmt.Errorf("invalid version range %q for channel %q: %v", c.VersionConstraint(), ch.Name, err)
+			}
+			warnRangeOutsideChannel(ch, matcher, warnf)
+			if err := filter(ch, c, matcher); err != nil {
+				return err
+			}
+		case c.Expression != "":
+			matcher, err := expressionMatcher(ch, c.Expression, nil)
+			if err != nil {
+				return err
+			}
+			// the expression is the selector here, so filter must not apply it again.
+			c.Expression = ""
+			if err := filter(ch, c, matcher); err != nil {
+				return err
+			}
+		case c.ExcludePrereleases:
+			c.ExcludePrereleases = false
+			if err := filter(ch, c, releasesMatcher(nil)); err != nil {
+				return err
+			}
+		}
+		// excluded versions are removed after the channel is filtered, whatever selected them, so that bundles they
+		// strand are removed with them.
+		if ch, ok := p.Channels[c.Name]; ok && len(c.ExcludeVersions) > 0 {
+			if err := excludeVersions(ch, c.ExcludeVersions, warnf); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(dropped) == 0 {
+		return nil
+	}
+	warnf(Warning{Code: WarningChannelsDroppedNoMatches, Package: p.Name}.withMessage("dropping channels %v from package %q: none of their bundles matched", dropped, p.Name))
+	if len(p.Channels) == 0 {
+		return fmt.Errorf("all channels were dropped because none of their bundles matched")
+	}
+	return setDefaultChannel(p, pkgConfig, warnf)
+}
+
+// keepHeadMatcher extends the matcher to also match the channel head, warning if the head did not match already.
+// Filtering with it keeps the head, along with the bundles that connect it to the matching bundles.
+func keepHeadMatcher(ch *model.Channel, matcher bundleMatcher, warnf logFunc) (bundleMatcher, error) {
+	head, err := ch.Head()
+	if err != nil {
+		return bundleMatcher{}, fmt.Errorf("error getting head of channel %q: %v", ch.Name, err)
+	}
+	if matcher.matches(head) {
+		return matcher, nil
+	}
+	warnf(Warning{Code: WarningHeadKeptOutsideSelection, Package: ch.Package.Name, Channel: ch.Name, Bundle: head.Name}.withMessage("keeping head %q with version %q in channel %q for package %q: it falls outside the specified %s but keepHeadAlways is set", head.Name, head.Version.String(), ch.Name, ch.Package.Name, matcher.description))
+	return bundleMatcher{
+		description: matcher.description,
+		matches: func(b *model.Bundle) bool {
+			return b == head || matcher.matches(b)
+		},
+		rule: extendedRule(matcher, keptByKeepHeadAlways),
+	}, nil
+}
+
+// channelHasMatches reports whether filtering the channel with the matcher would keep any bundles.
+func channelHasMatches(ch *model.Channel, matcher bundleMatcher) bool {
+	head, err := ch.Head()
+	if err != nil {
+		// let filterBundles report the error
+		return true
+	}
+	return isOrContainsMatchingBundle(head, matcher, ch)
+}
+
+// ExpandNameRegexes replaces each configuration entry that sets NameRegex with a copy of it, named after each of the
+// given packages that matches the expression and is not configured by name. Matches are added in name order.
+func ExpandNameRegexes(configuration *v1.FilterConfiguration, packageNames []string) error {
+	named := sets.New[string]()
+	for _, p := range configuration.Packages {
+		if p.NameRegex == "" {
+			named.Insert(p.Name)
+		}
+	}
+	names := sets.List(sets.New(packageNames...))
+	packages := make([]v1.Package, 0, len(configuration.Packages))
+	for _, p := range configuration.Packages {
+		if p.NameRegex == "" {
+			packages = append(packages, p)
+			continue
+		}
+		re, err := regexp.Compile(p.NameRegex)
+		if err != nil {
+			return fmt.Errorf("invalid nameRegex %q: %v", p.NameRegex, err)
+		}
+		for _, name := range names {
+			if named.Has(name) || !re.MatchString(name) {
+				continue
+			}
+			matched := p
+			matched.Name, matched.NameRegex = name, ""
+			packages = append(packages, matched)
+			named.Insert(name)
+		}
+	}
+	configuration.Packages = packages
+	return nil
+}
+
+// filterPackages removes the packages not named in the configuration or, when the configuration excludes
+// packages, only the excluded packages.
+func filterPackages(m model.Model, configuration *v1.FilterConfiguration, warnf logFunc) error {
+	if err := ExpandNameRegexes(configuration, sets.List(sets.KeySet(m))); err != nil {
+		return err
+	}
+	if len(configuration.ExcludePackages) > 0 {
+		for _, name := range configuration.ExcludePackages {
+			if _, ok := m[name]; !ok {
+				warnf(Warning{Code: WarningPackageNotFound, Package: name}.withMessage(warnPackageNotFound, name))
+			}
+			delete(m, name)
+		}
+		return nil
+	}
+	packages := sets.New[string]()
+	for _, p := range configuration.Packages {
+		packages.Insert(p.Name)
+	}
+	for _, pkg := range m {
+		if !packages.Has(pkg.Name) {
+			delete(m, pkg.Name)
+		}
+	}
+	return nil
+}
+
+// isChannelGlob reports whether a configured channel name is a glob pattern rather than an exact channel name.
+func isChannelGlob(name string) bool {
+	return strings.ContainsAny(name, "*?")
+}
+
+// ExpandChannelGlobs replaces each configured channel whose name is a glob pattern, as understood by path.Match, with
+// a copy of it named after each channel of the package that matches the pattern and is not configured by name.
+// Glob patterns that match no channels are dropped with a warning.
+func ExpandChannelGlobs(m model.Model, configuration *v1.FilterConfiguration, warnf func(Warning)) error {
+	for i := range configuration.Packages {
+		pkgConfig := &configuration.Packages[i]
+		pkg, ok := m[pkgConfig.Name]
+		if !ok {
+			continue
+		}
+		named := sets.New[string]()
+		for _, c := range pkgConfig.Channels {
+			if !isChannelGlob(c.Name) {
+				named.Insert(c.Name)
+			}
+		}
+		channels := make([]v1.Channel, 0, len(pkgConfig.Channels))
+		for _, c := range pkgConfig.Channels {
+			if !isChannelGlob(c.Name) {
+				channels = append(channels, c)
+				continue
+			}
+			matched := 0
+			for _, name := range sets.List(sets.KeySet(pkg.Channels)) {
+				ok, err := path.Match(c.Name, name)
+				if err != nil {
+					return fmt.Errorf("invalid channel pattern %q in package %q: %v", c.Name, pkg.Name, err)
+				}
+				if !ok || named.Has(name) {
+					continue
+				}
+				expanded := c
+				expanded.Name = name
+				channels = append(channels, expanded)
+				named.Insert(name)
+				matched++
+			}
+			if matched == 0 {
+				warnf(Warning{Code: WarningChannelPatternNotMatched, Package: pkg.Name}.withMessage(warnChannelPatternNotMatched, c.Name, pkg.Name, channelNames(pkg)))
+			}
+		}
+		pkgConfig.Channels = channels
+	}
+	return nil
+}
+
+func filterChannels(p *model.Package, pkgConfig v1.Package, opts filterOptions, warnf logFunc) error {
+	if len(pkgConfig.Channels) > 0 {
+		channels := sets.New[string]()
+		for _, c := range pkgConfig.Channels {
+			if _, ok := p.Channels[c.Name]; !ok {
+				warnf(Warning{Code: WarningChannelNotFound, Package: p.Name, Channel: c.Name}.withMessage(warnChannelNotFound, c.Name, p.Name, channelNames(p)))
+			}
+			channels.Insert(c.Name)
+		}
+		if opts.keepReferencedChannels {
+			referenced, err := referencedChannels(p, channels)
+			if err != nil {
+				return err
+			}
+			for _, name := range sets.List(referenced) {
+				warnf(Warning{Code: WarningReferencedChannelKept, Package: p.Name, Channel: name}.withMessage("keeping channel %q in package %q: it contains bundles referenced by a kept channel", name, p.Name))
+			}
+			channels = channels.Union(referenced)
+		}
+		for _, ch := range p.Channels {
+			if !channels.Has(ch.Name) {
+				delete(p.Channels, ch.Name)
+			}
+		}
+	} else if opts.excludeChannels != nil {
+		// the default channel is never excluded implicitly; to drop it, list the channels to keep explicitly.
+		var excluded []string
+		for _, name := range sets.List(sets.KeySet(p.Channels)) {
+			if name != p.DefaultChannel.Name && opts.excludeChannels.MatchString(name) {
+				excluded = append(excluded, name)
+				delete(p.Channels, name)
+			}
+		}
+		if len(excluded) > 0 {
+			warnf(Warning{Code: WarningChannelsExcludedByDefault, Package: p.Name}.withMessage("excluding channels [%s] from package %q: they match the default channel exclusion pattern %q (use --include-all-channels to keep them)", strings.Join(excluded, ", "), p.Name, opts.excludeChannels.String()))
+		}
+	}
+	if opts.channelPolicy != nil {
+		opts.channelPolicy.apply(p, pkgConfig, warnf)
+	}
+	if err := setDefaultChannel(p, pkgConfig, warnf); err != nil {
+		return fmt.Errorf("invalid default channel filter configuration: %v", err)
+	}
+	return nil
+}
+
+// referencedChannels returns the names of the channels, other than the kept channels, that contain a bundle
+// referenced by a kept channel's replaces, skips, or skipRange and that is not present in the referencing channel
+// itself. Channels included this way have their own references followed as well.
+func referencedChannels(p *model.Package, kept sets.Set[string]) (sets.Set[string], error) {
+	referenced := sets.New[string]()
+	queue := sets.List(kept)
+	for len(queue) > 0 {
+		ch, ok := p.Channels[queue[0]]
+		queue = queue[1:]
+		if !ok {
+			continue
+		}
+
+		refs := sets.New[string]()
+		var skipRanges []blangsemver.Range
+		for _, b := range ch.Bundles {
+			if b.Replaces != "" {
+				refs.Insert(b.Replaces)
+			}
+			refs.Insert(b.Skips...)
+			if b.SkipRange != "" {
+				skipRange, err := blangsemver.ParseRange(b.SkipRange)
+				if err != nil {
+					return nil, fmt.Errorf("invalid skipRange %q for bundle %q in channel %q: %v", b.SkipRange, b.Name, ch.Name, err)
+				}
+				skipRanges = append(skipRanges, skipRange)
+			}
+		}
+
+		for _, name := range sets.List(sets.KeySet(p.Channels)) {
+			if kept.Has(name) || referenced.Has(name) {
+				continue
+			}
+			for _, b := range p.Channels[name].Bundles {
+				if _, ok := ch.Bundles[b.Name]; ok {
+					continue
+				}
+				if refs.Has(b.Name) || inAnyRange(b.Version, skipRanges) {
+					referenced.Insert(name)
+					queue = append(queue, name)
+					break
+				}
+			}
+		}
+	}
+	return referenced, nil
+}
+
+func inAnyRange(v blangsemver.Version, ranges []blangsemver.Range) bool {
+	for _, r := range ranges {
+		if r(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// channelNames lists the names of the package's channels, for use in messages.
+func channelNames(p *model.Package) string {
+	return "[" + strings.Join(sets.List(sets.KeySet(p.Channels)), ", ") + "]"
+}
+
+// channelVersions lists the versions of the channel's bundles, highest first, for use in messages.
+func channelVersions(ch *model.Channel) string {
+	versions := make([]blangsemver.Version, 0, len(ch.Bundles))
+	for _, b := range ch.Bundles {
+		versions = append(versions, b.Version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].GT(versions[j]) })
+	strs := make([]string, len(versions))
+	for i, v := range versions {
+		strs[i] = v.String()
+	}
+	return "[" + strings.Join(strs, ", ") + "]"
+}
+
+func setDefaultChannel(p *model.Package, pkgConfig v1.Package, warnf logFunc) error {
+	// lots of complexity here. let's enumerate the cases
+	// 1. when default channel is set in the package config
+	//    a. if the configured channel exists after filtering, update model's default channel
+	//    b. if the configured channel does not exist after filtering
+	//       i. does the original model's default channel exist after filtering?
+	//          - if yes: warn: specified default channel override does not exist, keeping original default channel from catalog
+	//          - if no: specified default channel override does not exist, and original default channel does not exist
+	// 2. when the default channel is not set in the package config
+	//    a. if the original model's default channel does not exist after filtering, error: "default channel must be configured"
+
+	_, defaultChannelStillExists := p.Channels[p.DefaultChannel.Name]
+	if pkgConfig.DefaultChannel != "" {
+		if configDefaultChannel, ok := p.Channels[pkgConfig.DefaultChannel]; ok {
+			p.DefaultChannel = configDefaultChannel
+		} else if defaultChannelStillExists {
+			warnf(Warning{Code: WarningDefaultChannelOverrideMissing, Package: p.Name}.withMessage(warnDefaultChannelOverrideMissing, pkgConfig.DefaultChannel, channelNames(p)))
+		} else {
+			return fmt.Errorf("specified default channel override %q does not exist, and original default channel %q does not exist (available channels: %s)", pkgConfig.DefaultChannel, p.DefaultChannel.Name, channelNames(p))
+		}
+		return nil
+	}
+	if !defaultChannelStillExists {
+		if pkgConfig.DeriveDefaultChannelFrom != "" {
+			ch, err := derivedDefaultChannel(p, pkgConfig)
+			if err != nil {
+				return fmt.Errorf("the default channel %q was filtered out and a new default channel could not be derived: %v", p.DefaultChannel.Name, err)
+			}
+			p.DefaultChannel = ch
+			return nil
+		}
+		if ch := strategyDefaultChannel(p, pkgConfig.DefaultChannelStrategy); ch != nil {
+			warnf(Warning{Code: WarningDefaultChannelAutoSelected, Package: p.Name, Channel: ch.Name}.withMessage("the default channel %q of package %q was filtered out, selecting channel %q by the %s strategy", p.DefaultChannel.Name, p.Name, ch.Name, pkgConfig.DefaultChannelStrategy))
+			p.DefaultChannel = ch
+			return nil
+		}
+		return fmt.Errorf("the default channel %q was filtered out, a new default channel must be configured in the FilterConfiguration for this package (available channels: %s)", p.DefaultChannel.Name, channelNames(p))
+	}
+	return nil
+}
+
+// strategyDefaultChannel returns the channel chosen by a DefaultChannelStrategy, or nil when the strategy does not
+// choose one. Channels without a single head are not chosen by DefaultChannelStrategyHighestHeadVersion.
+func strategyDefaultChannel(p *model.Package, strategy string) *model.Channel {
+	var (
+		chosen     *model.Channel
+		chosenHead *model.Bundle
+	)
+	for _, name := range sets.List(sets.KeySet(p.Channels)) {
+		ch := p.Channels[name]
+		switch strategy {
+		case v1.DefaultChannelStrategyFirstAlphabetical:
+			return ch
+		case v1.DefaultChannelStrategyHighestHeadVersion:
+			head, err := ch.Head()
+			if err != nil {
+				continue
+			}
+			if chosenHead == nil || head.Version.GT(chosenHead.Version) {
+				chosen, chosenHead = ch, head
+			}
+		}
+	}
+	return chosen
+}
+
+func derivedDefaultChannel(p *model.Package, pkgConfig v1.Package) (*model.Channel, error) {
+	if pkgConfig.DeriveDefaultChannelFrom != v1.DeriveDefaultChannelFromAnnotation {
+		return nil, fmt.Errorf("unsupported deriveDefaultChannelFrom value %q: must be %q", pkgConfig.DeriveDefaultChannelFrom, v1.DeriveDefaultChannelFromAnnotation)
+	}
+	key := pkgConfig.DefaultChannelAnnotation
+	if key == "" {
+		key = v1.DefaultDefaultChannelAnnotation
+	}
+
+	var latest *model.Bundle
+	for _, ch := range p.Channels {
+		for _, b := range ch.Bundles {
+			if latest == nil || b.Version.GT(latest.Version) || (b.Version.EQ(latest.Version) && b.Name > latest.Name) {
+				latest = b
+			}
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("no bundles remain in package %q", p.Name)
+	}
+	md, err := bundleCSVMetadata(latest)
+	if err != nil {
+		return nil, err
+	}
+	if md == nil || md.Annotations[key] == "" {
+		return nil, fmt.Errorf("bundle %q does not have annotation %q", latest.Name, key)
+	}
+	ch, ok := p.Channels[md.Annotations[key]]
+	if !ok {
+		return nil, fmt.Errorf("channel %q from annotation %q of bundle %q does not exist", md.Annotations[key], key, latest.Name)
+	}
+	return ch, nil
+}
+
+// bundleMatcher selects the bundles of a channel that a filter wants to keep. The description identifies the
+// selection criteria in warnings and errors, e.g. `version range ">=1.0.0"`. The rule, when set, names the
+// configuration rule that selected a matched bundle, for attribution.
+type bundleMatcher struct {
+	description string
+	matches     func(*model.Bundle) bool
+	rule        func(*model.Bundle) string
+}
+
+// ruleOf returns the rule that selected a matched bundle, or "" if the matcher does not name one.
+func (m bundleMatcher) ruleOf(b *model.Bundle) string {
+	if m.rule == nil {
+		return ""
+	}
+	return m.rule(b)
+}
+
+// keepLatestMatcher matches the first n bundles of the channel's replaces chain, starting from the head.
+func keepLatestMatcher(ch *model.Channel, n int) (bundleMatcher, error) {
+	head, err := ch.Head()
+	if err != nil {
+		return bundleMatcher{}, fmt.Errorf("error getting head of channel %q: %v", ch.Name, err)
+	}
+	latest := sets.New[string]()
+	for cur := head; cur != nil && latest.Len() < n && !latest.Has(cur.Name); cur = ch.Bundles[cur.Replaces] {
+		latest.Insert(cur.Name)
+	}
+	return bundleMatcher{
+		description: fmt.Sprintf("latest %d bundles", n),
+		matches: func(b *model.Bundle) bool {
+			return latest.Has(b.Name)
+		},
+		rule: fixedRule(keptByKeepLatest),
+	}, nil
+}
+
+func headOnlyMatcher(ch *model.Channel) (bundleMatcher, error) {
+	head, err := ch.Head()
+	if err != nil {
+		return bundleMatcher{}, fmt.Errorf("error getting head of channel %q: %v", ch.Name, err)
+	}
+	return bundleMatcher{
+		description: "channel head",
+		matches: func(b *model.Bundle) bool {
+			return b.Name == head.Name
+		},
+		rule: fixedRule(keptByHeadOnly),
+	}, nil
+}
+
+// versionRangeMatcher matches the bundles whose version satisfies the constraint. A prerelease version only satisfies
+// a constraint that names a prerelease of the same version, unless includePrereleases is set, in which case it also
+// satisfies the constraint when its release version, without the prerelease part, does.
+func versionRangeMatcher(versionRange string, includePrereleases bool) (bundleMatcher, error) {
+	constraint, err := mmsemver.NewConstraint(versionRange)
+	if err != nil {
+		return bundleMatcher{}, err
+	}
+	description := fmt.Sprintf("version range %q", versionRange)
+	if includePrereleases {
+		description += " including prereleases"
+	}
+	return bundleMatcher{
+		description: description,
+		matches: func(b *model.Bundle) bool {
+			if constraint.Check(blangToMM(b.Version)) {
+				return true
+			}
+			if !includePrereleases || len(b.Version.Pre) == 0 {
+				return false
+			}
+			release := b.Version
+			release.Pre, release.Build = nil, nil
+			return constraint.Check(blangToMM(release))
+		},
+		rule: fixedRule(keptByVersionRange),
+	}, nil
+}
+
+// warnRangeOutsideChannel warns if the matcher matches none of the versions in the channel, which means the
+// channel's configuration can never select anything from the source.
+func warnRangeOutsideChannel(ch *model.Channel, matcher bundleMatcher, warnf logFunc) {
+	var lowest, highest *model.Bundle
+	for _, b := range ch.Bundles {
+		if matcher.matches(b) {
+			return
+		}
+		if lowest == nil || b.Version.LT(lowest.Version) {
+			lowest = b
+		}
+		if highest == nil || b.Version.GT(highest.Version) {
+			highest = b
+		}
+	}
+	if lowest == nil {
+		return
+	}
+	warnf(Warning{Code: WarningRangeOutsideChannel, Package: ch.Package.Name, Channel: ch.Name}.withMessage("the %s for channel %q in package %q cannot match any bundle: the channel's versions range from %s to %s", matcher.description, ch.Name, ch.Package.Name, lowest.Version, highest.Version))
+}
+
+func filterBundles(ch *model.Channel, matcher bundleMatcher, logger *slog.Logger, warnf logFunc) error {
+	// we need to keep a single coherent channel head, which might mean including one extra bundle that isn't
+	// matched. this case happens when a bundle on the replaces chain:
+	//   1. is not matched
+	//   2. contains a bundle in its replaces chain that is matched
+	//   3. contains a bundle in its skips list that is matched
+	// if this happens, we will emit a warning and include the bundle as the new channel head.
+
+	cur, err := ch.Head()
+	if err != nil {
+		return fmt.Errorf("error getting head of channel %q: %v", ch.Name, err)
+	}
+	// the walks below follow the replaces chain from the head, so it must end.
+	if cycle := replacesCycle(ch, cur); cycle != nil {
+		return fmt.Errorf("detected cycle in replaces chain of channel %q for package %q: %s", ch.Name, ch.Package.Name, strings.Join(cycle, " -> "))
+	}
+
+	logger = logger.With("package", ch.Package.Name, "channel", ch.Name)
+	var head *model.Bundle
+	for cur != nil && head == nil {
+		if matcher.matches(cur) {
+			logger.Debug("chose channel head: it matches the "+matcher.description, "bundle", cur.Name, "version", cur.Version.String())
+			head = cur
+			break
+		}
+		for _, skip := range cur.Skips {
+			skipBundle, ok := ch.Bundles[skip]
+			if !ok {
+				continue
+			}
+			if skipsToMatchingBundle(skipBundle, matcher, ch, sets.New[string]()) {
+				logger.Debug("chose channel head: it skips a bundle that matches the "+matcher.description, "bundle", cur.Name, "version", cur.Version.String(), "skips", skip)
+				head = cur
+				break
+			}
+		}
+		if head == nil {
+			logger.Debug("passed over channel head candidate: neither it nor its skips match the "+matcher.description, "bundle", cur.Name, "version", cur.Version.String())
+		}
+		cur = ch.Bundles[cur.Replaces]
+	}
+	var tail *model.Bundle
+	for cur != nil {
+		if !isOrContainsMatchingBundle(cur, matcher, ch) {
+			logger.Debug("chose channel tail: dropping this bundle and the ones it replaces, none of which match or skip a bundle that matches the "+matcher.description, "bundle", cur.Name, "version", cur.Version.String())
+			tail = cur
+			break
+		}
+		cur = ch.Bundles[cur.Replaces]
+	}
+	if head != nil && tail == nil {
+		logger.Debug("chose channel tail: keeping the replaces chain from the head to its end")
+	}
+
+	// we how have head and tail, let's traverse head to tail and build a list of bundles to keep
+	// warn if anything in the replaces chain is not matched
+	bundles := map[string]*model.Bundle{}
+	for cur = head; cur != tail; cur = ch.Bundles[cur.Replaces] {
+		if !matcher.matches(cur) {
+			warnf(Warning{Code: WarningOutOfRangeBundleIncluded, Package: ch.Package.Name, Channel: ch.Name, Bundle: cur.Name}.withMessage(warnOutOfRangeBundleIncluded, cur.Name, cur.Version.String(), ch.Name, ch.Package.Name, matcher.description))
+		}
+		bundles[cur.Name] = cur
+	}
+	keepSkippedBundles(ch, bundles, matcher, warnf)
+	if len(bundles) == 0 {
+		return fmt.Errorf("invalid filter configuration: no bundles in channel %q for package %q matched the %s (available versions: %s)", ch.Name, ch.Package.Name, matcher.description, channelVersions(ch))
+	}
+	ch.Bundles = bundles
+	return nil
+}
+
+// isOrContainsMatchingBundle reports whether b, or a bundle on its replaces chain, matches or skips a matching
+// bundle. A cycle in the chain ends the walk.
+func isOrContainsMatchingBundle(b *model.Bundle, matcher bundleMatcher, ch *model.Channel) bool {
+	visited := sets.New[string]()
+	for cur := b; cur != nil && !visited.Has(cur.Name); cur = ch.Bundles[cur.Replaces] {
+		visited.Insert(cur.Name)
+		if matcher.matches(cur) {
+			return true
+		}
+		for _, skip := range cur.Skips {
+			if skipBundle, ok := ch.Bundles[skip]; ok {
+				if skipsToMatchingBundle(skipBundle, matcher, ch, sets.New[string]()) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// skipsToMatchingBundle reports whether b matches, or skips a bundle that does, following skips through any number
+// of bundles. visited holds the bundles already followed, so that skips that loop back end the walk.
+func skipsToMatchingBundle(b *model.Bundle, matcher bundleMatcher, ch *model.Channel, visited sets.Set[string]) bool {
+	if visited.Has(b.Name) {
+		return false
+	}
+	visited.Insert(b.Name)
+	if matcher.matches(b) {
+		return true
+	}
+	for _, skip := range b.Skips {
+		if skipBundle, ok := ch.Bundles[skip]; ok && skipsToMatchingBundle(skipBundle, matcher, ch, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// keepSkippedBundles adds the bundles skipped by kept bundles that match, or that skip a bundle that does, to the
+// kept bundles, and then does the same for the bundles it added. Channels whose upgrade edges are skips rather than
+// replaces reach most of their bundles this way. A bundle kept only to connect a matching bundle to the head is
+// warned about like an unmatched bundle on the replaces chain.
+func keepSkippedBundles(ch *model.Channel, kept map[string]*model.Bundle, matcher bundleMatcher, warnf logFunc) {
+	pending := sets.List(sets.KeySet(kept))
+	for len(pending) > 0 {
+		cur := kept[pending[0]]
+		pending = pending[1:]
+		for _, skip := range cur.Skips {
+			skipBundle, ok := ch.Bundles[skip]
+			if !ok || kept[skip] != nil || !skipsToMatchingBundle(skipBundle, matcher, ch, sets.New[string]()) {
+				continue
+			}
+			if !matcher.matches(skipBundle) {
+				warnf(Warning{Code: WarningOutOfRangeBundleIncluded, Package: ch.Package.Name, Channel: ch.Name, Bundle: skipBundle.Name}.withMessage(warnOutOfRangeBundleIncluded, skipBundle.Name, skipBundle.Version.String(), ch.Name, ch.Package.Name, matcher.description))
+			}
+			kept[skip] = skipBundle
+			pending = append(pending, skip)
+		}
+	}
+}
+
+// replacesCycle returns the names of the bundles of the cycle in the replaces chain from b, starting and ending with
+// the first bundle the chain comes back to, or nil when the chain ends.
+func replacesCycle(ch *model.Channel, b *model.Bundle) []string {
+	index := map[string]int{}
+	var chain []string
+	for cur := b; cur != nil; cur = ch.Bundles[cur.Replaces] {
+		if i, ok := index[cur.Name]; ok {
+			return append(chain[i:], cur.Name)
+		}
+		index[cur.Name] = len(chain)
+		chain = append(chain, cur.Name)
+	}
+	return nil
+}
+
+func blangToMM(in blangsemver.Version) *mmsemver.Version {
+	pres := make([]string, len(in.Pre))
+	for i, p := range in.Pre {
+		pres[i] = p.String()
+	}
+	return mmsemver.New(
+		in.Major,
+		in.Minor,
+		in.Patch,
+		strings.Join(pres, "."),
+		strings.Join(in.Build, "."),
+	)
+}
@@ -0,0 +1,993 @@
+package filter
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/model"
+	"github.com/operator-framework/operator-registry/alpha/property"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+// keptVersions returns the versions of the bundles of each channel of the package, lowest first.
+func keptVersions(m model.Model, pkg string) map[string][]string {
+	p, ok := m[pkg]
+	if !ok {
+		return nil
+	}
+	kept := map[string][]string{}
+	for name, ch := range p.Channels {
+		bundles := make([]*model.Bundle, 0, len(ch.Bundles))
+		for _, b := range ch.Bundles {
+			bundles = append(bundles, b)
+		}
+		sort.Slice(bundles, func(i, j int) bool { return bundles[i].Version.LT(bundles[j].Version) })
+		for _, b := range bundles {
+			kept[name] = append(kept[name], b.Version.String())
+		}
+	}
+	return kept
+}
+
+// keptChannels returns the names of the channels of the package, in name order.
+func keptChannels(m model.Model, pkg string) []string {
+	var names []string
+	if p, ok := m[pkg]; ok {
+		for name := range p.Channels {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// warningCodes returns the codes of the warnings, in the order they were reported.
+func warningCodes(warnings []Warning) []WarningCode {
+	var codes []WarningCode
+	for _, w := range warnings {
+		codes = append(codes, w.Code)
+	}
+	return codes
+}
+
+// filterCatalog has a package foo with a stable channel 1.0.0 -> 1.1.0 -> 1.2.0 -> 2.0.0 and a candidate channel
+// 2.0.0 -> 2.1.0, and a package bar with a single bundle.
+func filterCatalog() *declcfg.DeclarativeConfig {
+	bundle := func(pkg, version string) declcfg.Bundle {
+		return declcfg.Bundle{
+			Schema:     declcfg.SchemaBundle,
+			Name:       pkg + ".v" + version,
+			Package:    pkg,
+			Image:      "example.com/" + pkg + ":v" + version,
+			Properties: []property.Property{property.MustBuildPackage(pkg, version)},
+		}
+	}
+	return &declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{
+			{Schema: declcfg.SchemaPackage, Name: "foo", DefaultChannel: "stable"},
+			{Schema: declcfg.SchemaPackage, Name: "bar", DefaultChannel: "alpha"},
+		},
+		Channels: []declcfg.Channel{
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "stable", Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v1.0.0"},
+				{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+				{Name: "foo.v1.2.0", Replaces: "foo.v1.1.0"},
+				{Name: "foo.v2.0.0", Replaces: "foo.v1.2.0"},
+			}},
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "candidate", Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v2.0.0"},
+				{Name: "foo.v2.1.0", Replaces: "foo.v2.0.0"},
+			}},
+			{Schema: declcfg.SchemaChannel, Package: "bar", Name: "alpha", Entries: []declcfg.ChannelEntry{
+				{Name: "bar.v0.1.0"},
+			}},
+		},
+		Bundles: []declcfg.Bundle{
+			bundle("foo", "1.0.0"),
+			bundle("foo", "1.1.0"),
+			bundle("foo", "1.2.0"),
+			bundle("foo", "2.0.0"),
+			bundle("foo", "2.1.0"),
+			bundle("bar", "0.1.0"),
+		},
+	}
+}
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		name         string
+		packages     []v1.Package
+		want         map[string][]string
+		wantDefault  string
+		wantWarnings []WarningCode
+		wantErr      string
+	}{
+		{
+			name:        "package without channels keeps every channel",
+			packages:    []v1.Package{{Name: "foo"}},
+			want:        map[string][]string{"stable": {"1.0.0", "1.1.0", "1.2.0", "2.0.0"}, "candidate": {"2.0.0", "2.1.0"}},
+			wantDefault: "stable",
+		},
+		{
+			name:        "versionRange keeps the matching bundles",
+			packages:    []v1.Package{{Name: "foo", Channels: []v1.Channel{{Name: "stable", VersionRange: ">=1.1.0 <2.0.0"}}}},
+			want:        map[string][]string{"stable": {"1.1.0", "1.2.0"}},
+			wantDefault: "stable",
+		},
+		{
+			name:         "versionRange keeps an unmatched bundle between matching ones",
+			packages:     []v1.Package{{Name: "foo", Channels: []v1.Channel{{Name: "stable", VersionRange: "<1.1.0 || >=1.2.0"}}}},
+			want:         map[string][]string{"stable": {"1.0.0", "1.1.0", "1.2.0", "2.0.0"}},
+			wantDefault:  "stable",
+			wantWarnings: []WarningCode{WarningOutOfRangeBundleIncluded},
+		},
+		{
+			name:         "versionRange that matches nothing",
+			packages:     []v1.Package{{Name: "foo", Channels: []v1.Channel{{Name: "stable", VersionRange: ">=5.0.0"}}}},
+			wantWarnings: []WarningCode{WarningRangeOutsideChannel},
+			wantErr:      `no bundles in channel "stable" for package "foo" matched the version range ">=5.0.0" (available versions: [2.0.0, 1.2.0, 1.1.0, 1.0.0])`,
+		},
+		{
+			name:        "defaultChannel replaces a filtered out default channel",
+			packages:    []v1.Package{{Name: "foo", DefaultChannel: "candidate", Channels: []v1.Channel{{Name: "candidate"}}}},
+			want:        map[string][]string{"candidate": {"2.0.0", "2.1.0"}},
+			wantDefault: "candidate",
+		},
+		{
+			name:     "filtered out default channel without an override",
+			packages: []v1.Package{{Name: "foo", Channels: []v1.Channel{{Name: "candidate"}}}},
+			wantErr:  `the default channel "stable" was filtered out, a new default channel must be configured`,
+		},
+		{
+			name:         "missing defaultChannel override keeps the catalog's default channel",
+			packages:     []v1.Package{{Name: "foo", DefaultChannel: "fast", Channels: []v1.Channel{{Name: "stable", VersionRange: ">=2.0.0"}}}},
+			want:         map[string][]string{"stable": {"2.0.0"}},
+			wantDefault:  "stable",
+			wantWarnings: []WarningCode{WarningDefaultChannelOverrideMissing},
+		},
+		{
+			name:         "missing channel",
+			packages:     []v1.Package{{Name: "foo", Channels: []v1.Channel{{Name: "stable"}, {Name: "fast"}}}},
+			want:         map[string][]string{"stable": {"1.0.0", "1.1.0", "1.2.0", "2.0.0"}},
+			wantDefault:  "stable",
+			wantWarnings: []WarningCode{WarningChannelNotFound},
+		},
+		{
+			name:         "missing package",
+			packages:     []v1.Package{{Name: "foo", Channels: []v1.Channel{{Name: "candidate", VersionRange: ">=2.1.0"}}, DefaultChannel: "candidate"}, {Name: "baz"}},
+			want:         map[string][]string{"candidate": {"2.1.0"}},
+			wantDefault:  "candidate",
+			wantWarnings: []WarningCode{WarningPackageNotFound},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := declcfg.ConvertToModel(*filterCatalog())
+			if err != nil {
+				t.Fatal(err)
+			}
+			config := v1.FilterConfiguration{
+				TypeMeta: metav1.TypeMeta{Kind: v1.Kind, APIVersion: v1.APIVersion},
+				Packages: tt.packages,
+			}
+			result, err := FilterModel(m, config)
+			if !reflect.DeepEqual(warningCodes(result.Warnings), tt.wantWarnings) {
+				t.Errorf("expected warnings %v, got %v", tt.wantWarnings, result.Warnings)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := keptVersions(m, "foo"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected channels %v, got %v", tt.want, got)
+			}
+			if got := m["foo"].DefaultChannel.Name; got != tt.wantDefault {
+				t.Errorf("expected default channel %q, got %q", tt.wantDefault, got)
+			}
+			if _, ok := m["bar"]; ok {
+				t.Error("expected the unconfigured package bar to be removed")
+			}
+		})
+	}
+}
+
+func TestFilterFollowsSkips(t *testing.T) {
+	// the channel's upgrade edges are skips: 3.0.0 replaces 1.0.0 and skips 2.1.0, which skips 2.0.0, which skips
+	// 1.5.0.
+	bundle := func(version string) declcfg.Bundle {
+		return declcfg.Bundle{
+			Schema:     declcfg.SchemaBundle,
+			Name:       "foo.v" + version,
+			Package:    "foo",
+			Image:      "example.com/foo:v" + version,
+			Properties: []property.Property{property.MustBuildPackage("foo", version)},
+		}
+	}
+	fbc := declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{{Schema: declcfg.SchemaPackage, Name: "foo", DefaultChannel: "stable"}},
+		Channels: []declcfg.Channel{{Schema: declcfg.SchemaChannel, Package: "foo", Name: "stable", Entries: []declcfg.ChannelEntry{
+			{Name: "foo.v1.0.0"},
+			{Name: "foo.v1.5.0"},
+			{Name: "foo.v2.0.0", Skips: []string{"foo.v1.5.0"}},
+			{Name: "foo.v2.1.0", Skips: []string{"foo.v2.0.0"}},
+			{Name: "foo.v3.0.0", Replaces: "foo.v1.0.0", Skips: []string{"foo.v2.1.0"}},
+		}}},
+		Bundles: []declcfg.Bundle{bundle("1.0.0"), bundle("1.5.0"), bundle("2.0.0"), bundle("2.1.0"), bundle("3.0.0")},
+	}
+
+	tests := []struct {
+		name         string
+		versionRange string
+		want         []string
+		wantWarnings []WarningCode
+	}{
+		{
+			name:         "head matches",
+			versionRange: ">=3.0.0",
+			want:         []string{"3.0.0"},
+		},
+		{
+			name:         "bundle skipped by the head",
+			versionRange: ">=2.1.0 <3.0.0",
+			want:         []string{"2.1.0", "3.0.0"},
+			wantWarnings: []WarningCode{WarningOutOfRangeBundleIncluded},
+		},
+		{
+			name:         "bundle several skips away from the head",
+			versionRange: ">=1.5.0 <2.0.0",
+			want:         []string{"1.5.0", "2.0.0", "2.1.0", "3.0.0"},
+			wantWarnings: []WarningCode{WarningOutOfRangeBundleIncluded, WarningOutOfRangeBundleIncluded, WarningOutOfRangeBundleIncluded},
+		},
+		{
+			name:         "bundle replaced by a head that skips nothing matching becomes the head",
+			versionRange: "<1.5.0",
+			want:         []string{"1.0.0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := declcfg.ConvertToModel(fbc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			config := v1.FilterConfiguration{
+				TypeMeta: metav1.TypeMeta{Kind: v1.Kind, APIVersion: v1.APIVersion},
+				Packages: []v1.Package{{Name: "foo", Channels: []v1.Channel{{Name: "stable", VersionRange: tt.versionRange}}}},
+			}
+			result, err := FilterModel(m, config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := keptVersions(m, "foo")["stable"]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected versions %v, got %v", tt.want, got)
+			}
+			if got := warningCodes(result.Warnings); !reflect.DeepEqual(got, tt.wantWarnings) {
+				t.Errorf("expected warnings %v, got %v", tt.wantWarnings, result.Warnings)
+			}
+		})
+	}
+}
+
+func TestFilterRejectsReplacesCycle(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel v1.Channel
+		wantErr string
+	}{
+		{
+			name:    "versionRange",
+			channel: v1.Channel{Name: "stable", VersionRange: ">=1.0.0"},
+			wantErr: `detected cycle in replaces chain of channel "stable" for package "foo": foo.v1.1.0 -> foo.v1.0.0 -> foo.v1.1.0`,
+		},
+		{
+			name:    "headOnly",
+			channel: v1.Channel{Name: "stable", HeadOnly: true},
+			wantErr: `detected cycle in replaces chain of channel "stable" for package "foo"`,
+		},
+		{
+			name:    "coversVersion",
+			channel: v1.Channel{Name: "stable", CoversVersion: "0.9.0"},
+			wantErr: `detected cycle in replaces chain of channel "stable"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := declcfg.ConvertToModel(*filterCatalog())
+			if err != nil {
+				t.Fatal(err)
+			}
+			// catalogs with cycles fail to convert, but a model built in code is not validated.
+			m["foo"].Channels["stable"].Bundles["foo.v1.0.0"].Replaces = "foo.v1.1.0"
+			config := v1.FilterConfiguration{
+				TypeMeta: metav1.TypeMeta{Kind: v1.Kind, APIVersion: v1.APIVersion},
+				Packages: []v1.Package{{Name: "foo", Channels: []v1.Channel{tt.channel}}},
+			}
+			if _, err := FilterModel(m, config); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestKeepReferencedChannels(t *testing.T) {
+	// fast's 1.2.0 replaces stable's 1.1.0, which replaces legacy's 1.0.0, and edge's 3.0.0 skips fast's 1.2.0 by
+	// skipRange. Each channel holds only its own bundles.
+	bundle := func(version string) declcfg.Bundle {
+		return declcfg.Bundle{
+			Schema:     declcfg.SchemaBundle,
+			Name:       "foo.v" + version,
+			Package:    "foo",
+			Image:      "example.com/foo:v" + version,
+			Properties: []property.Property{property.MustBuildPackage("foo", version)},
+		}
+	}
+	fbc := declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{{Schema: declcfg.SchemaPackage, Name: "foo", DefaultChannel: "stable"}},
+		Channels: []declcfg.Channel{
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "legacy", Entries: []declcfg.ChannelEntry{{Name: "foo.v1.0.0"}}},
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "stable", Entries: []declcfg.ChannelEntry{{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"}}},
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "fast", Entries: []declcfg.ChannelEntry{{Name: "foo.v1.2.0", Replaces: "foo.v1.1.0"}}},
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "edge", Entries: []declcfg.ChannelEntry{{Name: "foo.v3.0.0", SkipRange: ">=1.2.0 <3.0.0"}}},
+		},
+		Bundles: []declcfg.Bundle{bundle("1.0.0"), bundle("1.1.0"), bundle("1.2.0"), bundle("3.0.0")},
+	}
+
+	tests := []struct {
+		name         string
+		channels     []string
+		keep         bool
+		want         []string
+		wantWarnings []string
+	}{
+		{name: "disabled", channels: []string{"stable", "fast"}, want: []string{"fast", "stable"}},
+		{name: "replaces", channels: []string{"stable"}, keep: true, want: []string{"legacy", "stable"}, wantWarnings: []string{"legacy"}},
+		{name: "several kept channels", channels: []string{"stable", "fast"}, keep: true, want: []string{"fast", "legacy", "stable"}, wantWarnings: []string{"legacy"}},
+		{name: "skipRange, followed through the channels it adds", channels: []string{"stable", "edge"}, keep: true, want: []string{"edge", "fast", "legacy", "stable"}, wantWarnings: []string{"fast", "legacy"}},
+		{name: "nothing referenced", channels: []string{"legacy"}, keep: true, want: []string{"legacy"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := declcfg.ConvertToModel(fbc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pkg := v1.Package{Name: "foo", DefaultChannel: tt.channels[0]}
+			for _, name := range tt.channels {
+				pkg.Channels = append(pkg.Channels, v1.Channel{Name: name})
+			}
+			config := v1.FilterConfiguration{
+				TypeMeta: metav1.TypeMeta{Kind: v1.Kind, APIVersion: v1.APIVersion},
+				Packages: []v1.Package{pkg},
+			}
+			result, err := FilterModel(m, config, WithKeepReferencedChannels(tt.keep))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := keptChannels(m, "foo"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected channels %v, got %v", tt.want, got)
+			}
+			var warned []string
+			for _, w := range result.Warnings {
+				if w.Code == WarningReferencedChannelKept {
+					warned = append(warned, w.Channel)
+				}
+			}
+			if !reflect.DeepEqual(warned, tt.wantWarnings) {
+				t.Errorf("expected warnings about %v, got %v", tt.wantWarnings, result.Warnings)
+			}
+		})
+	}
+}
+
+func TestExcludeChannels(t *testing.T) {
+	bundle := func(version string) declcfg.Bundle {
+		return declcfg.Bundle{
+			Schema:     declcfg.SchemaBundle,
+			Name:       "foo.v" + version,
+			Package:    "foo",
+			Image:      "example.com/foo:v" + version,
+			Properties: []property.Property{property.MustBuildPackage("foo", version)},
+		}
+	}
+	channel := func(name string) declcfg.Channel {
+		return declcfg.Channel{Schema: declcfg.SchemaChannel, Package: "foo", Name: name, Entries: []declcfg.ChannelEntry{{Name: "foo.v1.0.0"}}}
+	}
+
+	tests := []struct {
+		name           string
+		defaultChannel string
+		regex          string
+		packages       []v1.Package
+		want           []string
+		wantExcluded   bool
+	}{
+		{
+			name:           "built-in pattern",
+			defaultChannel: "stable",
+			packages:       []v1.Package{{Name: "foo"}},
+			want:           []string{"stable", "testing-ground"},
+			wantExcluded:   true,
+		},
+		{
+			name:           "configured pattern",
+			defaultChannel: "stable",
+			regex:          "^testing",
+			packages:       []v1.Package{{Name: "foo"}},
+			want:           []string{"ci", "dev-preview", "stable", "test"},
+			wantExcluded:   true,
+		},
+		{
+			name:           "the default channel is kept",
+			defaultChannel: "ci",
+			packages:       []v1.Package{{Name: "foo"}},
+			want:           []string{"ci", "stable", "testing-ground"},
+			wantExcluded:   true,
+		},
+		{
+			name:           "listed channels are kept",
+			defaultChannel: "stable",
+			packages:       []v1.Package{{Name: "foo", Channels: []v1.Channel{{Name: "stable"}, {Name: "test"}}}},
+			want:           []string{"stable", "test"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fbc := declcfg.DeclarativeConfig{
+				Packages: []declcfg.Package{{Schema: declcfg.SchemaPackage, Name: "foo", DefaultChannel: tt.defaultChannel}},
+				Channels: []declcfg.Channel{channel("stable"), channel("test"), channel("ci"), channel("dev-preview"), channel("testing-ground")},
+				Bundles:  []declcfg.Bundle{bundle("1.0.0")},
+			}
+			m, err := declcfg.ConvertToModel(fbc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			config := v1.FilterConfiguration{
+				TypeMeta:                   metav1.TypeMeta{Kind: v1.Kind, APIVersion: v1.APIVersion},
+				Packages:                   tt.packages,
+				DefaultExcludeChannelRegex: tt.regex,
+			}
+			re, err := DefaultExcludeChannelRegex(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := FilterModel(m, config, WithExcludeChannels(re))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := keptChannels(m, "foo"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected channels %v, got %v", tt.want, got)
+			}
+			excluded := len(result.Warnings) == 1 && result.Warnings[0].Code == WarningChannelsExcludedByDefault
+			if excluded != tt.wantExcluded {
+				t.Errorf("expected an exclusion warning %t, got %v", tt.wantExcluded, result.Warnings)
+			}
+		})
+	}
+}
+
+func TestDefaultExcludeChannelRegexInvalid(t *testing.T) {
+	_, err := DefaultExcludeChannelRegex(v1.FilterConfiguration{DefaultExcludeChannelRegex: "("})
+	if err == nil || !strings.Contains(err.Error(), `invalid defaultExcludeChannelRegex "("`) {
+		t.Fatalf("expected an invalid pattern error, got %v", err)
+	}
+}
+
+func TestDropChannelsWithNoMatches(t *testing.T) {
+	tests := []struct {
+		name    string
+		pkg     v1.Package
+		want    map[string][]string
+		wantErr string
+	}{
+		{
+			name: "disabled",
+			pkg: v1.Package{Name: "foo", DefaultChannel: "candidate", Channels: []v1.Channel{
+				{Name: "stable", VersionRange: ">=2.1.0"}, {Name: "candidate", VersionRange: ">=2.1.0"},
+			}},
+			wantErr: `no bundles in channel "stable" for package "foo" matched`,
+		},
+		{
+			name: "channel without matches",
+			pkg: v1.Package{Name: "foo", DefaultChannel: "candidate", DropChannelsWithNoMatches: true, Channels: []v1.Channel{
+				{Name: "stable", VersionRange: ">=2.1.0"}, {Name: "candidate", VersionRange: ">=2.1.0"},
+			}},
+			want: map[string][]string{"candidate": {"2.1.0"}},
+		},
+		{
+			name: "dropped default channel",
+			pkg: v1.Package{Name: "foo", DropChannelsWithNoMatches: true, Channels: []v1.Channel{
+				{Name: "stable", VersionRange: ">=2.1.0"}, {Name: "candidate", VersionRange: ">=2.1.0"},
+			}},
+			wantErr: `the default channel "stable" was filtered out`,
+		},
+		{
+			name: "every channel without matches",
+			pkg: v1.Package{Name: "foo", DropChannelsWithNoMatches: true, Channels: []v1.Channel{
+				{Name: "stable", VersionRange: ">=3.0.0"}, {Name: "candidate", VersionRange: ">=3.0.0"},
+			}},
+			wantErr: "all channels were dropped because none of their bundles matched",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := declcfg.ConvertToModel(*filterCatalog())
+			if err != nil {
+				t.Fatal(err)
+			}
+			config := v1.FilterConfiguration{
+				TypeMeta: metav1.TypeMeta{Kind: v1.Kind, APIVersion: v1.APIVersion},
+				Packages: []v1.Package{tt.pkg},
+			}
+			result, err := FilterModel(m, config)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := keptVersions(m, "foo"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected channels %v, got %v", tt.want, got)
+			}
+			var dropped []string
+			for _, w := range result.Warnings {
+				if w.Code == WarningChannelsDroppedNoMatches {
+					dropped = append(dropped, w.Message)
+				}
+			}
+			if want := `dropping channels [stable] from package "foo": none of their bundles matched`; len(dropped) != 1 || dropped[0] != want {
+				t.Errorf("expected the warning %q, got %v", want, result.Warnings)
+			}
+		})
+	}
+}
+
+func TestKeepHeadAlways(t *testing.T) {
+	tests := []struct {
+		name         string
+		channel      v1.Channel
+		want         []string
+		wantWarnings []WarningCode
+	}{
+		{
+			name:         "head outside the range",
+			channel:      v1.Channel{Name: "stable", VersionRange: "<1.2.0", KeepHeadAlways: true},
+			want:         []string{"1.0.0", "1.1.0", "1.2.0", "2.0.0"},
+			wantWarnings: []WarningCode{WarningHeadKeptOutsideSelection, WarningOutOfRangeBundleIncluded},
+		},
+		{
+			name:    "head inside the range",
+			channel: v1.Channel{Name: "stable", VersionRange: ">=1.2.0", KeepHeadAlways: true},
+			want:    []string{"1.2.0", "2.0.0"},
+		},
+		{
+			name:         "head outside an expression",
+			channel:      v1.Channel{Name: "stable", Expression: `bundle.version == "1.0.0"`, KeepHeadAlways: true},
+			want:         []string{"1.0.0", "1.1.0", "1.2.0", "2.0.0"},
+			wantWarnings: []WarningCode{WarningHeadKeptOutsideSelection, WarningOutOfRangeBundleIncluded, WarningOutOfRangeBundleIncluded},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := declcfg.ConvertToModel(*filterCatalog())
+			if err != nil {
+				t.Fatal(err)
+			}
+			config := v1.FilterConfiguration{
+				TypeMeta: metav1.TypeMeta{Kind: v1.Kind, APIVersion: v1.APIVersion},
+				Packages: []v1.Package{{Name: "foo", Channels: []v1.Channel{tt.channel}}},
+			}
+			result, err := FilterModel(m, config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := keptVersions(m, "foo")["stable"]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected versions %v, got %v", tt.want, got)
+			}
+			if got := warningCodes(result.Warnings); !reflect.DeepEqual(got, tt.wantWarnings) {
+				t.Errorf("expected warnings %v, got %v", tt.wantWarnings, result.Warnings)
+			}
+		})
+	}
+}
+
+// filterChannel filters package foo of the catalog down to a single configured channel, returning the versions kept
+// in it.
+func filterChannel(t *testing.T, fbc *declcfg.DeclarativeConfig, channel v1.Channel, opts ...Option) ([]string, *FilterResult, error) {
+	t.Helper()
+	m, err := declcfg.ConvertToModel(*fbc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := v1.FilterConfiguration{
+		TypeMeta: metav1.TypeMeta{Kind: v1.Kind, APIVersion: v1.APIVersion},
+		Packages: []v1.Package{{Name: "foo", DefaultChannel: channel.Name, Channels: []v1.Channel{channel}}},
+	}
+	result, err := FilterModel(m, config, opts...)
+	return keptVersions(m, "foo")[channel.Name], result, err
+}
+
+func TestHeadOnly(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel v1.Channel
+		want    []string
+	}{
+		{name: "stable", channel: v1.Channel{Name: "stable", HeadOnly: true}, want: []string{"2.0.0"}},
+		{name: "candidate", channel: v1.Channel{Name: "candidate", HeadOnly: true}, want: []string{"2.1.0"}},
+		{name: "with includeVersions", channel: v1.Channel{Name: "stable", HeadOnly: true, IncludeVersions: []string{"1.1.0"}}, want: []string{"1.1.0", "1.2.0", "2.0.0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := filterChannel(t, filterCatalog(), tt.channel)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected versions %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMinAndMaxVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel v1.Channel
+		want    []string
+	}{
+		{name: "minVersion is inclusive", channel: v1.Channel{Name: "stable", MinVersion: "1.1.0"}, want: []string{"1.1.0", "1.2.0", "2.0.0"}},
+		{name: "maxVersion is inclusive", channel: v1.Channel{Name: "stable", MaxVersion: "1.1.0"}, want: []string{"1.0.0", "1.1.0"}},
+		{name: "both", channel: v1.Channel{Name: "stable", MinVersion: "1.1.0", MaxVersion: "1.2.0"}, want: []string{"1.1.0", "1.2.0"}},
+		{name: "equal bounds", channel: v1.Channel{Name: "stable", MinVersion: "1.2.0", MaxVersion: "1.2.0"}, want: []string{"1.2.0"}},
+		{name: "tolerant versions", channel: v1.Channel{Name: "stable", MinVersion: "v1.2"}, want: []string{"1.2.0", "2.0.0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := filterChannel(t, filterCatalog(), tt.channel)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected versions %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestKeepLatest(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel v1.Channel
+		want    []string
+	}{
+		{name: "one", channel: v1.Channel{Name: "stable", KeepLatest: 1}, want: []string{"2.0.0"}},
+		{name: "several", channel: v1.Channel{Name: "stable", KeepLatest: 3}, want: []string{"1.1.0", "1.2.0", "2.0.0"}},
+		{name: "more than the chain", channel: v1.Channel{Name: "stable", KeepLatest: 10}, want: []string{"1.0.0", "1.1.0", "1.2.0", "2.0.0"}},
+		{name: "counted from the source head", channel: v1.Channel{Name: "stable", KeepLatest: 2, ExcludeVersions: []string{"2.0.0"}}, want: []string{"1.2.0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := filterChannel(t, filterCatalog(), tt.channel)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected versions %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestExpandNameRegexes(t *testing.T) {
+	names := []string{"foo-operator", "foo", "bar-operator", "bar"}
+	tests := []struct {
+		name     string
+		packages []v1.Package
+		want     []v1.Package
+		wantErr  string
+	}{
+		{
+			name:     "matches in name order",
+			packages: []v1.Package{{NameRegex: "-operator$", DefaultChannel: "stable"}},
+			want:     []v1.Package{{Name: "bar-operator", DefaultChannel: "stable"}, {Name: "foo-operator", DefaultChannel: "stable"}},
+		},
+		{
+			name:     "packages configured by name win",
+			packages: []v1.Package{{NameRegex: "^foo", DefaultChannel: "stable"}, {Name: "foo-operator"}},
+			want:     []v1.Package{{Name: "foo", DefaultChannel: "stable"}, {Name: "foo-operator"}},
+		},
+		{
+			name:     "the first expression wins",
+			packages: []v1.Package{{NameRegex: "^foo", DefaultChannel: "stable"}, {NameRegex: "operator$", DefaultChannel: "fast"}},
+			want: []v1.Package{
+				{Name: "foo", DefaultChannel: "stable"}, {Name: "foo-operator", DefaultChannel: "stable"},
+				{Name: "bar-operator", DefaultChannel: "fast"},
+			},
+		},
+		{
+			name:     "no matches",
+			packages: []v1.Package{{Name: "bar"}, {NameRegex: "^baz"}},
+			want:     []v1.Package{{Name: "bar"}},
+		},
+		{
+			name:     "invalid expression",
+			packages: []v1.Package{{NameRegex: "("}},
+			wantErr:  `invalid nameRegex "("`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := v1.FilterConfiguration{Packages: tt.packages}
+			err := ExpandNameRegexes(&config, names)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(config.Packages, tt.want) {
+				t.Errorf("expected packages %+v, got %+v", tt.want, config.Packages)
+			}
+		})
+	}
+}
+
+func TestExpandChannelGlobs(t *testing.T) {
+	tests := []struct {
+		name         string
+		channels     []v1.Channel
+		want         []v1.Channel
+		wantWarnings []WarningCode
+		wantErr      string
+	}{
+		{
+			name:     "pattern",
+			channels: []v1.Channel{{Name: "*", VersionRange: ">=2.0.0"}},
+			want:     []v1.Channel{{Name: "candidate", VersionRange: ">=2.0.0"}, {Name: "stable", VersionRange: ">=2.0.0"}},
+		},
+		{
+			name:     "channels configured by name win",
+			channels: []v1.Channel{{Name: "*", VersionRange: ">=2.0.0"}, {Name: "stable", HeadOnly: true}},
+			want:     []v1.Channel{{Name: "candidate", VersionRange: ">=2.0.0"}, {Name: "stable", HeadOnly: true}},
+		},
+		{
+			name:     "the first pattern wins",
+			channels: []v1.Channel{{Name: "s*", HeadOnly: true}, {Name: "?????*", KeepLatest: 1}},
+			want:     []v1.Channel{{Name: "stable", HeadOnly: true}, {Name: "candidate", KeepLatest: 1}},
+		},
+		{
+			name:         "no matches",
+			channels:     []v1.Channel{{Name: "stable"}, {Name: "fast-*"}},
+			want:         []v1.Channel{{Name: "stable"}},
+			wantWarnings: []WarningCode{WarningChannelPatternNotMatched},
+		},
+		{
+			name:     "invalid pattern",
+			channels: []v1.Channel{{Name: "[*"}},
+			wantErr:  `invalid channel pattern "[*" in package "foo"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := declcfg.ConvertToModel(*filterCatalog())
+			if err != nil {
+				t.Fatal(err)
+			}
+			config := v1.FilterConfiguration{Packages: []v1.Package{{Name: "foo", Channels: tt.channels}, {Name: "baz", Channels: []v1.Channel{{Name: "*"}}}}}
+			var warnings []Warning
+			err = ExpandChannelGlobs(m, &config, func(w Warning) { warnings = append(warnings, w) })
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(config.Packages[0].Channels, tt.want) {
+				t.Errorf("expected channels %+v, got %+v", tt.want, config.Packages[0].Channels)
+			}
+			if got := config.Packages[1].Channels; len(got) != 1 || got[0].Name != "*" {
+				t.Errorf("expected the channels of a package missing from the catalog to be left alone, got %+v", got)
+			}
+			if got := warningCodes(warnings); !reflect.DeepEqual(got, tt.wantWarnings) {
+				t.Errorf("expected warnings %v, got %v", tt.wantWarnings, warnings)
+			}
+		})
+	}
+}
+
+func TestExcludePackages(t *testing.T) {
+	tests := []struct {
+		name         string
+		exclude      []string
+		want         []string
+		wantWarnings []WarningCode
+	}{
+		{name: "excluded package", exclude: []string{"bar"}, want: []string{"foo"}},
+		{name: "every package", exclude: []string{"foo", "bar"}},
+		{name: "missing package", exclude: []string{"baz"}, want: []string{"bar", "foo"}, wantWarnings: []WarningCode{WarningPackageNotFound}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := declcfg.ConvertToModel(*filterCatalog())
+			if err != nil {
+				t.Fatal(err)
+			}
+			config := v1.FilterConfiguration{
+				TypeMeta:        metav1.TypeMeta{Kind: v1.Kind, APIVersion: v1.APIVersion},
+				ExcludePackages: tt.exclude,
+			}
+			result, err := FilterModel(m, config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got []string
+			for name := range m {
+				got = append(got, name)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected packages %v, got %v", tt.want, got)
+			}
+			if got := warningCodes(result.Warnings); !reflect.DeepEqual(got, tt.wantWarnings) {
+				t.Errorf("expected warnings %v, got %v", tt.wantWarnings, result.Warnings)
+			}
+			// the packages that remain are not filtered.
+			if _, ok := m["foo"]; ok && len(m["foo"].Channels) != 2 {
+				t.Errorf("expected foo to keep both channels, got %v", keptChannels(m, "foo"))
+			}
+		})
+	}
+}
+
+func TestDeriveDefaultChannelFromAnnotation(t *testing.T) {
+	// the newest bundle, foo.v2.1.0 in candidate, names its default channel with both annotations.
+	bundle := func(version string, annotations map[string]string) declcfg.Bundle {
+		return declcfg.Bundle{
+			Schema:  declcfg.SchemaBundle,
+			Name:    "foo.v" + version,
+			Package: "foo",
+			Image:   "example.com/foo:v" + version,
+			Properties: []property.Property{
+				property.MustBuildPackage("foo", version),
+				property.MustBuild(&property.CSVMetadata{Annotations: annotations}),
+			},
+		}
+	}
+	fbc := &declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{{Schema: declcfg.SchemaPackage, Name: "foo", DefaultChannel: "stable"}},
+		Channels: []declcfg.Channel{
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "stable", Entries: []declcfg.ChannelEntry{{Name: "foo.v1.0.0"}}},
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "fast", Entries: []declcfg.ChannelEntry{{Name: "foo.v2.0.0"}}},
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "candidate", Entries: []declcfg.ChannelEntry{{Name: "foo.v2.1.0"}}},
+		},
+		Bundles: []declcfg.Bundle{
+			bundle("1.0.0", nil),
+			bundle("2.0.0", nil),
+			bundle("2.1.0", map[string]string{v1.DefaultDefaultChannelAnnotation: "fast", "example.com/default-channel": "candidate", "example.com/gone": "stable"}),
+		},
+	}
+
+	tests := []struct {
+		name       string
+		channels   []string
+		annotation string
+		want       string
+		wantErr    string
+	}{
+		{name: "default annotation", channels: []string{"fast", "candidate"}, want: "fast"},
+		{name: "configured annotation", channels: []string{"fast", "candidate"}, annotation: "example.com/default-channel", want: "candidate"},
+		{name: "missing annotation", channels: []string{"fast", "candidate"}, annotation: "example.com/missing", wantErr: `bundle "foo.v2.1.0" does not have annotation "example.com/missing"`},
+		{name: "annotated channel filtered out", channels: []string{"fast", "candidate"}, annotation: "example.com/gone", wantErr: `channel "stable" from annotation "example.com/gone" of bundle "foo.v2.1.0" does not exist`},
+		{name: "default channel kept", channels: []string{"stable", "candidate"}, want: "stable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := declcfg.ConvertToModel(*fbc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pkg := v1.Package{Name: "foo", DeriveDefaultChannelFrom: v1.DeriveDefaultChannelFromAnnotation, DefaultChannelAnnotation: tt.annotation}
+			for _, name := range tt.channels {
+				pkg.Channels = append(pkg.Channels, v1.Channel{Name: name})
+			}
+			config := v1.FilterConfiguration{
+				TypeMeta: metav1.TypeMeta{Kind: v1.Kind, APIVersion: v1.APIVersion},
+				Packages: []v1.Package{pkg},
+			}
+			_, err = FilterModel(m, config)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := m["foo"].DefaultChannel.Name; got != tt.want {
+				t.Errorf("expected default channel %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDefaultChannelStrategy(t *testing.T) {
+	// beta sorts first, but candidate's head has the highest version.
+	bundle := func(version string) declcfg.Bundle {
+		return declcfg.Bundle{
+			Schema:     declcfg.SchemaBundle,
+			Name:       "foo.v" + version,
+			Package:    "foo",
+			Image:      "example.com/foo:v" + version,
+			Properties: []property.Property{property.MustBuildPackage("foo", version)},
+		}
+	}
+	fbc := &declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{{Schema: declcfg.SchemaPackage, Name: "foo", DefaultChannel: "stable"}},
+		Channels: []declcfg.Channel{
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "stable", Entries: []declcfg.ChannelEntry{{Name: "foo.v1.0.0"}}},
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "beta", Entries: []declcfg.ChannelEntry{{Name: "foo.v1.0.0"}, {Name: "foo.v2.0.0", Replaces: "foo.v1.0.0"}}},
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "candidate", Entries: []declcfg.ChannelEntry{{Name: "foo.v2.1.0"}}},
+		},
+		Bundles: []declcfg.Bundle{bundle("1.0.0"), bundle("2.0.0"), bundle("2.1.0")},
+	}
+
+	tests := []struct {
+		name     string
+		strategy string
+		want     string
+		wantErr  string
+	}{
+		{name: "unset", wantErr: `the default channel "stable" was filtered out, a new default channel must be configured`},
+		{name: "error", strategy: v1.DefaultChannelStrategyError, wantErr: `the default channel "stable" was filtered out`},
+		{name: "highest head version", strategy: v1.DefaultChannelStrategyHighestHeadVersion, want: "candidate"},
+		{name: "first alphabetical", strategy: v1.DefaultChannelStrategyFirstAlphabetical, want: "beta"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := declcfg.ConvertToModel(*fbc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			config := v1.FilterConfiguration{
+				TypeMeta: metav1.TypeMeta{Kind: v1.Kind, APIVersion: v1.APIVersion},
+				Packages: []v1.Package{{Name: "foo", DefaultChannelStrategy: tt.strategy, Channels: []v1.Channel{{Name: "beta"}, {Name: "candidate"}}}},
+			}
+			result, err := FilterModel(m, config)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := m["foo"].DefaultChannel.Name; got != tt.want {
+				t.Errorf("expected default channel %q, got %q", tt.want, got)
+			}
+			if len(result.Warnings) != 1 || result.Warnings[0].Code != WarningDefaultChannelAutoSelected || result.Warnings[0].Channel != tt.want {
+				t.Errorf("expected a warning that %q was selected, got %v", tt.want, result.Warnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/operator-framework/operator-registry/alpha/model"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+// ChannelHeads records the head bundle of each channel, keyed by package name and then channel name.
+type ChannelHeads map[string]map[string]*model.Bundle
+
+// RecordChannelHeads returns the head of each channel of the model, for comparison after it is filtered.
+func RecordChannelHeads(m model.Model) (ChannelHeads, error) {
+	heads := ChannelHeads{}
+	for _, pkg := range m {
+		heads[pkg.Name] = map[string]*model.Bundle{}
+		for _, ch := range pkg.Channels {
+			head, err := ch.Head()
+			if err != nil {
+				return nil, fmt.Errorf("error getting head of channel %q in package %q: %v", ch.Name, pkg.Name, err)
+			}
+			heads[pkg.Name][ch.Name] = head
+		}
+	}
+	return heads, nil
+}
+
+// channelHeads returns the head of each channel of the package that has a single head.
+func channelHeads(pkg *model.Package) map[string]*model.Bundle {
+	heads := map[string]*model.Bundle{}
+	for _, ch := range pkg.Channels {
+		if head, err := ch.Head(); err == nil {
+			heads[ch.Name] = head
+		}
+	}
+	return heads
+}
+
+// excludedHeads returns the configured channels of the package whose version constraint, or the inventory that
+// replaces it, does not match their head from heads, or whose excludeVersions lists it.
+func excludedHeads(p v1.Package, heads map[string]*model.Bundle, opts filterOptions) (sets.Set[string], error) {
+	excluded := sets.New[string]()
+	for _, c := range p.Channels {
+		head, ok := heads[c.Name]
+		if !ok {
+			continue
+		}
+		matcher, err := channelMatcher(c, opts)
+		if err != nil {
+			return nil, err
+		}
+		if (matcher != nil && !matcher.matches(head)) || versionListed(head, c.ExcludeVersions) {
+			excluded.Insert(c.Name)
+		}
+	}
+	return excluded, nil
+}
+
+// assertHeadsUnchanged verifies that every channel in the filtered model has the same head version as it had in
+// the source catalog. A channel is exempt only when its configuration intentionally excludes the source head: its
+// versionRange, minVersion, or maxVersion, or the inventory used instead of them, does not match it, or its
+// excludeVersions lists it. Any other option that changes the head, such as kubeVersion or a channel merge, fails
+// the assertion.
+func assertHeadsUnchanged(m model.Model, source ChannelHeads, outcomes map[string]packageOutcome) error {
+	var changed []string
+	for _, pkgName := range sets.List(sets.KeySet(m)) {
+		pkg := m[pkgName]
+		for _, chName := range sets.List(sets.KeySet(pkg.Channels)) {
+			sourceHead, ok := source[pkgName][chName]
+			if !ok {
+				continue
+			}
+			head, err := pkg.Channels[chName].Head()
+			if err != nil {
+				return fmt.Errorf("error getting head of channel %q in package %q: %v", chName, pkgName, err)
+			}
+			if head.Version.EQ(sourceHead.Version) || outcomes[pkgName].headsExcluded.Has(chName) {
+				continue
+			}
+			changed = append(changed, fmt.Sprintf("package %q, channel %q: head changed from %q to %q", pkgName, chName, sourceHead.Version, head.Version))
+		}
+	}
+	if len(changed) > 0 {
+		return fmt.Errorf("channel heads changed unexpectedly:\n  %s", strings.Join(changed, "\n  "))
+	}
+	return nil
+}
+
+// channelMatcher returns the matcher that selected the bundles of a channel, or nil if the channel's bundles were
+// not filtered by one.
+func channelMatcher(channelConfig v1.Channel, opts filterOptions) (*bundleMatcher, error) {
+	if opts.inventory != nil {
+		matcher := opts.inventory.matcher()
+		return &matcher, nil
+	}
+	versionRange := channelConfig.VersionConstraint()
+	if versionRange == "" {
+		return nil, nil
+	}
+	matcher, err := versionRangeMatcher(versionRange, channelConfig.IncludePrereleases)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version range %q for channel %q: %v", versionRange, channelConfig.Name, err)
+	}
+	return &matcher, nil
+}
+
+// ExplainHeads describes, for each channel of the filtered model, why its head was chosen.
+func ExplainHeads(m model.Model, source ChannelHeads, configuration v1.FilterConfiguration, options ...Option) ([]string, error) {
+	opts := newFilterOptions(options)
+	channelConfigs := map[string]map[string]v1.Channel{}
+	for _, p := range configuration.Packages {
+		channelConfigs[p.Name] = map[string]v1.Channel{}
+		for _, c := range p.Channels {
+			channelConfigs[p.Name][c.Name] = c
+		}
+	}
+
+	var lines []string
+	for _, pkgName := range sets.List(sets.KeySet(m)) {
+		pkg := m[pkgName]
+		for _, chName := range sets.List(sets.KeySet(pkg.Channels)) {
+			head, err := pkg.Channels[chName].Head()
+			if err != nil {
+				return nil, fmt.Errorf("error getting head of channel %q in package %q: %v", chName, pkgName, err)
+			}
+			channelConfig := channelConfigs[pkgName][chName]
+			matcher, err := channelMatcher(channelConfig, opts)
+			if err != nil {
+				return nil, err
+			}
+			prefix := fmt.Sprintf("package %q, channel %q: ", pkgName, chName)
+			sourceHead := source[pkgName][chName]
+
+			if sourceHead == nil || head.Name == sourceHead.Name {
+				line := fmt.Sprintf("%shead %q (%s) is the original channel head", prefix, head.Name, head.Version)
+				if matcher != nil {
+					line += fmt.Sprintf(" and is in the %s", matcher.description)
+				}
+				lines = append(lines, line)
+				continue
+			}
+
+			var reason string
+			switch {
+			case opts.excludeDeprecated && sourceHead.Deprecation != nil:
+				reason = "is deprecated"
+			case matcher != nil && !matcher.matches(sourceHead):
+				reason = fmt.Sprintf("is outside the %s", matcher.description)
+			case channelConfig.CoversVersion != "":
+				reason = fmt.Sprintf("was removed while keeping the upgrade path from %q", channelConfig.CoversVersion)
+			default:
+				reason = "was removed"
+			}
+			selection := "is the newest remaining bundle in the replaces chain"
+			if matcher != nil && !matcher.matches(head) {
+				selection = fmt.Sprintf("was kept as head, although it is outside the %s, because it skips matching bundles", matcher.description)
+			}
+			lines = append(lines, fmt.Sprintf("%soriginal head %q (%s) %s; new head %q (%s) %s", prefix, sourceHead.Name, sourceHead.Version, reason, head.Name, head.Version, selection))
+		}
+	}
+	return lines, nil
+}
@@ -0,0 +1,188 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+// headsCatalog has a stable channel whose head is the prerelease foo.v2.0.0-rc.1, which is deprecated and needs
+// Kubernetes 1.25, and a candidate channel that continues it to foo.v3.0.0.
+func headsCatalog() *declcfg.DeclarativeConfig {
+	bundle := func(name, version string, props ...property.Property) declcfg.Bundle {
+		return declcfg.Bundle{
+			Schema:     declcfg.SchemaBundle,
+			Name:       name,
+			Package:    "foo",
+			Image:      "example.com/" + name,
+			Properties: append([]property.Property{property.MustBuildPackage("foo", version)}, props...),
+		}
+	}
+	return &declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{{Schema: declcfg.SchemaPackage, Name: "foo", DefaultChannel: "stable"}},
+		Channels: []declcfg.Channel{
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "stable", Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v1.0.0"},
+				{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+				{Name: "foo.v1.2.0", Replaces: "foo.v1.1.0"},
+				{Name: "foo.v2.0.0-rc.1", Replaces: "foo.v1.2.0"},
+			}},
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "candidate", Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v2.0.0-rc.1"},
+				{Name: "foo.v3.0.0", Replaces: "foo.v2.0.0-rc.1"},
+			}},
+		},
+		Bundles: []declcfg.Bundle{
+			bundle("foo.v1.0.0", "1.0.0", property.MustBuild(&property.CSVMetadata{MinKubeVersion: "1.20.0"})),
+			bundle("foo.v1.1.0", "1.1.0", property.MustBuild(&property.CSVMetadata{MinKubeVersion: "1.20.0"})),
+			bundle("foo.v1.2.0", "1.2.0", property.MustBuild(&property.CSVMetadata{MinKubeVersion: "1.20.0"})),
+			bundle("foo.v2.0.0-rc.1", "2.0.0-rc.1", property.MustBuild(&property.CSVMetadata{MinKubeVersion: "1.25.0"})),
+			bundle("foo.v3.0.0", "3.0.0", property.MustBuild(&property.CSVMetadata{MinKubeVersion: "1.20.0"})),
+		},
+		Deprecations: []declcfg.Deprecation{{
+			Schema:  declcfg.SchemaDeprecation,
+			Package: "foo",
+			Entries: []declcfg.DeprecationEntry{{
+				Reference: declcfg.PackageScopedReference{Schema: declcfg.SchemaBundle, Name: "foo.v2.0.0-rc.1"},
+				Message:   "foo.v2.0.0-rc.1 is deprecated",
+			}},
+		}},
+	}
+}
+
+func TestAssertHeadsUnchanged(t *testing.T) {
+	tests := []struct {
+		name    string
+		pkg     v1.Package
+		opts    []Option
+		wantErr string
+	}{
+		{
+			name: "head kept",
+			pkg:  v1.Package{Name: "foo", Channels: []v1.Channel{{Name: "stable", KeepLatest: 2}}},
+		},
+		{
+			name: "versionRange excludes the head",
+			pkg:  v1.Package{Name: "foo", Channels: []v1.Channel{{Name: "stable", VersionRange: "<1.2.0"}}},
+		},
+		{
+			name: "maxVersion excludes the head",
+			pkg:  v1.Package{Name: "foo", Channels: []v1.Channel{{Name: "stable", MaxVersion: "1.2.0"}}},
+		},
+		{
+			name:    "versionRange matches the head that kubeVersion removes",
+			pkg:     v1.Package{Name: "foo", KubeVersion: "1.22.0", Channels: []v1.Channel{{Name: "stable", VersionRange: ">=1.0.0-0"}}},
+			wantErr: `package "foo", channel "stable": head changed from "2.0.0-rc.1" to "1.2.0"`,
+		},
+		{
+			name:    "expression excludes the head",
+			pkg:     v1.Package{Name: "foo", Channels: []v1.Channel{{Name: "stable", Expression: `!bundle.version.startsWith("2.")`}}},
+			wantErr: `package "foo", channel "stable": head changed from "2.0.0-rc.1" to "1.2.0"`,
+		},
+		{
+			name: "excludeVersions removes the head",
+			pkg:  v1.Package{Name: "foo", Channels: []v1.Channel{{Name: "stable", MinVersion: "1.0.0", ExcludeVersions: []string{"2.0.0-rc.1"}}}},
+		},
+		{
+			name:    "excludePrereleases removes the head",
+			pkg:     v1.Package{Name: "foo", Channels: []v1.Channel{{Name: "stable", ExcludePrereleases: true}}},
+			wantErr: `package "foo", channel "stable": head changed from "2.0.0-rc.1" to "1.2.0"`,
+		},
+		{
+			name:    "kubeVersion removes the head",
+			pkg:     v1.Package{Name: "foo", KubeVersion: "1.22.0", Channels: []v1.Channel{{Name: "stable"}}},
+			wantErr: `package "foo", channel "stable": head changed from "2.0.0-rc.1" to "1.2.0"`,
+		},
+		{
+			name:    "deprecation exclusion removes the head",
+			pkg:     v1.Package{Name: "foo", Channels: []v1.Channel{{Name: "stable"}}},
+			opts:    []Option{WithExcludeDeprecated(true)},
+			wantErr: `package "foo", channel "stable": head changed from "2.0.0-rc.1" to "1.2.0"`,
+		},
+		{
+			name: "inventory excludes the head",
+			pkg:  v1.Package{Name: "foo", Channels: []v1.Channel{{Name: "stable"}}},
+			opts: []Option{WithInventory(Inventory{"foo": {"1.1.0": {}, "1.2.0": {}}})},
+		},
+		{
+			name:    "merged channel changes the head",
+			pkg:     v1.Package{Name: "foo", Channels: []v1.Channel{{Name: "stable"}, {Name: "candidate"}}, MergeChannels: []v1.ChannelMerge{{Into: "stable", From: []string{"candidate"}}}},
+			wantErr: `package "foo", channel "stable": head changed from "2.0.0-rc.1" to "3.0.0"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := v1.FilterConfiguration{
+				TypeMeta: metav1.TypeMeta{Kind: v1.Kind, APIVersion: v1.APIVersion},
+				Packages: []v1.Package{tt.pkg},
+			}
+			_, err := Filter(headsCatalog(), config, append(tt.opts, WithAssertHeadsUnchanged(true))...)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestExplainHeads(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel v1.Channel
+		opts    []Option
+		want    []string
+	}{
+		{
+			name:    "head in range",
+			channel: v1.Channel{Name: "stable", VersionRange: ">=1.1.0-0"},
+			want:    []string{`package "foo", channel "stable": head "foo.v2.0.0-rc.1" (2.0.0-rc.1) is the original channel head and is in the version range ">=1.1.0-0"`},
+		},
+		{
+			name:    "head out of range",
+			channel: v1.Channel{Name: "stable", VersionRange: "<2.0.0-0"},
+			want:    []string{`package "foo", channel "stable": original head "foo.v2.0.0-rc.1" (2.0.0-rc.1) is outside the version range "<2.0.0-0"; new head "foo.v1.2.0" (1.2.0) is the newest remaining bundle in the replaces chain`},
+		},
+		{
+			name:    "head deprecated",
+			channel: v1.Channel{Name: "stable"},
+			opts:    []Option{WithExcludeDeprecated(true)},
+			want:    []string{`package "foo", channel "stable": original head "foo.v2.0.0-rc.1" (2.0.0-rc.1) is deprecated; new head "foo.v1.2.0" (1.2.0) is the newest remaining bundle in the replaces chain`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := declcfg.ConvertToModel(*headsCatalog())
+			if err != nil {
+				t.Fatal(err)
+			}
+			source, err := RecordChannelHeads(m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			config := v1.FilterConfiguration{
+				TypeMeta: metav1.TypeMeta{Kind: v1.Kind, APIVersion: v1.APIVersion},
+				Packages: []v1.Package{{Name: "foo", Channels: []v1.Channel{tt.channel}}},
+			}
+			if _, err := FilterModel(m, config, tt.opts...); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := ExplainHeads(m, source, config, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("expected\n%s\ngot\n%s", strings.Join(tt.want, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
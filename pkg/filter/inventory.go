@@ -0,0 +1,107 @@
+package filter
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	blangsemver "github.com/blang/semver/v4"
+	"github.com/operator-framework/operator-registry/alpha/model"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/yaml"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+// inventoryEntry is a single item of an inventory file, which is a YAML or JSON list of these entries.
+type inventoryEntry struct {
+	Package string `json:"package"`
+	Version string `json:"version"`
+}
+
+// Inventory is the set of bundle versions to keep, keyed by package name.
+type Inventory map[string]sets.Set[string]
+
+// LoadInventory reads an inventory file, which is a YAML or JSON list of package names and bundle versions.
+func LoadInventory(path string) (Inventory, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []inventoryEntry
+	if err := yaml.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parse inventory: %v", err)
+	}
+	inv := Inventory{}
+	for i, e := range entries {
+		if e.Package == "" {
+			return nil, fmt.Errorf("inventory entry %d: package must be set", i)
+		}
+		v, err := blangsemver.Parse(e.Version)
+		if err != nil {
+			return nil, fmt.Errorf("inventory entry %d: invalid version %q for package %q: %v", i, e.Version, e.Package, err)
+		}
+		if inv[e.Package] == nil {
+			inv[e.Package] = sets.New[string]()
+		}
+		inv[e.Package].Insert(v.String())
+	}
+	return inv, nil
+}
+
+func (inv Inventory) matcher() bundleMatcher {
+	return bundleMatcher{
+		description: "inventory",
+		matches: func(b *model.Bundle) bool {
+			return inv[b.Package.Name].Has(b.Version.String())
+		},
+		rule: fixedRule(keptByInventory),
+	}
+}
+
+// filterInventory keeps only the inventory bundles (and the bundles needed to keep each channel coherent) in the
+// remaining channels of a package, in place of any configured version ranges. Channels that contain no inventory
+// bundles are dropped, and the default channel is re-resolved if it was one of them.
+func filterInventory(p *model.Package, pkgConfig v1.Package, inv Inventory, rules bundleRules, logger *slog.Logger, warnf logFunc) error {
+	matcher := inv.matcher()
+	for _, name := range sets.List(sets.KeySet(p.Channels)) {
+		ch := p.Channels[name]
+		found := false
+		for _, b := range ch.Bundles {
+			if matcher.matches(b) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			warnf(Warning{Code: WarningChannelDroppedNotInInventory, Package: p.Name, Channel: name}.withMessage("dropping channel %q in package %q: it contains no bundles from the inventory", name, p.Name))
+			delete(p.Channels, name)
+			continue
+		}
+		if err := filterBundles(ch, matcher, logger, warnf); err != nil {
+			return err
+		}
+		rules.record(ch, matcher)
+	}
+	if len(p.Channels) == 0 {
+		return fmt.Errorf("no bundles from the inventory were found in package %q", p.Name)
+	}
+	return setDefaultChannel(p, pkgConfig, warnf)
+}
+
+// warnMissingInventory warns about each inventory entry that is not present in the filtered model.
+func warnMissingInventory(m model.Model, inv Inventory, warnf logFunc) {
+	for _, pkgName := range sets.List(sets.KeySet(inv)) {
+		kept := sets.New[string]()
+		if pkg, ok := m[pkgName]; ok {
+			for _, ch := range pkg.Channels {
+				for _, b := range ch.Bundles {
+					kept.Insert(b.Version.String())
+				}
+			}
+		}
+		for _, version := range sets.List(inv[pkgName].Difference(kept)) {
+			warnf(Warning{Code: WarningInventoryEntryNotFound, Package: pkgName}.withMessage("inventory entry for package %q version %q not found in filtered catalog", pkgName, version))
+		}
+	}
+}
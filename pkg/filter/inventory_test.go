@@ -0,0 +1,154 @@
+package filter
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+func TestLoadInventory(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    Inventory
+		wantErr string
+	}{
+		{
+			name: "yaml",
+			data: "- package: foo\n  version: 1.0.0\n- package: foo\n  version: 1.1.0\n- package: bar\n  version: 0.1.0\n",
+			want: Inventory{"foo": sets.New("1.0.0", "1.1.0"), "bar": sets.New("0.1.0")},
+		},
+		{
+			name: "json",
+			data: `[{"package": "foo", "version": "1.0.0"}]`,
+			want: Inventory{"foo": sets.New("1.0.0")},
+		},
+		{name: "empty", data: "[]", want: Inventory{}},
+		{name: "missing package", data: "- version: 1.0.0\n", wantErr: "inventory entry 0: package must be set"},
+		{name: "invalid version", data: "- package: foo\n  version: one\n", wantErr: `inventory entry 0: invalid version "one" for package "foo"`},
+		{name: "not a list", data: "package: foo\n", wantErr: "parse inventory"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "inventory.yaml")
+			if err := os.WriteFile(path, []byte(tt.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadInventory(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected inventory %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFilterInventory(t *testing.T) {
+	// foo has a stable channel 1.0.0 -> 1.1.0 -> 1.2.0 and a candidate channel 1.2.0 -> 2.0.0.
+	bundle := func(version string) declcfg.Bundle {
+		return declcfg.Bundle{
+			Schema:     declcfg.SchemaBundle,
+			Name:       "foo.v" + version,
+			Package:    "foo",
+			Image:      "example.com/foo:v" + version,
+			Properties: []property.Property{property.MustBuildPackage("foo", version)},
+		}
+	}
+	fbc := declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{{Schema: declcfg.SchemaPackage, Name: "foo", DefaultChannel: "stable"}},
+		Channels: []declcfg.Channel{
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "stable", Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v1.0.0"},
+				{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+				{Name: "foo.v1.2.0", Replaces: "foo.v1.1.0"},
+			}},
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "candidate", Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v1.2.0"},
+				{Name: "foo.v2.0.0", Replaces: "foo.v1.2.0"},
+			}},
+		},
+		Bundles: []declcfg.Bundle{bundle("1.0.0"), bundle("1.1.0"), bundle("1.2.0"), bundle("2.0.0")},
+	}
+
+	tests := []struct {
+		name         string
+		foo          v1.Package
+		inventory    Inventory
+		want         map[string][]string
+		wantWarnings []WarningCode
+		wantErr      string
+	}{
+		{
+			name:         "in place of the configured selection",
+			foo:          v1.Package{Name: "foo", Channels: []v1.Channel{{Name: "stable", HeadOnly: true}, {Name: "candidate", HeadOnly: true}}},
+			inventory:    Inventory{"foo": sets.New("1.0.0", "1.2.0")},
+			want:         map[string][]string{"stable": {"1.0.0", "1.1.0", "1.2.0"}, "candidate": {"1.2.0"}},
+			wantWarnings: []WarningCode{WarningOutOfRangeBundleIncluded},
+		},
+		{
+			name:         "channels without inventory bundles dropped",
+			foo:          v1.Package{Name: "foo"},
+			inventory:    Inventory{"foo": sets.New("1.1.0")},
+			want:         map[string][]string{"stable": {"1.1.0"}},
+			wantWarnings: []WarningCode{WarningChannelDroppedNotInInventory},
+		},
+		{
+			name:         "missing entries",
+			foo:          v1.Package{Name: "foo"},
+			inventory:    Inventory{"foo": sets.New("1.2.0", "3.0.0"), "bar": sets.New("0.1.0")},
+			want:         map[string][]string{"stable": {"1.2.0"}, "candidate": {"1.2.0"}},
+			wantWarnings: []WarningCode{WarningInventoryEntryNotFound, WarningInventoryEntryNotFound},
+		},
+		{
+			name:      "no inventory bundles",
+			foo:       v1.Package{Name: "foo"},
+			inventory: Inventory{"foo": sets.New("3.0.0")},
+			wantErr:   `no bundles from the inventory were found in package "foo"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := declcfg.ConvertToModel(fbc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			config := v1.FilterConfiguration{
+				TypeMeta: metav1.TypeMeta{Kind: v1.Kind, APIVersion: v1.APIVersion},
+				Packages: []v1.Package{tt.foo},
+			}
+			result, err := FilterModel(m, config, WithInventory(tt.inventory))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := keptVersions(m, "foo"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected channels %v, got %v", tt.want, got)
+			}
+			if got := warningCodes(result.Warnings); !reflect.DeepEqual(got, tt.wantWarnings) {
+				t.Errorf("expected warnings %v, got %v", tt.wantWarnings, got)
+			}
+		})
+	}
+}
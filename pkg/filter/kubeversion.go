@@ -0,0 +1,74 @@
+package filter
+
+import (
+	"fmt"
+	"log/slog"
+
+	blangsemver "github.com/blang/semver/v4"
+	"github.com/operator-framework/operator-registry/alpha/model"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+// kubeVersionMatcher matches the bundles that can be installed on the given Kubernetes version, which are those whose
+// CSV declares a minKubeVersion no higher than it. Bundles that do not declare a minKubeVersion are matched with a
+// warning.
+func kubeVersionMatcher(kubeVersion string, warnf logFunc) (bundleMatcher, error) {
+	target, err := blangsemver.ParseTolerant(kubeVersion)
+	if err != nil {
+		return bundleMatcher{}, err
+	}
+	return bundleMatcher{
+		description: fmt.Sprintf("Kubernetes version %q", kubeVersion),
+		matches: func(b *model.Bundle) bool {
+			md, err := bundleCSVMetadata(b)
+			if err != nil || md == nil || md.MinKubeVersion == "" {
+				warnf(Warning{Code: WarningMinKubeVersionMissing, Package: b.Package.Name, Bundle: b.Name}.withMessage(warnMinKubeVersionMissing, b.Name, b.Package.Name))
+				return true
+			}
+			minKubeVersion, err := blangsemver.ParseTolerant(md.MinKubeVersion)
+			if err != nil {
+				warnf(Warning{Code: WarningMinKubeVersionMissing, Package: b.Package.Name, Bundle: b.Name}.withMessage(warnMinKubeVersionMissing, b.Name, b.Package.Name))
+				return true
+			}
+			return minKubeVersion.LTE(target)
+		},
+		rule: fixedRule(keptByKubeVersion),
+	}, nil
+}
+
+// filterKubeVersion removes the bundles that cannot be installed on the kubeVersion of each channel, or of the
+// package for channels that do not set one. It runs after the channel's bundles are selected, and keeps the
+// channel coherent in the same way.
+func filterKubeVersion(p *model.Package, pkgConfig v1.Package, rules bundleRules, logger *slog.Logger, warnf logFunc) error {
+	channelConfigs := map[string]v1.Channel{}
+	for _, c := range pkgConfig.Channels {
+		channelConfigs[c.Name] = c
+	}
+	for _, name := range sets.List(sets.KeySet(p.Channels)) {
+		kubeVersion := pkgConfig.KubeVersion
+		if c, ok := channelConfigs[name]; ok && c.KubeVersion != "" {
+			kubeVersion = c.KubeVersion
+		}
+		if kubeVersion == "" {
+			continue
+		}
+		// warn once for each bundle without a usable minKubeVersion, however many times it is matched.
+		warned := sets.New[string]()
+		matcher, err := kubeVersionMatcher(kubeVersion, func(w Warning) {
+			if !warned.Has(w.Message) {
+				warned.Insert(w.Message)
+				warnf(w)
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("invalid kubeVersion %q for channel %q: %v", kubeVersion, name, err)
+		}
+		if err := filterBundles(p.Channels[name], matcher, logger, warnf); err != nil {
+			return err
+		}
+		rules.narrow(p.Channels[name], matcher)
+	}
+	return nil
+}
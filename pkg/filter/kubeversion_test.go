@@ -0,0 +1,111 @@
+package filter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+func TestFilterKubeVersion(t *testing.T) {
+	// stable is 1.0.0 -> 1.1.0 -> 1.2.0 -> 2.0.0 and candidate is 1.2.0 -> 2.1.0. Each bundle needs a newer
+	// Kubernetes version than the one it replaces, except 2.1.0, which does not declare one.
+	bundle := func(version, minKubeVersion string) declcfg.Bundle {
+		return declcfg.Bundle{
+			Schema:  declcfg.SchemaBundle,
+			Name:    "foo.v" + version,
+			Package: "foo",
+			Image:   "example.com/foo:v" + version,
+			Properties: []property.Property{
+				property.MustBuildPackage("foo", version),
+				property.MustBuild(&property.CSVMetadata{MinKubeVersion: minKubeVersion}),
+			},
+		}
+	}
+	fbc := declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{{Schema: declcfg.SchemaPackage, Name: "foo", DefaultChannel: "stable"}},
+		Channels: []declcfg.Channel{
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "stable", Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v1.0.0"},
+				{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+				{Name: "foo.v1.2.0", Replaces: "foo.v1.1.0"},
+				{Name: "foo.v2.0.0", Replaces: "foo.v1.2.0"},
+			}},
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "candidate", Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v1.2.0"},
+				{Name: "foo.v2.1.0", Replaces: "foo.v1.2.0"},
+			}},
+		},
+		Bundles: []declcfg.Bundle{
+			bundle("1.0.0", "1.20.0"), bundle("1.1.0", "1.22.0"), bundle("1.2.0", "1.26.0"), bundle("2.0.0", "1.28.0"),
+			bundle("2.1.0", ""),
+		},
+	}
+
+	tests := []struct {
+		name         string
+		foo          v1.Package
+		want         map[string][]string
+		wantWarnings []WarningCode
+		wantErr      string
+	}{
+		{
+			name:         "package kubeVersion",
+			foo:          v1.Package{Name: "foo", KubeVersion: "1.24.0"},
+			want:         map[string][]string{"stable": {"1.0.0", "1.1.0"}, "candidate": {"2.1.0"}},
+			wantWarnings: []WarningCode{WarningMinKubeVersionMissing},
+		},
+		{
+			name: "channel kubeVersion overrides the package",
+			foo: v1.Package{Name: "foo", KubeVersion: "1.24.0", Channels: []v1.Channel{
+				{Name: "stable"},
+				{Name: "candidate", KubeVersion: "1.30.0"},
+			}},
+			want:         map[string][]string{"stable": {"1.0.0", "1.1.0"}, "candidate": {"1.2.0", "2.1.0"}},
+			wantWarnings: []WarningCode{WarningMinKubeVersionMissing},
+		},
+		{
+			name: "tolerant version",
+			foo:  v1.Package{Name: "foo", Channels: []v1.Channel{{Name: "stable", KubeVersion: "v1.27"}}},
+			want: map[string][]string{"stable": {"1.0.0", "1.1.0", "1.2.0"}},
+		},
+		{
+			name:    "invalid kubeVersion",
+			foo:     v1.Package{Name: "foo", KubeVersion: "one"},
+			wantErr: `invalid kubeVersion "one"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := declcfg.ConvertToModel(fbc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			config := v1.FilterConfiguration{
+				TypeMeta: metav1.TypeMeta{Kind: v1.Kind, APIVersion: v1.APIVersion},
+				Packages: []v1.Package{tt.foo},
+			}
+			result, err := FilterModel(m, config)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := keptVersions(m, "foo"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected channels %v, got %v", tt.want, got)
+			}
+			if got := warningCodes(result.Warnings); !reflect.DeepEqual(got, tt.wantWarnings) {
+				t.Errorf("expected warnings %v, got %v", tt.wantWarnings, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/operator-framework/operator-registry/alpha/model"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+// mergeChannels applies the package's channel merges to its kept channels. Source channels that were not kept are
+// ignored, and a merge none of whose channels were kept does nothing. The default channel follows its channel into
+// the merged channel.
+func mergeChannels(p *model.Package, merges []v1.ChannelMerge) error {
+	for _, mc := range merges {
+		var sources []*model.Channel
+		seen := sets.New[string]()
+		for _, name := range append([]string{mc.Into}, mc.From...) {
+			if ch, ok := p.Channels[name]; ok && !seen.Has(name) {
+				sources = append(sources, ch)
+				seen.Insert(name)
+			}
+		}
+		if len(sources) == 0 {
+			continue
+		}
+		merged, err := mergeChannelBundles(p, mc.Into, sources)
+		if err != nil {
+			return fmt.Errorf("merging [%s] into %q: %v", strings.Join(sets.List(seen), ", "), mc.Into, err)
+		}
+		if _, err := merged.Head(); err != nil {
+			return fmt.Errorf("merging [%s] into %q: the merged upgrade graphs must end in a single head: %v", strings.Join(sets.List(seen), ", "), mc.Into, err)
+		}
+		for _, ch := range sources {
+			delete(p.Channels, ch.Name)
+			if p.DefaultChannel == ch {
+				p.DefaultChannel = merged
+			}
+		}
+		p.Channels[merged.Name] = merged
+	}
+	return nil
+}
+
+// mergeChannelBundles returns a channel containing copies of the bundles of the source channels. A bundle in more
+// than one source keeps the edges of the earliest one, and skips the bundles the other entries replace or skip.
+func mergeChannelBundles(p *model.Package, name string, sources []*model.Channel) (*model.Channel, error) {
+	merged := &model.Channel{Package: p, Name: name, Bundles: map[string]*model.Bundle{}}
+	byVersion := map[string]string{}
+	for _, src := range sources {
+		if src.Name == name {
+			merged.Deprecation = src.Deprecation
+			merged.Properties = src.Properties
+		}
+		for _, bName := range sets.List(sets.KeySet(src.Bundles)) {
+			b := src.Bundles[bName]
+			if existing, ok := merged.Bundles[b.Name]; ok {
+				skips := sets.New(existing.Skips...)
+				for _, ref := range append([]string{b.Replaces}, b.Skips...) {
+					if ref != "" && ref != existing.Replaces && !skips.Has(ref) {
+						existing.Skips = append(existing.Skips, ref)
+						skips.Insert(ref)
+					}
+				}
+				continue
+			}
+			if other, ok := byVersion[b.Version.String()]; ok {
+				return nil, fmt.Errorf("bundles %q and %q have the same version %s", other, b.Name, b.Version)
+			}
+			byVersion[b.Version.String()] = b.Name
+			cp := *b
+			cp.Channel = merged
+			cp.Skips = append([]string(nil), b.Skips...)
+			merged.Bundles[cp.Name] = &cp
+		}
+	}
+	return merged, nil
+}
@@ -0,0 +1,142 @@
+package filter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+// mergeCatalog has a package foo with a stable channel 1.0.0 -> 1.1.0 -> 1.2.0, a fast channel that continues it to
+// 2.0.0 and in which 1.2.0 skips 1.0.0, a lone channel whose only bundle is 3.0.0, and a rebuild channel whose only
+// bundle is another build of 1.0.0.
+func mergeCatalog() *declcfg.DeclarativeConfig {
+	bundle := func(name, version string) declcfg.Bundle {
+		return declcfg.Bundle{
+			Schema:     declcfg.SchemaBundle,
+			Name:       name,
+			Package:    "foo",
+			Image:      "example.com/" + name,
+			Properties: []property.Property{property.MustBuildPackage("foo", version)},
+		}
+	}
+	return &declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{{Schema: declcfg.SchemaPackage, Name: "foo", DefaultChannel: "fast"}},
+		Channels: []declcfg.Channel{
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "stable", Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v1.0.0"},
+				{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+				{Name: "foo.v1.2.0", Replaces: "foo.v1.1.0"},
+			}},
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "fast", Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v1.2.0", Skips: []string{"foo.v1.0.0"}},
+				{Name: "foo.v2.0.0", Replaces: "foo.v1.2.0"},
+			}},
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "lone", Entries: []declcfg.ChannelEntry{{Name: "foo.v3.0.0"}}},
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "rebuild", Entries: []declcfg.ChannelEntry{{Name: "foo.v1.0.0-rebuild"}}},
+		},
+		Bundles: []declcfg.Bundle{
+			bundle("foo.v1.0.0", "1.0.0"), bundle("foo.v1.1.0", "1.1.0"), bundle("foo.v1.2.0", "1.2.0"), bundle("foo.v2.0.0", "2.0.0"),
+			bundle("foo.v3.0.0", "3.0.0"), bundle("foo.v1.0.0-rebuild", "1.0.0"),
+		},
+	}
+}
+
+func TestMergeChannels(t *testing.T) {
+	tests := []struct {
+		name               string
+		foo                v1.Package
+		want               map[string][]string
+		wantDefaultChannel string
+		wantSkips          []string
+		wantErr            string
+	}{
+		{
+			name: "into one of the sources",
+			foo: v1.Package{
+				Name:          "foo",
+				Channels:      []v1.Channel{{Name: "stable"}, {Name: "fast"}},
+				MergeChannels: []v1.ChannelMerge{{Into: "stable", From: []string{"fast"}}},
+			},
+			want:               map[string][]string{"stable": {"1.0.0", "1.1.0", "1.2.0", "2.0.0"}},
+			wantDefaultChannel: "stable",
+			wantSkips:          []string{"foo.v1.0.0"},
+		},
+		{
+			name: "into a new channel",
+			foo: v1.Package{
+				Name:          "foo",
+				Channels:      []v1.Channel{{Name: "stable"}, {Name: "fast"}},
+				MergeChannels: []v1.ChannelMerge{{Into: "all", From: []string{"fast", "stable"}}},
+			},
+			want:               map[string][]string{"all": {"1.0.0", "1.1.0", "1.2.0", "2.0.0"}},
+			wantDefaultChannel: "all",
+			wantSkips:          []string{"foo.v1.0.0", "foo.v1.1.0"},
+		},
+		{
+			name: "sources that were not kept are ignored",
+			foo: v1.Package{
+				Name:           "foo",
+				DefaultChannel: "stable",
+				Channels:       []v1.Channel{{Name: "stable"}},
+				MergeChannels:  []v1.ChannelMerge{{Into: "stable", From: []string{"fast"}}, {Into: "lone", From: []string{"rebuild"}}},
+			},
+			want:               map[string][]string{"stable": {"1.0.0", "1.1.0", "1.2.0"}},
+			wantDefaultChannel: "stable",
+		},
+		{
+			name: "more than one head",
+			foo: v1.Package{
+				Name:          "foo",
+				Channels:      []v1.Channel{{Name: "fast"}, {Name: "lone"}},
+				MergeChannels: []v1.ChannelMerge{{Into: "fast", From: []string{"lone"}}},
+			},
+			wantErr: `merging [fast, lone] into "fast": the merged upgrade graphs must end in a single head`,
+		},
+		{
+			name: "the same version",
+			foo: v1.Package{
+				Name:          "foo",
+				Channels:      []v1.Channel{{Name: "fast"}, {Name: "stable"}, {Name: "rebuild"}},
+				MergeChannels: []v1.ChannelMerge{{Into: "stable", From: []string{"rebuild"}}},
+			},
+			wantErr: `merging [rebuild, stable] into "stable": bundles "foo.v1.0.0" and "foo.v1.0.0-rebuild" have the same version 1.0.0`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := declcfg.ConvertToModel(*mergeCatalog())
+			if err != nil {
+				t.Fatal(err)
+			}
+			config := v1.FilterConfiguration{
+				TypeMeta: metav1.TypeMeta{Kind: v1.Kind, APIVersion: v1.APIVersion},
+				Packages: []v1.Package{tt.foo},
+			}
+			_, err = FilterModel(m, config)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := keptVersions(m, "foo"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected channels %v, got %v", tt.want, got)
+			}
+			if got := m["foo"].DefaultChannel.Name; got != tt.wantDefaultChannel {
+				t.Errorf("expected default channel %q, got %q", tt.wantDefaultChannel, got)
+			}
+			if got := m["foo"].DefaultChannel.Bundles["foo.v1.2.0"].Skips; !reflect.DeepEqual(got, tt.wantSkips) {
+				t.Errorf("expected foo.v1.2.0 to skip %v, got %v", tt.wantSkips, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/operator-framework/operator-registry/alpha/model"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// validateMonotonicChains verifies that, in every channel of the model, each bundle on the replaces chain from the
+// head has a higher version than the bundle it replaces, and that every bundle has a higher version than the bundles
+// it skips.
+func validateMonotonicChains(m model.Model) error {
+	var errs []string
+	for _, pkgName := range sets.List(sets.KeySet(m)) {
+		pkg := m[pkgName]
+		for _, chName := range sets.List(sets.KeySet(pkg.Channels)) {
+			violations, err := monotonicViolations(pkg.Channels[chName])
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("package %q, channel %q: %v", pkgName, chName, err))
+				continue
+			}
+			for _, v := range violations {
+				errs = append(errs, fmt.Sprintf("package %q, channel %q: %s", pkgName, chName, v))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("upgrade edges are not ordered by version:\n  %s", strings.Join(errs, "\n  "))
+	}
+	return nil
+}
+
+// monotonicViolations describes each step of the channel's replaces chain, from the head, where a bundle does not
+// have a higher version than the bundle it replaces, followed by each skip, in bundle name order, of a bundle that
+// does not have a higher version than the bundle it skips. Skipped bundles that are not in the channel are ignored.
+func monotonicViolations(ch *model.Channel) ([]string, error) {
+	head, err := ch.Head()
+	if err != nil {
+		return nil, err
+	}
+	var violations []string
+	visited := sets.New[string]()
+	for cur := head; cur != nil && !visited.Has(cur.Name); cur = ch.Bundles[cur.Replaces] {
+		visited.Insert(cur.Name)
+		replaced, ok := ch.Bundles[cur.Replaces]
+		if ok && !cur.Version.GT(replaced.Version) {
+			violations = append(violations, fmt.Sprintf("bundle %q with version %q replaces bundle %q with version %q", cur.Name, cur.Version, replaced.Name, replaced.Version))
+		}
+	}
+	for _, name := range sets.List(sets.KeySet(ch.Bundles)) {
+		b := ch.Bundles[name]
+		for _, skip := range b.Skips {
+			skipped, ok := ch.Bundles[skip]
+			if ok && !b.Version.GT(skipped.Version) {
+				violations = append(violations, fmt.Sprintf("bundle %q with version %q skips bundle %q with version %q", b.Name, b.Version, skipped.Name, skipped.Version))
+			}
+		}
+	}
+	return violations, nil
+}
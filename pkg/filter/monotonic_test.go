@@ -0,0 +1,82 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+func TestEnforceMonotonicChain(t *testing.T) {
+	// ordered is 1.0.0 -> 1.1.0 -> 1.2.0, backwards is 1.0.0 -> 1.2.0 -> 1.1.0, and in skipsNewer 2.1.0 replaces
+	// 2.0.0 and skips 3.0.0.
+	bundle := func(version string) declcfg.Bundle {
+		return declcfg.Bundle{
+			Schema:     declcfg.SchemaBundle,
+			Name:       "foo.v" + version,
+			Package:    "foo",
+			Image:      "example.com/foo:v" + version,
+			Properties: []property.Property{property.MustBuildPackage("foo", version)},
+		}
+	}
+	fbc := &declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{{Schema: declcfg.SchemaPackage, Name: "foo", DefaultChannel: "ordered"}},
+		Channels: []declcfg.Channel{
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "ordered", Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v1.0.0"},
+				{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+				{Name: "foo.v1.2.0", Replaces: "foo.v1.1.0"},
+			}},
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "backwards", Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v1.0.0"},
+				{Name: "foo.v1.2.0", Replaces: "foo.v1.0.0"},
+				{Name: "foo.v1.1.0", Replaces: "foo.v1.2.0"},
+			}},
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "skipsNewer", Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v3.0.0"},
+				{Name: "foo.v2.0.0"},
+				{Name: "foo.v2.1.0", Replaces: "foo.v2.0.0", Skips: []string{"foo.v3.0.0"}},
+			}},
+		},
+		Bundles: []declcfg.Bundle{bundle("1.0.0"), bundle("1.1.0"), bundle("1.2.0"), bundle("2.0.0"), bundle("2.1.0"), bundle("3.0.0")},
+	}
+
+	tests := []struct {
+		name    string
+		channel string
+		enforce bool
+		wantErr string
+	}{
+		{name: "ordered", channel: "ordered", enforce: true},
+		{name: "not enforced", channel: "backwards"},
+		{
+			name:    "replaces a higher version",
+			channel: "backwards",
+			enforce: true,
+			wantErr: `package "foo", channel "backwards": bundle "foo.v1.1.0" with version "1.1.0" replaces bundle "foo.v1.2.0" with version "1.2.0"`,
+		},
+		{
+			name:    "skips a higher version",
+			channel: "skipsNewer",
+			enforce: true,
+			wantErr: `package "foo", channel "skipsNewer": bundle "foo.v2.1.0" with version "2.1.0" skips bundle "foo.v3.0.0" with version "3.0.0"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := filterChannel(t, fbc, v1.Channel{Name: tt.channel}, WithEnforceMonotonicChain(tt.enforce))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package filter
+
+import (
+	"io"
+	"log/slog"
+	"regexp"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+// Option changes how a FilterConfiguration is applied.
+type Option func(*filterOptions)
+
+func newFilterOptions(opts []Option) filterOptions {
+	o := filterOptions{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithWarnings sends each warning to warnf as soon as it is reported, in addition to collecting it in the
+// FilterResult.
+func WithWarnings(warnf func(Warning)) Option {
+	return func(o *filterOptions) { o.warnf = warnf }
+}
+
+// WithLogger logs how filtering proceeds to logger at debug level, such as how the head and tail of each channel's
+// kept bundles were chosen. Nothing is logged by default. Warnings are still only reported with WithWarnings.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *filterOptions) { o.logger = logger }
+}
+
+// WithKeepReferencedChannels keeps the channels that contain bundles referenced by a kept channel, in packages that
+// list their channels.
+func WithKeepReferencedChannels(keep bool) Option {
+	return func(o *filterOptions) { o.keepReferencedChannels = keep }
+}
+
+// WithRequireFullReachability fails filtering if a kept bundle cannot upgrade to its channel head.
+func WithRequireFullReachability(require bool) Option {
+	return func(o *filterOptions) { o.requireFullReachability = require }
+}
+
+// WithEnforceMonotonicChain fails filtering if a kept bundle replaces or skips a bundle with the same or a higher
+// version.
+func WithEnforceMonotonicChain(enforce bool) Option {
+	return func(o *filterOptions) { o.enforceMonotonicChain = enforce }
+}
+
+// WithInventory keeps exactly the bundle versions of the inventory instead of applying the configured version
+// ranges.
+func WithInventory(inv Inventory) Option {
+	return func(o *filterOptions) { o.inventory = inv }
+}
+
+// WithExcludeChannels drops the channels matching re from packages that do not list their channels. No channels are
+// dropped this way by default; pass DefaultExcludeChannelRegex to match the command line's behavior.
+func WithExcludeChannels(re *regexp.Regexp) Option {
+	return func(o *filterOptions) { o.excludeChannels = re }
+}
+
+// WithChannelPolicy drops the channels that the policy does not allow from every package.
+func WithChannelPolicy(policy *ChannelPolicy) Option {
+	return func(o *filterOptions) { o.channelPolicy = policy }
+}
+
+// WithMaxTotalBundles limits the number of distinct bundles in the filtered catalog, enforced according to strategy,
+// which is BundleBudgetStrategyError or BundleBudgetStrategyTrimOldest.
+func WithMaxTotalBundles(max int, strategy string) Option {
+	return func(o *filterOptions) {
+		o.maxTotalBundles = max
+		o.maxTotalBundlesStrategy = strategy
+	}
+}
+
+// WithAssertHeadsUnchanged fails filtering if a channel's head changes although its version constraint and
+// excludeVersions match the source head.
+func WithAssertHeadsUnchanged(assert bool) Option {
+	return func(o *filterOptions) { o.assertHeadsUnchanged = assert }
+}
+
+// WithExcludeDeprecated removes deprecated packages, channels, and bundles after the configuration is applied.
+func WithExcludeDeprecated(exclude bool) Option {
+	return func(o *filterOptions) { o.excludeDeprecated = exclude }
+}
+
+// WithRollbackSafe keeps the bundle that each kept bundle replaces.
+func WithRollbackSafe(rollbackSafe bool) Option {
+	return func(o *filterOptions) { o.rollbackSafe = rollbackSafe }
+}
+
+// WithPruneEmpty removes channels without bundles and packages without channels before the result is validated.
+// Filtering never leaves them behind, so this only changes models that were built without being validated.
+func WithPruneEmpty(prune bool) Option {
+	return func(o *filterOptions) { o.pruneEmpty = prune }
+}
+
+// WithDropDanglingEdges removes the replaces and skips of kept bundles that name bundles that were filtered out. OLM
+// upgrades an installed bundle along these edges even when the bundle it names is not in the catalog, so dropping
+// them stops installations of filtered-out bundles from upgrading to the kept ones.
+func WithDropDanglingEdges(drop bool) Option {
+	return func(o *filterOptions) { o.dropDanglingEdges = drop }
+}
+
+// WithBestEffort continues with the remaining packages when a package cannot be filtered, instead of failing. The
+// packages that fail are removed from the filtered catalog and reported in the FilterResult's Errors; the packages
+// that remain are still validated.
+func WithBestEffort(bestEffort bool) Option {
+	return func(o *filterOptions) { o.bestEffort = bestEffort }
+}
+
+// WithAttribution records the configuration rule that kept each bundle in the FilterResult's Attributions, as each
+// package is filtered. packages are the configuration's packages as written, before NameRegex entries were expanded
+// or packageSelector matches added, which the attributions refer to by index; when nil, they refer to the packages
+// of the configuration being applied.
+func WithAttribution(packages []v1.Package) Option {
+	return func(o *filterOptions) { o.attribution, o.attributionPackages = true, packages }
+}
+
+// WithWorkers filters up to this many packages at once. Warnings, errors, and the filtered catalog are the same as
+// when the packages are filtered one at a time, which is the default. Values below one are treated as one.
+func WithWorkers(workers int) Option {
+	return func(o *filterOptions) { o.workers = workers }
+}
@@ -0,0 +1,19 @@
+package filter
+
+import (
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/model"
+)
+
+// RetainedOthers returns the objects with schemas the model does not represent that still belong in the filtered
+// catalog: those of the packages that were kept, and those that belong to no package. They are returned unchanged,
+// in their original order.
+func RetainedOthers(others []declcfg.Meta, m model.Model) []declcfg.Meta {
+	var retained []declcfg.Meta
+	for _, o := range others {
+		if _, ok := m[o.Package]; ok || o.Package == "" {
+			retained = append(retained, o)
+		}
+	}
+	return retained
+}
@@ -0,0 +1,38 @@
+package filter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/model"
+)
+
+func TestRetainedOthers(t *testing.T) {
+	others := []declcfg.Meta{
+		{Schema: "example.com.icon", Package: "foo", Name: "foo-icon"},
+		{Schema: "example.com.notes", Name: "catalog-notes"},
+		{Schema: "example.com.icon", Package: "bar", Name: "bar-icon"},
+		{Schema: "example.com.notes", Package: "foo", Name: "foo-notes"},
+	}
+	tests := []struct {
+		name string
+		m    model.Model
+		want []string
+	}{
+		{name: "every package kept", m: model.Model{"foo": {}, "bar": {}}, want: []string{"foo-icon", "catalog-notes", "bar-icon", "foo-notes"}},
+		{name: "objects of removed packages dropped", m: model.Model{"foo": {}}, want: []string{"foo-icon", "catalog-notes", "foo-notes"}},
+		{name: "objects without a package kept", m: model.Model{}, want: []string{"catalog-notes"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, o := range RetainedOthers(others, tt.m) {
+				got = append(got, o.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected objects %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package filter
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/operator-framework/operator-registry/alpha/model"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/yaml"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+// ChannelPolicy constrains which channels can be kept in any package, regardless of the filter configuration.
+// A nil allowed set allows every channel that is not denied.
+type ChannelPolicy struct {
+	allowed sets.Set[string]
+	denied  sets.Set[string]
+}
+
+// LoadChannelPolicy reads the allowed and denied channels files, either of which may be empty. Each file is a
+// YAML or JSON list of channel names.
+func LoadChannelPolicy(allowedFile, deniedFile string) (*ChannelPolicy, error) {
+	if allowedFile == "" && deniedFile == "" {
+		return nil, nil
+	}
+	policy := &ChannelPolicy{denied: sets.New[string]()}
+	if allowedFile != "" {
+		allowed, err := loadChannelList(allowedFile)
+		if err != nil {
+			return nil, fmt.Errorf("load allowed channels: %v", err)
+		}
+		policy.allowed = allowed
+	}
+	if deniedFile != "" {
+		denied, err := loadChannelList(deniedFile)
+		if err != nil {
+			return nil, fmt.Errorf("load denied channels: %v", err)
+		}
+		policy.denied = denied
+	}
+	return policy, nil
+}
+
+func loadChannelList(path string) (sets.Set[string], error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	if err := yaml.Unmarshal(data, &names); err != nil {
+		return nil, fmt.Errorf("parse %q: %v", path, err)
+	}
+	return sets.New(names...), nil
+}
+
+func (cp *ChannelPolicy) allows(name string) bool {
+	if cp.denied.Has(name) {
+		return false
+	}
+	return cp.allowed == nil || cp.allowed.Has(name)
+}
+
+// apply removes the channels of the package that the policy does not allow, warning about each channel that the
+// package configuration asked for.
+func (cp *ChannelPolicy) apply(p *model.Package, pkgConfig v1.Package, warnf logFunc) {
+	requested := sets.New[string]()
+	for _, c := range pkgConfig.Channels {
+		requested.Insert(c.Name)
+	}
+	var dropped []string
+	for _, name := range sets.List(sets.KeySet(p.Channels)) {
+		if cp.allows(name) {
+			continue
+		}
+		if requested.Has(name) {
+			warnf(Warning{Code: WarningChannelDeniedByPolicy, Package: p.Name, Channel: name}.withMessage("dropping channel %q from package %q: it is configured but not allowed by the channel policy", name, p.Name))
+		} else {
+			dropped = append(dropped, name)
+		}
+		delete(p.Channels, name)
+	}
+	if len(dropped) > 0 {
+		warnf(Warning{Code: WarningChannelDeniedByPolicy, Package: p.Name}.withMessage("dropping channels [%s] from package %q: they are not allowed by the channel policy", strings.Join(dropped, ", "), p.Name))
+	}
+}
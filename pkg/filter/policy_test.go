@@ -0,0 +1,133 @@
+package filter
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+// writeChannelList writes a channel list file and returns its path, or returns an empty path for empty data.
+func writeChannelList(t *testing.T, name, data string) string {
+	t.Helper()
+	if data == "" {
+		return ""
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadChannelPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed string
+		denied  string
+		// want lists whether each of stable, candidate, and alpha is allowed.
+		want    []bool
+		wantErr string
+	}{
+		{name: "allowed", allowed: "- stable\n- alpha\n", want: []bool{true, false, true}},
+		{name: "denied", denied: `["candidate"]`, want: []bool{true, false, true}},
+		{name: "denied wins over allowed", allowed: "- stable\n- candidate\n", denied: "- candidate\n", want: []bool{true, false, false}},
+		{name: "invalid allowed", allowed: "stable: true\n", wantErr: "load allowed channels"},
+		{name: "invalid denied", denied: "stable: true\n", wantErr: "load denied channels"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := LoadChannelPolicy(writeChannelList(t, "allowed.yaml", tt.allowed), writeChannelList(t, "denied.yaml", tt.denied))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got []bool
+			for _, name := range []string{"stable", "candidate", "alpha"} {
+				got = append(got, policy.allows(name))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	if policy, err := LoadChannelPolicy("", ""); policy != nil || err != nil {
+		t.Errorf("expected no policy without files, got %v, %v", policy, err)
+	}
+}
+
+func TestChannelPolicy(t *testing.T) {
+	tests := []struct {
+		name         string
+		foo          v1.Package
+		denied       string
+		want         map[string][]string
+		wantWarnings []WarningCode
+		wantErr      string
+	}{
+		{
+			name:         "channels not configured",
+			foo:          v1.Package{Name: "foo"},
+			denied:       "- candidate\n",
+			want:         map[string][]string{"stable": {"1.0.0", "1.1.0", "1.2.0", "2.0.0"}},
+			wantWarnings: []WarningCode{WarningChannelDeniedByPolicy},
+		},
+		{
+			name:         "configured channel",
+			foo:          v1.Package{Name: "foo", Channels: []v1.Channel{{Name: "stable"}, {Name: "candidate"}}},
+			denied:       "- candidate\n",
+			want:         map[string][]string{"stable": {"1.0.0", "1.1.0", "1.2.0", "2.0.0"}},
+			wantWarnings: []WarningCode{WarningChannelDeniedByPolicy},
+		},
+		{
+			name:    "default channel denied",
+			foo:     v1.Package{Name: "foo"},
+			denied:  "- stable\n",
+			wantErr: `the default channel "stable" was filtered out`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := LoadChannelPolicy("", writeChannelList(t, "denied.yaml", tt.denied))
+			if err != nil {
+				t.Fatal(err)
+			}
+			m, err := declcfg.ConvertToModel(*filterCatalog())
+			if err != nil {
+				t.Fatal(err)
+			}
+			config := v1.FilterConfiguration{
+				TypeMeta: metav1.TypeMeta{Kind: v1.Kind, APIVersion: v1.APIVersion},
+				Packages: []v1.Package{tt.foo},
+			}
+			result, err := FilterModel(m, config, WithChannelPolicy(policy))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := keptVersions(m, "foo"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected channels %v, got %v", tt.want, got)
+			}
+			if got := warningCodes(result.Warnings); !reflect.DeepEqual(got, tt.wantWarnings) {
+				t.Errorf("expected warnings %v, got %v", tt.wantWarnings, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package filter
+
+import (
+	"fmt"
+
+	"github.com/operator-framework/operator-registry/alpha/model"
+)
+
+// releasesMatcher narrows a matcher to the bundles whose version is not a prerelease. A nil matcher selects every
+// bundle.
+func releasesMatcher(matcher *bundleMatcher) bundleMatcher {
+	if matcher == nil {
+		return bundleMatcher{
+			description: "releases",
+			matches:     func(b *model.Bundle) bool { return len(b.Version.Pre) == 0 },
+			rule:        fixedRule(keptByReleases),
+		}
+	}
+	base := *matcher
+	return bundleMatcher{
+		description: fmt.Sprintf("%s, excluding prereleases", base.description),
+		matches:     func(b *model.Bundle) bool { return len(b.Version.Pre) == 0 && base.matches(b) },
+		rule:        base.rule,
+	}
+}
@@ -0,0 +1,81 @@
+package filter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+// prereleaseCatalog has a package foo with a stable channel 1.0.0 -> 1.1.0-rc.1 -> 1.1.0 -> 1.2.0-rc.1.
+func prereleaseCatalog() *declcfg.DeclarativeConfig {
+	bundle := func(version string) declcfg.Bundle {
+		return declcfg.Bundle{
+			Schema:     declcfg.SchemaBundle,
+			Name:       "foo.v" + version,
+			Package:    "foo",
+			Image:      "example.com/foo:v" + version,
+			Properties: []property.Property{property.MustBuildPackage("foo", version)},
+		}
+	}
+	return &declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{{Schema: declcfg.SchemaPackage, Name: "foo", DefaultChannel: "stable"}},
+		Channels: []declcfg.Channel{{Schema: declcfg.SchemaChannel, Package: "foo", Name: "stable", Entries: []declcfg.ChannelEntry{
+			{Name: "foo.v1.0.0"},
+			{Name: "foo.v1.1.0-rc.1", Replaces: "foo.v1.0.0"},
+			{Name: "foo.v1.1.0", Replaces: "foo.v1.1.0-rc.1"},
+			{Name: "foo.v1.2.0-rc.1", Replaces: "foo.v1.1.0"},
+		}}},
+		Bundles: []declcfg.Bundle{bundle("1.0.0"), bundle("1.1.0-rc.1"), bundle("1.1.0"), bundle("1.2.0-rc.1")},
+	}
+}
+
+func TestExcludePrereleases(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel v1.Channel
+		want    []string
+	}{
+		{name: "alone", channel: v1.Channel{Name: "stable", ExcludePrereleases: true}, want: []string{"1.0.0", "1.1.0-rc.1", "1.1.0"}},
+		{name: "narrows keepLatest", channel: v1.Channel{Name: "stable", KeepLatest: 3, ExcludePrereleases: true}, want: []string{"1.1.0"}},
+		{name: "narrows an expression", channel: v1.Channel{Name: "stable", Expression: `bundle.version != "1.0.0"`, ExcludePrereleases: true}, want: []string{"1.1.0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := filterChannel(t, prereleaseCatalog(), tt.channel)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected versions %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestIncludePrereleases(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel v1.Channel
+		want    []string
+	}{
+		{name: "versionRange", channel: v1.Channel{Name: "stable", VersionRange: ">=1.1.0"}, want: []string{"1.1.0"}},
+		{name: "versionRange including prereleases", channel: v1.Channel{Name: "stable", VersionRange: ">=1.1.0", IncludePrereleases: true}, want: []string{"1.1.0-rc.1", "1.1.0", "1.2.0-rc.1"}},
+		{name: "maxVersion including prereleases", channel: v1.Channel{Name: "stable", MaxVersion: "1.1.0", IncludePrereleases: true}, want: []string{"1.0.0", "1.1.0-rc.1", "1.1.0"}},
+		{name: "versionRange naming a prerelease", channel: v1.Channel{Name: "stable", VersionRange: ">=1.2.0-rc.0"}, want: []string{"1.2.0-rc.1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := filterChannel(t, prereleaseCatalog(), tt.channel)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected versions %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package filter
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/operator-framework/operator-registry/alpha/model"
+	"github.com/operator-framework/operator-registry/alpha/property"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+// providedAPIsMatcher matches the bundles whose olm.gvk properties provide every one of the given APIs.
+func providedAPIsMatcher(apis []v1.GVK) bundleMatcher {
+	descriptions := make([]string, 0, len(apis))
+	for _, api := range apis {
+		descriptions = append(descriptions, api.String())
+	}
+	return bundleMatcher{
+		description: fmt.Sprintf("provided APIs [%s]", strings.Join(descriptions, ", ")),
+		matches: func(b *model.Bundle) bool {
+			var provided []property.GVK
+			if b.PropertiesP != nil {
+				provided = b.PropertiesP.GVKs
+			}
+			for _, api := range apis {
+				if !providesAPI(provided, api) {
+					return false
+				}
+			}
+			return true
+		},
+		rule: fixedRule(keptByProvidedAPIs),
+	}
+}
+
+func providesAPI(provided []property.GVK, api v1.GVK) bool {
+	for _, gvk := range provided {
+		if gvk.Group == api.Group && gvk.Kind == api.Kind && (api.Version == "" || gvk.Version == api.Version) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterProvidedAPIs removes the bundles that do not provide the providedAPIs of their channel. It runs after the
+// channel's bundles are selected by its version options, and keeps the channel coherent in the same way, so a kept
+// bundle that is needed to connect the head to older matching bundles may lack some of the APIs.
+func filterProvidedAPIs(p *model.Package, pkgConfig v1.Package, rules bundleRules, logger *slog.Logger, warnf logFunc) error {
+	for _, c := range pkgConfig.Channels {
+		ch, ok := p.Channels[c.Name]
+		if !ok || len(c.ProvidedAPIs) == 0 {
+			continue
+		}
+		matcher := providedAPIsMatcher(c.ProvidedAPIs)
+		if err := filterBundles(ch, matcher, logger, warnf); err != nil {
+			return err
+		}
+		rules.narrow(ch, matcher)
+	}
+	return nil
+}
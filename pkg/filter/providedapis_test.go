@@ -0,0 +1,94 @@
+package filter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+func TestProvidedAPIs(t *testing.T) {
+	// stable is 1.0.0 -> 1.1.0 -> 1.2.0 -> 2.0.0. Widget moves from v1alpha1 to v1 in 1.1.0, Gadget is added in 1.2.0,
+	// and Widget is removed in 2.0.0.
+	bundle := func(version string, gvks ...property.Property) declcfg.Bundle {
+		return declcfg.Bundle{
+			Schema:     declcfg.SchemaBundle,
+			Name:       "foo.v" + version,
+			Package:    "foo",
+			Image:      "example.com/foo:v" + version,
+			Properties: append([]property.Property{property.MustBuildPackage("foo", version)}, gvks...),
+		}
+	}
+	widgetV1alpha1 := property.MustBuildGVK("example.com", "v1alpha1", "Widget")
+	widgetV1 := property.MustBuildGVK("example.com", "v1", "Widget")
+	gadgetV1 := property.MustBuildGVK("example.com", "v1", "Gadget")
+	fbc := &declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{{Schema: declcfg.SchemaPackage, Name: "foo", DefaultChannel: "stable"}},
+		Channels: []declcfg.Channel{{Schema: declcfg.SchemaChannel, Package: "foo", Name: "stable", Entries: []declcfg.ChannelEntry{
+			{Name: "foo.v1.0.0"},
+			{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+			{Name: "foo.v1.2.0", Replaces: "foo.v1.1.0"},
+			{Name: "foo.v2.0.0", Replaces: "foo.v1.2.0"},
+		}}},
+		Bundles: []declcfg.Bundle{
+			bundle("1.0.0", widgetV1alpha1),
+			bundle("1.1.0", widgetV1),
+			bundle("1.2.0", widgetV1, gadgetV1),
+			bundle("2.0.0", gadgetV1),
+		},
+	}
+
+	tests := []struct {
+		name    string
+		channel v1.Channel
+		want    []string
+		wantErr string
+	}{
+		{
+			name:    "any version",
+			channel: v1.Channel{Name: "stable", ProvidedAPIs: []v1.GVK{{Group: "example.com", Kind: "Widget"}}},
+			want:    []string{"1.0.0", "1.1.0", "1.2.0"},
+		},
+		{
+			name:    "a version",
+			channel: v1.Channel{Name: "stable", ProvidedAPIs: []v1.GVK{{Group: "example.com", Version: "v1", Kind: "Widget"}}},
+			want:    []string{"1.1.0", "1.2.0"},
+		},
+		{
+			name:    "every API",
+			channel: v1.Channel{Name: "stable", ProvidedAPIs: []v1.GVK{{Group: "example.com", Kind: "Widget"}, {Group: "example.com", Kind: "Gadget"}}},
+			want:    []string{"1.2.0"},
+		},
+		{
+			name:    "narrows minVersion",
+			channel: v1.Channel{Name: "stable", MinVersion: "1.1.0", ProvidedAPIs: []v1.GVK{{Group: "example.com", Kind: "Gadget"}}},
+			want:    []string{"1.2.0", "2.0.0"},
+		},
+		{
+			name:    "not provided",
+			channel: v1.Channel{Name: "stable", ProvidedAPIs: []v1.GVK{{Group: "example.com", Version: "v2", Kind: "Widget"}}},
+			wantErr: `no bundles in channel "stable" for package "foo" matched the provided APIs [example.com/v2, Kind=Widget]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := filterChannel(t, fbc, tt.channel)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected versions %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
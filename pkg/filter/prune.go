@@ -0,0 +1,45 @@
+package filter
+
+import (
+	"fmt"
+
+	"github.com/operator-framework/operator-registry/alpha/model"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+// pruneEmpty removes channels without bundles and then packages without channels. When a package's default
+// channel is removed, a new one is chosen from its configuration as if the channel had been filtered out.
+func pruneEmpty(m model.Model, configuration v1.FilterConfiguration, warnf logFunc) error {
+	pkgConfigs := map[string]v1.Package{}
+	for _, p := range configuration.Packages {
+		pkgConfigs[p.Name] = p
+	}
+	for _, pkgName := range sets.List(sets.KeySet(m)) {
+		pkg := m[pkgName]
+		var pruned []string
+		for _, chName := range sets.List(sets.KeySet(pkg.Channels)) {
+			if len(pkg.Channels[chName].Bundles) == 0 {
+				pruned = append(pruned, chName)
+				delete(pkg.Channels, chName)
+			}
+		}
+		if len(pkg.Channels) == 0 {
+			warnf(Warning{Code: WarningEmptyPruned, Package: pkgName}.withMessage("pruning package %q: it has no channels with bundles", pkgName))
+			delete(m, pkgName)
+			continue
+		}
+		if len(pruned) == 0 {
+			continue
+		}
+		warnf(Warning{Code: WarningEmptyPruned, Package: pkgName}.withMessage("pruning channels %v from package %q: they have no bundles", pruned, pkgName))
+		if pkg.DefaultChannel == nil {
+			continue
+		}
+		if err := setDefaultChannel(pkg, pkgConfigs[pkgName], warnf); err != nil {
+			return fmt.Errorf("could not prune package %q: %v", pkgName, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/model"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+func TestPruneEmpty(t *testing.T) {
+	tests := []struct {
+		name string
+		// defaultBeta makes the empty beta channel the default channel of pkg-0.
+		defaultBeta  bool
+		prune        bool
+		wantChannels []string
+		wantDefault  string
+		wantWarnings []WarningCode
+		wantErr      string
+	}{
+		{
+			name:    "not pruned",
+			wantErr: "filtered model is invalid",
+		},
+		{
+			name:         "empty channel and package",
+			prune:        true,
+			wantChannels: []string{"stable"},
+			wantDefault:  "stable",
+			wantWarnings: []WarningCode{WarningEmptyPruned, WarningEmptyPruned},
+		},
+		{
+			name:        "default channel pruned",
+			defaultBeta: true,
+			prune:       true,
+			wantErr:     `could not prune package "pkg-0": the default channel "beta" was filtered out`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// models that were not validated, such as model dumps, may hold channels and packages without
+			// anything in them: pkg-0 gets an empty beta channel, and package empty has only an empty channel.
+			m, err := declcfg.ConvertToModel(*syntheticCatalog(1, 3))
+			if err != nil {
+				t.Fatal(err)
+			}
+			pkg := m["pkg-0"]
+			pkg.Channels["beta"] = &model.Channel{Package: pkg, Name: "beta", Bundles: map[string]*model.Bundle{}}
+			if tt.defaultBeta {
+				pkg.DefaultChannel = pkg.Channels["beta"]
+			}
+			empty := &model.Package{Name: "empty"}
+			empty.DefaultChannel = &model.Channel{Package: empty, Name: "stable", Bundles: map[string]*model.Bundle{}}
+			empty.Channels = map[string]*model.Channel{"stable": empty.DefaultChannel}
+			m["empty"] = empty
+			config := syntheticConfiguration()
+			config.Packages = append(config.Packages, v1.Package{Name: "pkg-0"}, v1.Package{Name: "empty"})
+
+			result, err := FilterModel(m, config, WithPruneEmpty(tt.prune))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, ok := m["empty"]; ok {
+				t.Error("expected package empty to be pruned")
+			}
+			if got := keptChannels(m, "pkg-0"); fmt.Sprint(got) != fmt.Sprint(tt.wantChannels) {
+				t.Errorf("expected channels %v, got %v", tt.wantChannels, got)
+			}
+			if got := m["pkg-0"].DefaultChannel.Name; got != tt.wantDefault {
+				t.Errorf("expected default channel %q, got %q", tt.wantDefault, got)
+			}
+			if got := warningCodes(result.Warnings); fmt.Sprint(got) != fmt.Sprint(tt.wantWarnings) {
+				t.Errorf("expected warnings %v, got %v", tt.wantWarnings, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+
+	blangsemver "github.com/blang/semver/v4"
+	"github.com/operator-framework/operator-registry/alpha/model"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// validateFullReachability verifies that, in every channel of the model, every bundle can upgrade to the channel
+// head by following replaces, skips, and skipRange edges.
+func validateFullReachability(m model.Model) error {
+	var errs []string
+	for _, pkgName := range sets.List(sets.KeySet(m)) {
+		pkg := m[pkgName]
+		for _, chName := range sets.List(sets.KeySet(pkg.Channels)) {
+			unreachable, err := unreachableBundles(pkg.Channels[chName])
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("package %q, channel %q: %v", pkgName, chName, err))
+				continue
+			}
+			if len(unreachable) > 0 {
+				errs = append(errs, fmt.Sprintf("package %q, channel %q: bundles cannot reach channel head: %s", pkgName, chName, strings.Join(unreachable, ", ")))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("channels are not fully reachable:\n  %s", strings.Join(errs, "\n  "))
+	}
+	return nil
+}
+
+// unreachableBundles returns the sorted names of the bundles in ch that have no upgrade path to the channel head.
+func unreachableBundles(ch *model.Channel) ([]string, error) {
+	head, err := ch.Head()
+	if err != nil {
+		return nil, err
+	}
+
+	reached := sets.New[string](head.Name)
+	queue := []*model.Bundle{head}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+
+		var skipRange blangsemver.Range
+		if cur.SkipRange != "" {
+			skipRange, err = blangsemver.ParseRange(cur.SkipRange)
+			if err != nil {
+				return nil, fmt.Errorf("invalid skipRange %q for bundle %q: %v", cur.SkipRange, cur.Name, err)
+			}
+		}
+		for _, b := range ch.Bundles {
+			if reached.Has(b.Name) {
+				continue
+			}
+			if b.Name == cur.Replaces || sets.New(cur.Skips...).Has(b.Name) || (skipRange != nil && skipRange(b.Version)) {
+				reached.Insert(b.Name)
+				queue = append(queue, b)
+			}
+		}
+	}
+	return sets.List(sets.KeySet(ch.Bundles).Difference(reached)), nil
+}
@@ -0,0 +1,76 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+func TestRequireFullReachability(t *testing.T) {
+	// In connected, 2.0.0 replaces 1.1.0, which replaces 1.0.0, and also skips 1.0.0. In islands, 2.0.0 is the head
+	// and 0.9.0 and 0.9.1 only skip each other, and rescued is islands with the head also skipping both by skipRange.
+	bundle := func(version string) declcfg.Bundle {
+		return declcfg.Bundle{
+			Schema:     declcfg.SchemaBundle,
+			Name:       "foo.v" + version,
+			Package:    "foo",
+			Image:      "example.com/foo:v" + version,
+			Properties: []property.Property{property.MustBuildPackage("foo", version)},
+		}
+	}
+	islands := func(name, skipRange string) declcfg.Channel {
+		return declcfg.Channel{Schema: declcfg.SchemaChannel, Package: "foo", Name: name, Entries: []declcfg.ChannelEntry{
+			{Name: "foo.v0.9.0", Skips: []string{"foo.v0.9.1"}},
+			{Name: "foo.v0.9.1", Skips: []string{"foo.v0.9.0"}},
+			{Name: "foo.v2.0.0", SkipRange: skipRange},
+		}}
+	}
+	fbc := &declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{{Schema: declcfg.SchemaPackage, Name: "foo", DefaultChannel: "connected"}},
+		Channels: []declcfg.Channel{
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "connected", Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v1.0.0"},
+				{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+				{Name: "foo.v2.0.0", Replaces: "foo.v1.1.0", Skips: []string{"foo.v1.0.0"}},
+			}},
+			islands("islands", ""),
+			islands("rescued", ">=0.9.0 <1.0.0"),
+		},
+		Bundles: []declcfg.Bundle{bundle("0.9.0"), bundle("0.9.1"), bundle("1.0.0"), bundle("1.1.0"), bundle("2.0.0")},
+	}
+
+	tests := []struct {
+		name    string
+		channel string
+		require bool
+		wantErr string
+	}{
+		{name: "replaces and skips", channel: "connected", require: true},
+		{name: "skipRange", channel: "rescued", require: true},
+		{name: "not required", channel: "islands"},
+		{
+			name:    "unreachable",
+			channel: "islands",
+			require: true,
+			wantErr: `package "foo", channel "islands": bundles cannot reach channel head: foo.v0.9.0, foo.v0.9.1`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := filterChannel(t, fbc, v1.Channel{Name: tt.channel}, WithRequireFullReachability(tt.require))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
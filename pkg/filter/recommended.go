@@ -0,0 +1,85 @@
+package filter
+
+import (
+	"fmt"
+
+	blangsemver "github.com/blang/semver/v4"
+	"github.com/operator-framework/operator-registry/alpha/model"
+	"github.com/operator-framework/operator-registry/alpha/property"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+// PropertyTypeRecommendedVersion is the type of the channel property that records a channel's configured
+// recommendedVersion.
+const PropertyTypeRecommendedVersion = "fbc-filter.recommended-version"
+
+// RecommendedVersion is the value of a PropertyTypeRecommendedVersion property.
+type RecommendedVersion struct {
+	Version string `json:"version"`
+}
+
+func init() {
+	property.AddToScheme(PropertyTypeRecommendedVersion, &RecommendedVersion{})
+}
+
+func isRecommendedVersion(b *model.Bundle, version string) bool {
+	if version == "" {
+		return false
+	}
+	v, err := blangsemver.ParseTolerant(version)
+	return err == nil && b.Version.EQ(v)
+}
+
+func recommendedBundle(ch *model.Channel, version string) *model.Bundle {
+	for _, b := range ch.Bundles {
+		if isRecommendedVersion(b, version) {
+			return b
+		}
+	}
+	return nil
+}
+
+// recommendedVersionMatcher extends the matcher to also match the bundle with the recommended version, warning if
+// it did not match already. Filtering with it keeps that bundle, along with the bundles that connect it to the head.
+func recommendedVersionMatcher(ch *model.Channel, version string, matcher bundleMatcher, warnf logFunc) (bundleMatcher, error) {
+	recommended := recommendedBundle(ch, version)
+	if recommended == nil {
+		return bundleMatcher{}, fmt.Errorf("recommended version %s is not in channel %q (available versions: %s)", version, ch.Name, channelVersions(ch))
+	}
+	if matcher.matches(recommended) {
+		return matcher, nil
+	}
+	warnf(Warning{Code: WarningRecommendedOutsideSelection, Package: ch.Package.Name, Channel: ch.Name, Bundle: recommended.Name}.withMessage("keeping recommended bundle %q with version %q in channel %q for package %q: it falls outside the specified %s", recommended.Name, recommended.Version.String(), ch.Name, ch.Package.Name, matcher.description))
+	return bundleMatcher{
+		description: matcher.description,
+		matches: func(b *model.Bundle) bool {
+			return b == recommended || matcher.matches(b)
+		},
+		rule: extendedRule(matcher, keptByRecommended),
+	}, nil
+}
+
+// recordRecommendedVersions adds a PropertyTypeRecommendedVersion property to each kept channel that configures a
+// recommendedVersion, failing if the recommended bundle did not survive filtering. It must run after every option
+// that removes bundles, so that the property never names a bundle that is not in the channel.
+func recordRecommendedVersions(p *model.Package, pkgConfig v1.Package) error {
+	for _, c := range pkgConfig.Channels {
+		ch, ok := p.Channels[c.Name]
+		if !ok || c.RecommendedVersion == "" {
+			continue
+		}
+		recommended := recommendedBundle(ch, c.RecommendedVersion)
+		if recommended == nil {
+			return fmt.Errorf("recommended version %s was removed from channel %q after it was selected, by excludeVersions, kubeVersion, providedAPIs, or deprecation exclusion (remaining versions: %s)", c.RecommendedVersion, ch.Name, channelVersions(ch))
+		}
+		props := ch.Properties[:0:0]
+		for _, prop := range ch.Properties {
+			if prop.Type != PropertyTypeRecommendedVersion {
+				props = append(props, prop)
+			}
+		}
+		ch.Properties = append(props, property.MustBuild(&RecommendedVersion{Version: recommended.Version.String()}))
+	}
+	return nil
+}
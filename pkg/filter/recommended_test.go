@@ -0,0 +1,120 @@
+package filter
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/model"
+	"github.com/operator-framework/operator-registry/alpha/property"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+func TestRecommendedVersion(t *testing.T) {
+	// stable is 1.0.0 -> 1.1.0 -> 1.2.0 -> 2.0.0, in which only 2.0.0 needs Kubernetes 1.26.
+	bundle := func(version, minKubeVersion string) declcfg.Bundle {
+		return declcfg.Bundle{
+			Schema:  declcfg.SchemaBundle,
+			Name:    "foo.v" + version,
+			Package: "foo",
+			Image:   "example.com/foo:v" + version,
+			Properties: []property.Property{
+				property.MustBuildPackage("foo", version),
+				property.MustBuild(&property.CSVMetadata{MinKubeVersion: minKubeVersion}),
+			},
+		}
+	}
+	fbc := declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{{Schema: declcfg.SchemaPackage, Name: "foo", DefaultChannel: "stable"}},
+		Channels: []declcfg.Channel{{Schema: declcfg.SchemaChannel, Package: "foo", Name: "stable", Entries: []declcfg.ChannelEntry{
+			{Name: "foo.v1.0.0"},
+			{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+			{Name: "foo.v1.2.0", Replaces: "foo.v1.1.0"},
+			{Name: "foo.v2.0.0", Replaces: "foo.v1.2.0"},
+		}}},
+		Bundles: []declcfg.Bundle{bundle("1.0.0", "1.20.0"), bundle("1.1.0", "1.20.0"), bundle("1.2.0", "1.20.0"), bundle("2.0.0", "1.26.0")},
+	}
+	// recommended returns the versions recorded by the channel's recommended version properties.
+	recommended := func(ch *model.Channel) []string {
+		var versions []string
+		for _, p := range ch.Properties {
+			if p.Type != PropertyTypeRecommendedVersion {
+				continue
+			}
+			var rv RecommendedVersion
+			if err := json.Unmarshal(p.Value, &rv); err != nil {
+				t.Fatal(err)
+			}
+			versions = append(versions, rv.Version)
+		}
+		return versions
+	}
+
+	tests := []struct {
+		name            string
+		foo             v1.Package
+		want            []string
+		wantRecommended []string
+		wantWarnings    []WarningCode
+		wantErr         string
+	}{
+		{
+			name:            "within the selection",
+			foo:             v1.Package{Name: "foo", Channels: []v1.Channel{{Name: "stable", MinVersion: "1.1.0", RecommendedVersion: "1.2.0"}}},
+			want:            []string{"1.1.0", "1.2.0", "2.0.0"},
+			wantRecommended: []string{"1.2.0"},
+		},
+		{
+			name:            "outside the selection",
+			foo:             v1.Package{Name: "foo", Channels: []v1.Channel{{Name: "stable", HeadOnly: true, RecommendedVersion: "v1.1"}}},
+			want:            []string{"1.1.0", "1.2.0", "2.0.0"},
+			wantRecommended: []string{"1.1.0"},
+			wantWarnings:    []WarningCode{WarningRecommendedOutsideSelection, WarningOutOfRangeBundleIncluded},
+		},
+		{
+			name:    "not in the channel",
+			foo:     v1.Package{Name: "foo", Channels: []v1.Channel{{Name: "stable", HeadOnly: true, RecommendedVersion: "3.0.0"}}},
+			wantErr: `recommended version 3.0.0 is not in channel "stable"`,
+		},
+		{
+			name:    "removed after it was selected",
+			foo:     v1.Package{Name: "foo", KubeVersion: "1.24.0", Channels: []v1.Channel{{Name: "stable", MinVersion: "1.2.0", RecommendedVersion: "2.0.0"}}},
+			wantErr: `recommended version 2.0.0 was removed from channel "stable" after it was selected`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := declcfg.ConvertToModel(fbc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			config := v1.FilterConfiguration{
+				TypeMeta: metav1.TypeMeta{Kind: v1.Kind, APIVersion: v1.APIVersion},
+				Packages: []v1.Package{tt.foo},
+			}
+			result, err := FilterModel(m, config)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := keptVersions(m, "foo")["stable"]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected versions %v, got %v", tt.want, got)
+			}
+			if got := recommended(m["foo"].Channels["stable"]); !reflect.DeepEqual(got, tt.wantRecommended) {
+				t.Errorf("expected recommended versions %v, got %v", tt.wantRecommended, got)
+			}
+			if got := warningCodes(result.Warnings); !reflect.DeepEqual(got, tt.wantWarnings) {
+				t.Errorf("expected warnings %v, got %v", tt.wantWarnings, got)
+			}
+		})
+	}
+}
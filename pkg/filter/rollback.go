@@ -0,0 +1,54 @@
+package filter
+
+import (
+	"github.com/operator-framework/operator-registry/alpha/model"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// sourceChannelBundles returns a copy of the bundles of each channel of the package, keyed by channel name and
+// then bundle name, so that they are still known after the channels are filtered.
+func sourceChannelBundles(p *model.Package) map[string]map[string]*model.Bundle {
+	source := map[string]map[string]*model.Bundle{}
+	for chName, ch := range p.Channels {
+		source[chName] = make(map[string]*model.Bundle, len(ch.Bundles))
+		for name, b := range ch.Bundles {
+			source[chName][name] = b
+		}
+	}
+	return source
+}
+
+// ensureRollbackSafe re-includes, in each channel of the package, the bundle replaced by every kept bundle other
+// than the lowest version kept bundle, so that every upgrade within the channel can be rolled back by one step.
+// Re-included bundles are subject to the same rule, until they reach a version below the lowest version originally
+// kept or a bundle that replaces nothing in the source channel.
+func ensureRollbackSafe(p *model.Package, source map[string]map[string]*model.Bundle, rules bundleRules, warnf logFunc) {
+	for _, chName := range sets.List(sets.KeySet(p.Channels)) {
+		ch := p.Channels[chName]
+		var oldest *model.Bundle
+		for _, b := range ch.Bundles {
+			if oldest == nil || b.Version.LT(oldest.Version) || (b.Version.EQ(oldest.Version) && b.Name < oldest.Name) {
+				oldest = b
+			}
+		}
+		queue := sets.List(sets.KeySet(ch.Bundles))
+		for len(queue) > 0 {
+			b := ch.Bundles[queue[0]]
+			queue = queue[1:]
+			if b == oldest || !b.Version.GT(oldest.Version) {
+				continue
+			}
+			replaced, ok := source[chName][b.Replaces]
+			if !ok {
+				continue
+			}
+			if _, kept := ch.Bundles[replaced.Name]; kept {
+				continue
+			}
+			warnf(Warning{Code: WarningRollbackBundleIncluded, Package: p.Name, Channel: ch.Name, Bundle: replaced.Name}.withMessage("including bundle %q with version %q in channel %q for package %q: it is replaced by kept bundle %q and is required for rollback safety", replaced.Name, replaced.Version, ch.Name, p.Name, b.Name))
+			ch.Bundles[replaced.Name] = replaced
+			rules.set(ch.Name, replaced.Name, keptByRollbackSafe)
+			queue = append(queue, replaced.Name)
+		}
+	}
+}
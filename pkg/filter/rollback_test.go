@@ -0,0 +1,81 @@
+package filter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+func TestRollbackSafe(t *testing.T) {
+	// stable is 1.0.0 -> 1.1.0 -> 1.2.0 and 1.1.0 -> 2.0.0, in which 2.0.0 also skips 1.2.0.
+	bundle := func(version string) declcfg.Bundle {
+		return declcfg.Bundle{
+			Schema:     declcfg.SchemaBundle,
+			Name:       "foo.v" + version,
+			Package:    "foo",
+			Image:      "example.com/foo:v" + version,
+			Properties: []property.Property{property.MustBuildPackage("foo", version)},
+		}
+	}
+	fbc := &declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{{Schema: declcfg.SchemaPackage, Name: "foo", DefaultChannel: "stable"}},
+		Channels: []declcfg.Channel{{Schema: declcfg.SchemaChannel, Package: "foo", Name: "stable", Entries: []declcfg.ChannelEntry{
+			{Name: "foo.v1.0.0"},
+			{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+			{Name: "foo.v1.2.0", Replaces: "foo.v1.1.0"},
+			{Name: "foo.v2.0.0", Replaces: "foo.v1.1.0", Skips: []string{"foo.v1.2.0"}},
+		}}},
+		Bundles: []declcfg.Bundle{bundle("1.0.0"), bundle("1.1.0"), bundle("1.2.0"), bundle("2.0.0")},
+	}
+
+	tests := []struct {
+		name         string
+		channel      v1.Channel
+		rollbackSafe bool
+		want         []string
+		wantWarnings []WarningCode
+	}{
+		{
+			name:    "replaced bundle left out without the option",
+			channel: v1.Channel{Name: "stable", MinVersion: "1.2.0"},
+			want:    []string{"1.2.0", "2.0.0"},
+		},
+		{
+			name:         "replaced bundle re-included",
+			channel:      v1.Channel{Name: "stable", MinVersion: "1.2.0"},
+			rollbackSafe: true,
+			want:         []string{"1.1.0", "1.2.0", "2.0.0"},
+			wantWarnings: []WarningCode{WarningRollbackBundleIncluded},
+		},
+		{
+			name:         "nothing below the oldest kept bundle",
+			channel:      v1.Channel{Name: "stable", MinVersion: "1.1.0"},
+			rollbackSafe: true,
+			want:         []string{"1.1.0", "1.2.0", "2.0.0"},
+		},
+		{
+			name:         "head only",
+			channel:      v1.Channel{Name: "stable", HeadOnly: true},
+			rollbackSafe: true,
+			want:         []string{"2.0.0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, result, err := filterChannel(t, fbc, tt.channel, WithRollbackSafe(tt.rollbackSafe))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected versions %v, got %v", tt.want, got)
+			}
+			if got := warningCodes(result.Warnings); !reflect.DeepEqual(got, tt.wantWarnings) {
+				t.Errorf("expected warnings %v, got %v", tt.wantWarnings, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package filter
+
+import (
+	"fmt"
+
+	blangsemver "github.com/blang/semver/v4"
+	"github.com/operator-framework/operator-registry/alpha/model"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+// overrideHeadSkipRange rewrites the skipRange of the channel head. With the "auto" override, a head that has a
+// skipRange gets one spanning the kept bundles below it (">=<lowest kept version> <<head version>"), or none at all
+// if the head is the only bundle left. Any other override is parsed to make sure it is a valid range and then set
+// as the head's skipRange.
+func overrideHeadSkipRange(ch *model.Channel, override string, warnf logFunc) error {
+	head, err := ch.Head()
+	if err != nil {
+		return fmt.Errorf("error getting head of channel %q: %v", ch.Name, err)
+	}
+
+	skipRange := override
+	if override == v1.SkipRangeOverrideAuto {
+		if head.SkipRange == "" {
+			return nil
+		}
+		var lowest *blangsemver.Version
+		for _, b := range ch.Bundles {
+			if b != head && (lowest == nil || b.Version.LT(*lowest)) {
+				lowest = &b.Version
+			}
+		}
+		skipRange = ""
+		if lowest != nil {
+			skipRange = fmt.Sprintf(">=%s <%s", lowest, head.Version)
+		}
+	} else if _, err := blangsemver.ParseRange(override); err != nil {
+		return fmt.Errorf("invalid skipRangeOverride %q for channel %q: %v", override, ch.Name, err)
+	}
+
+	if skipRange != head.SkipRange {
+		warnf(Warning{Code: WarningSkipRangeRewritten, Package: ch.Package.Name, Channel: ch.Name, Bundle: head.Name}.withMessage("rewriting skipRange of bundle %q in channel %q for package %q from %q to %q", head.Name, ch.Name, ch.Package.Name, head.SkipRange, skipRange))
+		head.SkipRange = skipRange
+	}
+	return nil
+}
+
+// rewriteSkipRanges applies a package's SkipRanges setting to the kept bundles of each of its channels.
+func rewriteSkipRanges(p *model.Package, mode string, warnf logFunc) error {
+	for _, chName := range sets.List(sets.KeySet(p.Channels)) {
+		ch := p.Channels[chName]
+		for _, bName := range sets.List(sets.KeySet(ch.Bundles)) {
+			b := ch.Bundles[bName]
+			if b.SkipRange == "" {
+				continue
+			}
+			skipRange := ""
+			if mode == v1.SkipRangesRecompute {
+				var err error
+				if skipRange, err = recomputedSkipRange(ch, b); err != nil {
+					return err
+				}
+			}
+			if skipRange != b.SkipRange {
+				warnf(Warning{Code: WarningSkipRangeRewritten, Package: p.Name, Channel: ch.Name, Bundle: b.Name}.withMessage("rewriting skipRange of bundle %q in channel %q for package %q from %q to %q", b.Name, ch.Name, p.Name, b.SkipRange, skipRange))
+				b.SkipRange = skipRange
+			}
+		}
+	}
+	return nil
+}
+
+// recomputedSkipRange returns the skipRange of b narrowed to the other kept bundles of the channel that it covers, as
+// described by SkipRangesRecompute.
+func recomputedSkipRange(ch *model.Channel, b *model.Bundle) (string, error) {
+	original, err := blangsemver.ParseRange(b.SkipRange)
+	if err != nil {
+		return "", fmt.Errorf("invalid skipRange %q for bundle %q in channel %q: %v", b.SkipRange, b.Name, ch.Name, err)
+	}
+	var lowest, highest *blangsemver.Version
+	for _, other := range ch.Bundles {
+		if other == b || !original(other.Version) {
+			continue
+		}
+		if lowest == nil || other.Version.LT(*lowest) {
+			lowest = &other.Version
+		}
+		if highest == nil || other.Version.GT(*highest) {
+			highest = &other.Version
+		}
+	}
+	if lowest == nil {
+		return "", nil
+	}
+	narrowed := fmt.Sprintf(">=%s <=%s", lowest, highest)
+	narrowedRange := blangsemver.MustParseRange(narrowed)
+	for _, other := range ch.Bundles {
+		if other != b && narrowedRange(other.Version) && !original(other.Version) {
+			return b.SkipRange, nil
+		}
+	}
+	return narrowed, nil
+}
+
+// skipRangeOverlapMatcher extends a matcher to also match the bundles whose skipRange overlaps the bundles it
+// matches, i.e. covers the version of at least one of them, regardless of the bundle's own version. Such a bundle
+// can be upgraded to from a matched bundle without a replaces or skips edge.
+func skipRangeOverlapMatcher(ch *model.Channel, matcher bundleMatcher) (bundleMatcher, error) {
+	overlapping := map[string]struct{}{}
+	for _, b := range ch.Bundles {
+		if b.SkipRange == "" || matcher.matches(b) {
+			continue
+		}
+		skipRange, err := blangsemver.ParseRange(b.SkipRange)
+		if err != nil {
+			return bundleMatcher{}, fmt.Errorf("invalid skipRange %q for bundle %q in channel %q: %v", b.SkipRange, b.Name, ch.Name, err)
+		}
+		for _, other := range ch.Bundles {
+			if other != b && matcher.matches(other) && skipRange(other.Version) {
+				overlapping[b.Name] = struct{}{}
+				break
+			}
+		}
+	}
+	return bundleMatcher{
+		description: fmt.Sprintf("%s or a skipRange overlapping it", matcher.description),
+		matches: func(b *model.Bundle) bool {
+			if _, ok := overlapping[b.Name]; ok {
+				return true
+			}
+			return matcher.matches(b)
+		},
+		rule: extendedRule(matcher, keptByMatchSkipRange),
+	}, nil
+}
@@ -0,0 +1,200 @@
+package filter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/model"
+	"github.com/operator-framework/operator-registry/alpha/property"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+// skipRangeCatalog has a package foo with a stable channel 1.0.0 -> 1.1.0 -> 1.2.0 -> 2.0.0, in which 1.2.0 skips
+// >=1.0.0 <1.2.0 and 2.0.0 skips >=1.0.0 <2.0.0 by skipRange.
+func skipRangeCatalog() *declcfg.DeclarativeConfig {
+	bundle := func(version string) declcfg.Bundle {
+		return declcfg.Bundle{
+			Schema:     declcfg.SchemaBundle,
+			Name:       "foo.v" + version,
+			Package:    "foo",
+			Image:      "example.com/foo:v" + version,
+			Properties: []property.Property{property.MustBuildPackage("foo", version)},
+		}
+	}
+	return &declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{{Schema: declcfg.SchemaPackage, Name: "foo", DefaultChannel: "stable"}},
+		Channels: []declcfg.Channel{{Schema: declcfg.SchemaChannel, Package: "foo", Name: "stable", Entries: []declcfg.ChannelEntry{
+			{Name: "foo.v1.0.0"},
+			{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+			{Name: "foo.v1.2.0", Replaces: "foo.v1.1.0", SkipRange: ">=1.0.0 <1.2.0"},
+			{Name: "foo.v2.0.0", Replaces: "foo.v1.2.0", SkipRange: ">=1.0.0 <2.0.0"},
+		}}},
+		Bundles: []declcfg.Bundle{bundle("1.0.0"), bundle("1.1.0"), bundle("1.2.0"), bundle("2.0.0")},
+	}
+}
+
+// filterSkipRanges filters foo with the package configuration and returns the skipRange of each bundle kept in
+// stable that has one.
+func filterSkipRanges(t *testing.T, foo v1.Package) (map[string]string, *FilterResult, error) {
+	t.Helper()
+	m, err := declcfg.ConvertToModel(*skipRangeCatalog())
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := v1.FilterConfiguration{
+		TypeMeta: metav1.TypeMeta{Kind: v1.Kind, APIVersion: v1.APIVersion},
+		Packages: []v1.Package{foo},
+	}
+	result, err := FilterModel(m, config)
+	if err != nil {
+		return nil, result, err
+	}
+	return bundleSkipRanges(m["foo"].Channels["stable"]), result, nil
+}
+
+func bundleSkipRanges(ch *model.Channel) map[string]string {
+	skipRanges := map[string]string{}
+	for name, b := range ch.Bundles {
+		if b.SkipRange != "" {
+			skipRanges[name] = b.SkipRange
+		}
+	}
+	return skipRanges
+}
+
+func TestSkipRangeOverride(t *testing.T) {
+	tests := []struct {
+		name         string
+		channel      v1.Channel
+		want         map[string]string
+		wantWarnings []WarningCode
+		wantErr      string
+	}{
+		{
+			name:         "auto spans the kept bundles",
+			channel:      v1.Channel{Name: "stable", MinVersion: "1.1.0", SkipRangeOverride: v1.SkipRangeOverrideAuto},
+			want:         map[string]string{"foo.v1.2.0": ">=1.0.0 <1.2.0", "foo.v2.0.0": ">=1.1.0 <2.0.0"},
+			wantWarnings: []WarningCode{WarningSkipRangeRewritten},
+		},
+		{
+			name:    "auto keeps a skipRange that already spans them",
+			channel: v1.Channel{Name: "stable", MinVersion: "1.0.0", SkipRangeOverride: v1.SkipRangeOverrideAuto},
+			want:    map[string]string{"foo.v1.2.0": ">=1.0.0 <1.2.0", "foo.v2.0.0": ">=1.0.0 <2.0.0"},
+		},
+		{
+			name:         "auto removes the skipRange of a lone head",
+			channel:      v1.Channel{Name: "stable", HeadOnly: true, SkipRangeOverride: v1.SkipRangeOverrideAuto},
+			want:         map[string]string{},
+			wantWarnings: []WarningCode{WarningSkipRangeRewritten},
+		},
+		{
+			name:         "explicit range",
+			channel:      v1.Channel{Name: "stable", HeadOnly: true, SkipRangeOverride: ">=1.5.0 <2.0.0"},
+			want:         map[string]string{"foo.v2.0.0": ">=1.5.0 <2.0.0"},
+			wantWarnings: []WarningCode{WarningSkipRangeRewritten},
+		},
+		{
+			name:    "invalid range",
+			channel: v1.Channel{Name: "stable", HeadOnly: true, SkipRangeOverride: "not a range"},
+			wantErr: `invalid skipRangeOverride "not a range" for channel "stable"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, result, err := filterSkipRanges(t, v1.Package{Name: "foo", Channels: []v1.Channel{tt.channel}})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected skipRanges %v, got %v", tt.want, got)
+			}
+			if got := warningCodes(result.Warnings); !reflect.DeepEqual(got, tt.wantWarnings) {
+				t.Errorf("expected warnings %v, got %v", tt.wantWarnings, got)
+			}
+		})
+	}
+}
+
+func TestRewriteSkipRanges(t *testing.T) {
+	tests := []struct {
+		name         string
+		foo          v1.Package
+		want         map[string]string
+		wantWarnings []WarningCode
+	}{
+		{
+			name:         "recompute narrows to the kept versions",
+			foo:          v1.Package{Name: "foo", SkipRanges: v1.SkipRangesRecompute, Channels: []v1.Channel{{Name: "stable", MinVersion: "1.1.0"}}},
+			want:         map[string]string{"foo.v1.2.0": ">=1.1.0 <=1.1.0", "foo.v2.0.0": ">=1.1.0 <=1.2.0"},
+			wantWarnings: []WarningCode{WarningSkipRangeRewritten, WarningSkipRangeRewritten},
+		},
+		{
+			name:         "recompute removes skipRanges that cover nothing kept",
+			foo:          v1.Package{Name: "foo", SkipRanges: v1.SkipRangesRecompute, Channels: []v1.Channel{{Name: "stable", MinVersion: "1.2.0"}}},
+			want:         map[string]string{"foo.v2.0.0": ">=1.2.0 <=1.2.0"},
+			wantWarnings: []WarningCode{WarningSkipRangeRewritten, WarningSkipRangeRewritten},
+		},
+		{
+			name:         "strip",
+			foo:          v1.Package{Name: "foo", SkipRanges: v1.SkipRangesStrip},
+			want:         map[string]string{},
+			wantWarnings: []WarningCode{WarningSkipRangeRewritten, WarningSkipRangeRewritten},
+		},
+		{
+			name: "skipRangeOverride applies afterwards",
+			foo: v1.Package{Name: "foo", SkipRanges: v1.SkipRangesRecompute, Channels: []v1.Channel{
+				{Name: "stable", MinVersion: "1.1.0", SkipRangeOverride: v1.SkipRangeOverrideAuto},
+			}},
+			want:         map[string]string{"foo.v1.2.0": ">=1.1.0 <=1.1.0", "foo.v2.0.0": ">=1.1.0 <2.0.0"},
+			wantWarnings: []WarningCode{WarningSkipRangeRewritten, WarningSkipRangeRewritten, WarningSkipRangeRewritten},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, result, err := filterSkipRanges(t, tt.foo)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected skipRanges %v, got %v", tt.want, got)
+			}
+			if got := warningCodes(result.Warnings); !reflect.DeepEqual(got, tt.wantWarnings) {
+				t.Errorf("expected warnings %v, got %v", tt.wantWarnings, got)
+			}
+		})
+	}
+}
+
+func TestMatchSkipRange(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel v1.Channel
+		want    []string
+	}{
+		{name: "not set", channel: v1.Channel{Name: "stable", VersionRange: "<1.1.0"}, want: []string{"1.0.0"}},
+		{name: "skipRanges overlapping the range", channel: v1.Channel{Name: "stable", VersionRange: "1.1.0", MatchSkipRange: true}, want: []string{"1.1.0", "1.2.0", "2.0.0"}},
+		{name: "no skipRange overlaps the range", channel: v1.Channel{Name: "stable", VersionRange: ">=2.0.0", MatchSkipRange: true}, want: []string{"2.0.0"}},
+		{name: "maxVersion", channel: v1.Channel{Name: "stable", MaxVersion: "1.1.0", MatchSkipRange: true}, want: []string{"1.0.0", "1.1.0", "1.2.0", "2.0.0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := filterChannel(t, skipRangeCatalog(), tt.channel)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected versions %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
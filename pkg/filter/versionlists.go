@@ -0,0 +1,112 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+
+	blangsemver "github.com/blang/semver/v4"
+	"github.com/operator-framework/operator-registry/alpha/model"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// versionListed reports whether the bundle's version is one of the versions. Versions that do not parse never match;
+// the configuration validation reports them.
+func versionListed(b *model.Bundle, versions []string) bool {
+	for _, version := range versions {
+		if v, err := blangsemver.ParseTolerant(version); err == nil && b.Version.EQ(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// includeVersionsMatcher extends the matcher to also match the bundles with the included versions, all of which
+// must be in the channel. Filtering with it keeps them, along with the bundles that connect them to the other kept
+// bundles.
+func includeVersionsMatcher(ch *model.Channel, versions []string, matcher bundleMatcher) (bundleMatcher, error) {
+	for _, version := range versions {
+		found := false
+		for _, b := range ch.Bundles {
+			if versionListed(b, []string{version}) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return bundleMatcher{}, fmt.Errorf("included version %s is not in channel %q (available versions: %s)", version, ch.Name, channelVersions(ch))
+		}
+	}
+	return bundleMatcher{
+		description: matcher.description,
+		matches: func(b *model.Bundle) bool {
+			return versionListed(b, versions) || matcher.matches(b)
+		},
+		rule: extendedRule(matcher, keptByIncludeVersions),
+	}, nil
+}
+
+// excludeVersions removes the bundles with the excluded versions from the channel, including any that filtering
+// kept for coherence. Bundles that are then no longer on the head's replaces chain or skipped by another bundle
+// would be stranded, so they are removed as well, with a warning. If the head itself is excluded, the highest version
+// bundle that nothing else upgrades from becomes the head.
+func excludeVersions(ch *model.Channel, versions []string, warnf logFunc) error {
+	head, err := ch.Head()
+	if err != nil {
+		return fmt.Errorf("error getting head of channel %q: %v", ch.Name, err)
+	}
+	var excluded []string
+	for _, name := range sets.List(sets.KeySet(ch.Bundles)) {
+		if versionListed(ch.Bundles[name], versions) {
+			excluded = append(excluded, name)
+			delete(ch.Bundles, name)
+		}
+	}
+	if len(excluded) == 0 {
+		return nil
+	}
+	if len(ch.Bundles) == 0 {
+		return fmt.Errorf("every bundle in channel %q for package %q has an excluded version", ch.Name, ch.Package.Name)
+	}
+	if _, ok := ch.Bundles[head.Name]; !ok {
+		if head = highestHead(ch); head == nil {
+			return fmt.Errorf("no channel head remains in channel %q after excluding bundles [%s]", ch.Name, strings.Join(excluded, ", "))
+		}
+	}
+	for {
+		connected := sets.New[string]()
+		for cur := head; cur != nil && !connected.Has(cur.Name); cur = ch.Bundles[cur.Replaces] {
+			connected.Insert(cur.Name)
+		}
+		for _, b := range ch.Bundles {
+			connected.Insert(b.Skips...)
+		}
+		var orphaned []string
+		for _, name := range sets.List(sets.KeySet(ch.Bundles)) {
+			if !connected.Has(name) {
+				orphaned = append(orphaned, name)
+				delete(ch.Bundles, name)
+			}
+		}
+		if len(orphaned) == 0 {
+			return nil
+		}
+		warnf(Warning{Code: WarningExcludedVersionOrphans, Package: ch.Package.Name, Channel: ch.Name}.withMessage("dropping bundles [%s] from channel %q for package %q: excluding bundles [%s] left them without an upgrade path to the channel head", strings.Join(orphaned, ", "), ch.Name, ch.Package.Name, strings.Join(excluded, ", ")))
+	}
+}
+
+// highestHead returns the highest version bundle of the channel that no other bundle replaces or skips, or nil if
+// there is none.
+func highestHead(ch *model.Channel) *model.Bundle {
+	incoming := sets.New[string]()
+	for _, b := range ch.Bundles {
+		incoming.Insert(b.Replaces)
+		incoming.Insert(b.Skips...)
+	}
+	var head *model.Bundle
+	for _, b := range ch.Bundles {
+		if !incoming.Has(b.Name) && (head == nil || b.Version.GT(head.Version)) {
+			head = b
+		}
+	}
+	return head
+}
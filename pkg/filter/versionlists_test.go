@@ -0,0 +1,131 @@
+package filter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+// versionListsCatalog has a package foo with a stable channel 1.0.0 -> 1.1.0 -> 1.2.0 -> 2.0.0, in which 2.0.0 also
+// skips 1.1.0.
+func versionListsCatalog() *declcfg.DeclarativeConfig {
+	bundle := func(version string) declcfg.Bundle {
+		return declcfg.Bundle{
+			Schema:     declcfg.SchemaBundle,
+			Name:       "foo.v" + version,
+			Package:    "foo",
+			Image:      "example.com/foo:v" + version,
+			Properties: []property.Property{property.MustBuildPackage("foo", version)},
+		}
+	}
+	return &declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{{Schema: declcfg.SchemaPackage, Name: "foo", DefaultChannel: "stable"}},
+		Channels: []declcfg.Channel{{Schema: declcfg.SchemaChannel, Package: "foo", Name: "stable", Entries: []declcfg.ChannelEntry{
+			{Name: "foo.v1.0.0"},
+			{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+			{Name: "foo.v1.2.0", Replaces: "foo.v1.1.0"},
+			{Name: "foo.v2.0.0", Replaces: "foo.v1.2.0", Skips: []string{"foo.v1.1.0"}},
+		}}},
+		Bundles: []declcfg.Bundle{bundle("1.0.0"), bundle("1.1.0"), bundle("1.2.0"), bundle("2.0.0")},
+	}
+}
+
+func TestIncludeVersions(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel v1.Channel
+		want    []string
+		wantErr string
+	}{
+		{name: "below the selection", channel: v1.Channel{Name: "stable", HeadOnly: true, IncludeVersions: []string{"1.1.0"}}, want: []string{"1.1.0", "1.2.0", "2.0.0"}},
+		{name: "tolerant version", channel: v1.Channel{Name: "stable", HeadOnly: true, IncludeVersions: []string{"v1.0"}}, want: []string{"1.0.0", "1.1.0", "1.2.0", "2.0.0"}},
+		{name: "already selected", channel: v1.Channel{Name: "stable", MinVersion: "1.2.0", IncludeVersions: []string{"2.0.0"}}, want: []string{"1.2.0", "2.0.0"}},
+		{
+			name:    "not in the channel",
+			channel: v1.Channel{Name: "stable", HeadOnly: true, IncludeVersions: []string{"3.0.0"}},
+			wantErr: `included version 3.0.0 is not in channel "stable"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := filterChannel(t, versionListsCatalog(), tt.channel)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected versions %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestExcludeVersions(t *testing.T) {
+	tests := []struct {
+		name         string
+		channel      v1.Channel
+		want         []string
+		wantWarnings []WarningCode
+		wantErr      string
+	}{
+		{
+			name:    "tail",
+			channel: v1.Channel{Name: "stable", ExcludeVersions: []string{"1.0.0"}},
+			want:    []string{"1.1.0", "1.2.0", "2.0.0"},
+		},
+		{
+			name:         "bundles left without an upgrade path dropped",
+			channel:      v1.Channel{Name: "stable", ExcludeVersions: []string{"1.2.0"}},
+			want:         []string{"1.1.0", "2.0.0"},
+			wantWarnings: []WarningCode{WarningExcludedVersionOrphans},
+		},
+		{
+			name:    "head",
+			channel: v1.Channel{Name: "stable", ExcludeVersions: []string{"v2"}},
+			want:    []string{"1.0.0", "1.1.0", "1.2.0"},
+		},
+		{
+			name:    "bundles kept for coherence",
+			channel: v1.Channel{Name: "stable", VersionRange: "<1.1.0 || >=2.0.0", ExcludeVersions: []string{"1.1.0"}},
+			want:    []string{"1.2.0", "2.0.0"},
+			wantWarnings: []WarningCode{
+				WarningOutOfRangeBundleIncluded, WarningOutOfRangeBundleIncluded, WarningExcludedVersionOrphans,
+			},
+		},
+		{
+			name:    "every bundle",
+			channel: v1.Channel{Name: "stable", HeadOnly: true, ExcludeVersions: []string{"2.0.0"}},
+			wantErr: `every bundle in channel "stable" for package "foo" has an excluded version`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, result, err := filterChannel(t, versionListsCatalog(), tt.channel)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected versions %v, got %v", tt.want, got)
+			}
+			if got := warningCodes(result.Warnings); !reflect.DeepEqual(got, tt.wantWarnings) {
+				t.Errorf("expected warnings %v, got %v", tt.wantWarnings, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package filter
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// WarningCode identifies the kind of a Warning. Codes are stable, so callers can match on them.
+type WarningCode string
+
+const (
+	WarningPackageNotFound               WarningCode = "package-not-found"
+	WarningChannelNotFound               WarningCode = "channel-not-found"
+	WarningChannelPatternNotMatched      WarningCode = "channel-pattern-not-matched"
+	WarningDefaultChannelOverrideMissing WarningCode = "default-channel-override-missing"
+	WarningOutOfRangeBundleIncluded      WarningCode = "out-of-range-bundle-included"
+	WarningMinKubeVersionMissing         WarningCode = "min-kube-version-missing"
+	WarningRangeOutsideChannel           WarningCode = "range-outside-channel"
+	WarningHeadKeptOutsideSelection      WarningCode = "head-kept-outside-selection"
+	WarningChannelsDroppedNoMatches      WarningCode = "channels-dropped-no-matches"
+	WarningReferencedChannelKept         WarningCode = "referenced-channel-kept"
+	WarningChannelsExcludedByDefault     WarningCode = "channels-excluded-by-default"
+	WarningChannelDeniedByPolicy         WarningCode = "channel-denied-by-policy"
+	WarningChannelDroppedNotInInventory  WarningCode = "channel-dropped-not-in-inventory"
+	WarningInventoryEntryNotFound        WarningCode = "inventory-entry-not-found"
+	WarningDeprecatedExcluded            WarningCode = "deprecated-excluded"
+	WarningRollbackBundleIncluded        WarningCode = "rollback-bundle-included"
+	WarningBundleTrimmed                 WarningCode = "bundle-trimmed"
+	WarningSkipRangeRewritten            WarningCode = "skiprange-rewritten"
+	WarningEmptyPruned                   WarningCode = "empty-pruned"
+	WarningRecommendedOutsideSelection   WarningCode = "recommended-outside-selection"
+	WarningExcludedVersionOrphans        WarningCode = "excluded-version-orphans"
+	WarningDefaultChannelAutoSelected    WarningCode = "default-channel-auto-selected"
+	WarningDanglingEdgesDropped          WarningCode = "dangling-edges-dropped"
+
+	// The codes of the warnings that the command line reports about the catalogs it reads and writes, rather than
+	// the filter.
+	WarningVersionNormalized     WarningCode = "version-normalized"
+	WarningBundleVersionUnknown  WarningCode = "bundle-version-unknown"
+	WarningCSVMetadataKept       WarningCode = "csv-metadata-kept"
+	WarningOLMFeatureUnsupported WarningCode = "olm-feature-unsupported"
+)
+
+// The codes of the warnings that indicate a problem with the catalog data or with how the configuration matches it.
+var dataWarnings = sets.New(
+	WarningPackageNotFound,
+	WarningChannelNotFound,
+	WarningChannelPatternNotMatched,
+	WarningDefaultChannelOverrideMissing,
+	WarningOutOfRangeBundleIncluded,
+	WarningMinKubeVersionMissing,
+	WarningVersionNormalized,
+	WarningBundleVersionUnknown,
+)
+
+// IsDataWarning reports whether a warning code indicates a problem with the catalog data or with how the
+// configuration matches it, rather than describing a choice made while filtering.
+func IsDataWarning(code WarningCode) bool {
+	return dataWarnings.Has(code)
+}
+
+// Warning describes something notable that happened while applying a configuration. Package, Channel, and Bundle
+// name the objects it is about, when it is about a single one of each.
+type Warning struct {
+	Code    WarningCode `json:"code"`
+	Package string      `json:"package,omitempty"`
+	Channel string      `json:"channel,omitempty"`
+	Bundle  string      `json:"bundle,omitempty"`
+	Message string      `json:"message"`
+}
+
+func (w Warning) withMessage(format string, args ...interface{}) Warning {
+	w.Message = fmt.Sprintf(format, args...)
+	return w
+}
+
+// The message formats of the data warnings.
+const (
+	warnPackageNotFound               = "package %q not found in catalog"
+	warnChannelNotFound               = "channel %q not found in package %q (available channels: %s)"
+	warnChannelPatternNotMatched      = "channel pattern %q matches no channels in package %q (available channels: %s)"
+	warnDefaultChannelOverrideMissing = "specified default channel override %q does not exist, keeping original default channel from catalog (available channels: %s)"
+	warnOutOfRangeBundleIncluded      = "including bundle %q with version %q in channel %q for package %q: it falls outside the specified %s but is required to ensure inclusion of all matching bundles"
+	warnMinKubeVersionMissing         = "treating bundle %q in package %q as compatible with every Kubernetes version: its CSV does not declare a valid minKubeVersion"
+)
@@ -0,0 +1,87 @@
+package filter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+func TestWarnings(t *testing.T) {
+	m, err := declcfg.ConvertToModel(*filterCatalog())
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := v1.FilterConfiguration{
+		TypeMeta: metav1.TypeMeta{Kind: v1.Kind, APIVersion: v1.APIVersion},
+		Packages: []v1.Package{
+			{Name: "foo", Channels: []v1.Channel{{Name: "stable", VersionRange: "<1.2.0", KeepHeadAlways: true}, {Name: "beta"}}},
+			{Name: "baz"},
+		},
+	}
+	var reported []Warning
+	result, err := FilterModel(m, config, WithWarnings(func(w Warning) { reported = append(reported, w) }))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []Warning{
+		{Code: WarningChannelNotFound, Package: "foo", Channel: "beta", Message: `channel "beta" not found in package "foo" (available channels: [candidate, stable])`},
+		{Code: WarningPackageNotFound, Package: "baz", Message: `package "baz" not found in catalog`},
+	}
+	var got []Warning
+	for _, w := range result.Warnings {
+		if w.Code == WarningPackageNotFound || w.Code == WarningChannelNotFound {
+			got = append(got, w)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected warnings %v, got %v", want, got)
+	}
+	if !reflect.DeepEqual(reported, result.Warnings) {
+		t.Errorf("expected the reported warnings %v to match the result %v", reported, result.Warnings)
+	}
+}
+
+func TestIsDataWarning(t *testing.T) {
+	tests := []struct {
+		code WarningCode
+		want bool
+	}{
+		{code: WarningPackageNotFound, want: true},
+		{code: WarningChannelNotFound, want: true},
+		{code: WarningOutOfRangeBundleIncluded, want: true},
+		{code: WarningVersionNormalized, want: true},
+		{code: WarningBundleVersionUnknown, want: true},
+		{code: WarningHeadKeptOutsideSelection, want: false},
+		{code: WarningBundleTrimmed, want: false},
+		{code: WarningCode("unknown"), want: false},
+	}
+	for _, tt := range tests {
+		if got := IsDataWarning(tt.code); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.code, tt.want, got)
+		}
+	}
+}
+
+func TestDataWarnings(t *testing.T) {
+	result := FilterResult{Warnings: []Warning{
+		{Code: WarningHeadKeptOutsideSelection, Package: "foo", Channel: "stable"},
+		{Code: WarningChannelNotFound, Package: "foo", Channel: "beta"},
+		{Code: WarningBundleTrimmed, Package: "foo", Channel: "stable", Bundle: "foo.v1.0.0"},
+		{Code: WarningPackageNotFound, Package: "baz"},
+	}}
+	want := []Warning{
+		{Code: WarningChannelNotFound, Package: "foo", Channel: "beta"},
+		{Code: WarningPackageNotFound, Package: "baz"},
+	}
+	if got := result.DataWarnings(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected data warnings %v, got %v", want, got)
+	}
+	if got := (FilterResult{Warnings: result.Warnings[2:3]}).DataWarnings(); got != nil {
+		t.Errorf("expected no data warnings, got %v", got)
+	}
+}
@@ -0,0 +1,64 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	blangsemver "github.com/blang/semver/v4"
+	"github.com/operator-framework/operator-registry/alpha/model"
+)
+
+const (
+	withinOfHeadMajor = "major"
+	withinOfHeadMinor = "minor"
+	withinOfHeadPatch = "patch"
+)
+
+// withinOfHeadMatcher matches the bundles whose version is within a distance of the channel head's version. The
+// distance is written as <count>.<unit>:
+//   - N.major keeps versions whose major version is at most N below the head's.
+//   - N.minor keeps versions with the head's major version whose minor version is at most N below the head's.
+//   - N.patch keeps versions with the head's major and minor version whose patch version is at most N below the
+//     head's.
+//
+// Versions are compared to the head's version including prereleases, so a prerelease of the lowest allowed
+// release falls outside the distance.
+func withinOfHeadMatcher(ch *model.Channel, within string) (bundleMatcher, error) {
+	count, unit, ok := strings.Cut(within, ".")
+	n, err := strconv.ParseUint(count, 10, 64)
+	if !ok || err != nil {
+		return bundleMatcher{}, fmt.Errorf("invalid distance %q: must be <count>.<unit>, e.g. 2.minor", within)
+	}
+	head, err := ch.Head()
+	if err != nil {
+		return bundleMatcher{}, fmt.Errorf("error getting head of channel %q: %v", ch.Name, err)
+	}
+
+	lower := blangsemver.Version{Major: head.Version.Major, Minor: head.Version.Minor, Patch: head.Version.Patch}
+	switch unit {
+	case withinOfHeadMajor:
+		lower = blangsemver.Version{Major: subtractClamped(head.Version.Major, n)}
+	case withinOfHeadMinor:
+		lower = blangsemver.Version{Major: head.Version.Major, Minor: subtractClamped(head.Version.Minor, n)}
+	case withinOfHeadPatch:
+		lower.Patch = subtractClamped(head.Version.Patch, n)
+	default:
+		return bundleMatcher{}, fmt.Errorf("invalid distance %q: unit must be one of %q, %q, or %q", within, withinOfHeadMajor, withinOfHeadMinor, withinOfHeadPatch)
+	}
+
+	return bundleMatcher{
+		description: fmt.Sprintf("distance %q of head %q (>=%s)", within, head.Name, lower),
+		matches: func(b *model.Bundle) bool {
+			return b.Version.GTE(lower)
+		},
+		rule: fixedRule(keptByWithinOfHead),
+	}, nil
+}
+
+func subtractClamped(v, n uint64) uint64 {
+	if n > v {
+		return 0
+	}
+	return v - n
+}
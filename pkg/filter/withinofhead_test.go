@@ -0,0 +1,68 @@
+package filter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+func TestWithinOfHead(t *testing.T) {
+	// stable is 0.9.0 -> 1.8.0 -> 2.0.0-rc.1 -> 2.0.0 -> 2.1.0 -> 2.1.1 -> 2.1.2.
+	versions := []string{"0.9.0", "1.8.0", "2.0.0-rc.1", "2.0.0", "2.1.0", "2.1.1", "2.1.2"}
+	fbc := &declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{{Schema: declcfg.SchemaPackage, Name: "foo", DefaultChannel: "stable"}},
+		Channels: []declcfg.Channel{{Schema: declcfg.SchemaChannel, Package: "foo", Name: "stable"}},
+	}
+	for i, version := range versions {
+		entry := declcfg.ChannelEntry{Name: "foo.v" + version}
+		if i > 0 {
+			entry.Replaces = "foo.v" + versions[i-1]
+		}
+		fbc.Channels[0].Entries = append(fbc.Channels[0].Entries, entry)
+		fbc.Bundles = append(fbc.Bundles, declcfg.Bundle{
+			Schema:     declcfg.SchemaBundle,
+			Name:       "foo.v" + version,
+			Package:    "foo",
+			Image:      "example.com/foo:v" + version,
+			Properties: []property.Property{property.MustBuildPackage("foo", version)},
+		})
+	}
+
+	tests := []struct {
+		name    string
+		within  string
+		want    []string
+		wantErr string
+	}{
+		{name: "same major", within: "0.major", want: []string{"2.0.0", "2.1.0", "2.1.1", "2.1.2"}},
+		{name: "majors below", within: "1.major", want: []string{"1.8.0", "2.0.0-rc.1", "2.0.0", "2.1.0", "2.1.1", "2.1.2"}},
+		{name: "more majors than the head has", within: "5.major", want: versions},
+		{name: "same minor", within: "0.minor", want: []string{"2.1.0", "2.1.1", "2.1.2"}},
+		{name: "minors below", within: "1.minor", want: []string{"2.0.0", "2.1.0", "2.1.1", "2.1.2"}},
+		{name: "patches below", within: "1.patch", want: []string{"2.1.1", "2.1.2"}},
+		{name: "no unit", within: "2", wantErr: `invalid distance "2": must be <count>.<unit>`},
+		{name: "unknown unit", within: "2.build", wantErr: `invalid distance "2.build": unit must be one of "major", "minor", or "patch"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := filterChannel(t, fbc, v1.Channel{Name: "stable", WithinOfHead: tt.within})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected versions %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
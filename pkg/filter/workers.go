@@ -0,0 +1,88 @@
+package filter
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/operator-framework/operator-registry/alpha/model"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+// packageJob is the work of filtering one package of the model with its configuration entry. Warnings are buffered
+// so that they can be reported in configuration order whatever order the jobs finish in.
+type packageJob struct {
+	config v1.Package
+
+	warnings []Warning
+	outcome  packageOutcome
+	err      error
+}
+
+// filterPackagesConcurrently applies each package's configuration to the model with up to workers packages filtered
+// at once. Packages do not share any state in the model, so only the package map itself is guarded: it is read before
+// the workers start, and packages are removed from it after they finish. Warnings, removals, and errors are then
+// applied in configuration order, exactly as if the packages had been filtered one after the other. A package may
+// only be configured once, since entries for the same package could not be filtered independently. The outcomes of
+// the packages that were filtered are returned by package name.
+func filterPackagesConcurrently(m model.Model, packages []v1.Package, opts filterOptions, result *FilterResult, warnf logFunc) (map[string]packageOutcome, error) {
+	jobs := make([]*packageJob, 0, len(packages))
+	names := map[string]struct{}{}
+	for _, p := range packages {
+		if _, ok := names[p.Name]; ok {
+			return nil, fmt.Errorf("package %q is configured more than once", p.Name)
+		}
+		names[p.Name] = struct{}{}
+		jobs = append(jobs, &packageJob{config: p})
+	}
+
+	workers := opts.workers
+	if workers < 1 {
+		workers = 1
+	}
+	pending := make(chan *packageJob)
+	var wg sync.WaitGroup
+	for i := 0; i < workers && i < len(jobs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range pending {
+				job.run(m[job.config.Name], opts)
+			}
+		}()
+	}
+	for _, job := range jobs {
+		pending <- job
+	}
+	close(pending)
+	wg.Wait()
+
+	outcomes := map[string]packageOutcome{}
+	for _, job := range jobs {
+		for _, w := range job.warnings {
+			warnf(w)
+		}
+		if job.err != nil {
+			if !opts.bestEffort {
+				return nil, job.err
+			}
+			result.Errors = append(result.Errors, PackageError{Package: job.config.Name, Err: job.err})
+		}
+		if job.outcome.removed || job.err != nil {
+			delete(m, job.config.Name)
+			continue
+		}
+		outcomes[job.config.Name] = job.outcome
+	}
+	return outcomes, nil
+}
+
+// run filters the package with the job's configuration entry. A nil package is one that is not in the model.
+func (job *packageJob) run(pkgModel *model.Package, opts filterOptions) {
+	warnf := func(w Warning) { job.warnings = append(job.warnings, w) }
+	if pkgModel == nil {
+		warnf(Warning{Code: WarningPackageNotFound, Package: job.config.Name}.withMessage(warnPackageNotFound, job.config.Name))
+		return
+	}
+	job.outcome, job.err = filterPackage(pkgModel, job.config, opts, warnf)
+}
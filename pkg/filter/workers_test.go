@@ -0,0 +1,204 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+// syntheticCatalog returns a catalog of packages named pkg-0, pkg-1, ..., each with a single stable channel of
+// bundles 1.0.0 through 1.<bundles-1>.0 in a replaces chain.
+func syntheticCatalog(packages, bundles int) *declcfg.DeclarativeConfig {
+	fbc := &declcfg.DeclarativeConfig{}
+	for p := 0; p < packages; p++ {
+		pkg := fmt.Sprintf("pkg-%d", p)
+		fbc.Packages = append(fbc.Packages, declcfg.Package{Schema: declcfg.SchemaPackage, Name: pkg, DefaultChannel: "stable"})
+		ch := declcfg.Channel{Schema: declcfg.SchemaChannel, Package: pkg, Name: "stable"}
+		for b := 0; b < bundles; b++ {
+			name := fmt.Sprintf("%s.v1.%d.0", pkg, b)
+			entry := declcfg.ChannelEntry{Name: name}
+			if b > 0 {
+				entry.Replaces = fmt.Sprintf("%s.v1.%d.0", pkg, b-1)
+			}
+			ch.Entries = append(ch.Entries, entry)
+			fbc.Bundles = append(fbc.Bundles, declcfg.Bundle{
+				Schema:     declcfg.SchemaBundle,
+				Name:       name,
+				Package:    pkg,
+				Image:      "example.com/" + name,
+				Properties: []property.Property{property.MustBuildPackage(pkg, fmt.Sprintf("1.%d.0", b))},
+			})
+		}
+		fbc.Channels = append(fbc.Channels, ch)
+	}
+	return fbc
+}
+
+func syntheticConfiguration(names ...string) v1.FilterConfiguration {
+	config := v1.FilterConfiguration{TypeMeta: metav1.TypeMeta{Kind: v1.Kind, APIVersion: v1.APIVersion}}
+	for _, name := range names {
+		config.Packages = append(config.Packages, v1.Package{
+			Name:     name,
+			Channels: []v1.Channel{{Name: "stable", VersionRange: ">=1.5.0"}},
+		})
+	}
+	return config
+}
+
+func TestFilterPackagesConcurrently(t *testing.T) {
+	fbc := syntheticCatalog(4, 10)
+	tests := []struct {
+		name         string
+		packages     []string
+		wantWarnings []string
+		wantErr      string
+	}{
+		{
+			name:     "every package",
+			packages: []string{"pkg-0", "pkg-1", "pkg-2", "pkg-3"},
+		},
+		{
+			name:         "warnings in configuration order",
+			packages:     []string{"pkg-3", "missing-b", "pkg-1", "missing-a"},
+			wantWarnings: []string{"missing-b", "missing-a"},
+		},
+		{
+			name:     "duplicate entries",
+			packages: []string{"pkg-0", "pkg-1", "pkg-0"},
+			wantErr:  `package "pkg-0" is configured more than once`,
+		},
+	}
+	for _, tt := range tests {
+		for _, workers := range []int{1, 3} {
+			t.Run(fmt.Sprintf("%s/workers=%d", tt.name, workers), func(t *testing.T) {
+				m, err := declcfg.ConvertToModel(*fbc)
+				if err != nil {
+					t.Fatal(err)
+				}
+				result, err := FilterModel(m, syntheticConfiguration(tt.packages...), WithWorkers(workers))
+				if tt.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				var warned []string
+				for _, w := range result.Warnings {
+					if w.Code == WarningPackageNotFound {
+						warned = append(warned, w.Package)
+					}
+				}
+				if fmt.Sprint(warned) != fmt.Sprint(tt.wantWarnings) {
+					t.Errorf("expected package-not-found warnings for %v, got %v", tt.wantWarnings, warned)
+				}
+				for _, name := range tt.packages {
+					if strings.HasPrefix(name, "missing") {
+						continue
+					}
+					pkg, ok := m[name]
+					if !ok {
+						t.Fatalf("expected package %q to be kept", name)
+					}
+					if got := len(pkg.Channels["stable"].Bundles); got != 5 {
+						t.Errorf("package %q: expected 5 bundles, got %d", name, got)
+					}
+				}
+				if len(m) != len(tt.packages)-len(tt.wantWarnings) {
+					t.Errorf("expected %d packages, got %d", len(tt.packages)-len(tt.wantWarnings), len(m))
+				}
+			})
+		}
+	}
+}
+
+func TestBestEffort(t *testing.T) {
+	tests := []struct {
+		name string
+		// unordered makes bundle 1.6.0 of pkg-1, which always survives, skip 1.8.0, while pkg-0 always fails with
+		// an invalid versionRange.
+		unordered  bool
+		wantErrors []string
+		wantErr    string
+	}{
+		{
+			name:       "failed package left out",
+			wantErrors: []string{`could not filter bundles in package "pkg-0": invalid version range "not a range"`},
+		},
+		{
+			name:      "survivors still validated",
+			unordered: true,
+			wantErr:   `package "pkg-1", channel "stable": bundle "pkg-1.v1.6.0" with version "1.6.0" skips bundle "pkg-1.v1.8.0"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fbc := syntheticCatalog(2, 10)
+			if tt.unordered {
+				fbc.Channels[1].Entries[6].Skips = []string{"pkg-1.v1.8.0"}
+			}
+			config := syntheticConfiguration("pkg-0", "pkg-1")
+			config.Packages[0].Channels[0].VersionRange = "not a range"
+			result, err := Filter(fbc, config, WithBestEffort(true), WithEnforceMonotonicChain(true))
+			if len(result.Errors) != 1 || result.Errors[0].Package != "pkg-0" {
+				t.Errorf("expected an error for package pkg-0 only, got %v", result.Errors)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for i, want := range tt.wantErrors {
+				if !strings.Contains(result.Errors[i].Error(), want) {
+					t.Errorf("expected error containing %q, got %q", want, result.Errors[i].Error())
+				}
+			}
+			if len(fbc.Packages) != 1 || fbc.Packages[0].Name != "pkg-1" {
+				t.Fatalf("expected only package pkg-1 to be kept, got %+v", fbc.Packages)
+			}
+			if len(fbc.Bundles) != 5 {
+				t.Errorf("expected 5 bundles of pkg-1, got %d", len(fbc.Bundles))
+			}
+		})
+	}
+}
+
+// BenchmarkFilter compares filtering a large catalog with one worker and with several; the speedup is bounded by
+// GOMAXPROCS.
+func BenchmarkFilter(b *testing.B) {
+	const packages = 500
+	fbc := syntheticCatalog(packages, 50)
+	names := make([]string, 0, packages)
+	for p := 0; p < packages; p++ {
+		names = append(names, fmt.Sprintf("pkg-%d", p))
+	}
+	config := syntheticConfiguration(names...)
+
+	for _, workers := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				m, err := declcfg.ConvertToModel(*fbc)
+				if err != nil {
+					b.Fatal(err)
+				}
+				b.StartTimer()
+				if _, err := FilterModel(m, config, WithWorkers(workers)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+
+	"fbc-filter/pkg/filter"
+)
+
+const (
+	propertyStyleKeepBoth     = "keep-both"
+	propertyStyleBundleObject = "bundle-object"
+	propertyStyleCSVMetadata  = "csv-metadata"
+)
+
+func validatePropertyStyle(style string) error {
+	switch style {
+	case propertyStyleKeepBoth, propertyStyleBundleObject, propertyStyleCSVMetadata:
+		return nil
+	}
+	return fmt.Errorf("invalid property style %q: must be one of %q, %q, or %q", style, propertyStyleKeepBoth, propertyStyleBundleObject, propertyStyleCSVMetadata)
+}
+
+// normalizePropertyStyle rewrites the properties of each bundle so that the CSV is represented using only the
+// requested property type.
+//   - keep-both leaves the bundle properties untouched.
+//   - csv-metadata replaces olm.bundle.object properties with a single olm.csv.metadata property (building it from
+//     the bundle's CSV if necessary), which is the same conversion performed by --migrate.
+//   - bundle-object removes olm.csv.metadata properties from bundles that carry olm.bundle.object properties. Bundles
+//     that only have olm.csv.metadata are left as-is because the full bundle objects cannot be reconstructed from it.
+func normalizePropertyStyle(fbc *declcfg.DeclarativeConfig, style string, warnf func(filter.Warning)) error {
+	switch style {
+	case propertyStyleKeepBoth:
+		return nil
+	case propertyStyleCSVMetadata:
+		for i := range fbc.Bundles {
+			if err := convertToCSVMetadata(&fbc.Bundles[i]); err != nil {
+				return fmt.Errorf("could not convert bundle %q to %s properties: %v", fbc.Bundles[i].Name, property.TypeCSVMetadata, err)
+			}
+		}
+	case propertyStyleBundleObject:
+		for i := range fbc.Bundles {
+			b := &fbc.Bundles[i]
+			if !hasPropertyType(b.Properties, property.TypeBundleObject) {
+				if hasPropertyType(b.Properties, property.TypeCSVMetadata) {
+					warnf(filter.Warning{
+						Code:    filter.WarningCSVMetadataKept,
+						Package: b.Package,
+						Bundle:  b.Name,
+						Message: fmt.Sprintf("keeping %s property for bundle %q in package %q: it has no %s properties to convert to", property.TypeCSVMetadata, b.Name, b.Package, property.TypeBundleObject),
+					})
+				}
+				continue
+			}
+			b.Properties = removePropertyType(b.Properties, property.TypeCSVMetadata)
+		}
+	default:
+		return validatePropertyStyle(style)
+	}
+	return nil
+}
+
+func convertToCSVMetadata(b *declcfg.Bundle) error {
+	if !hasPropertyType(b.Properties, property.TypeBundleObject) {
+		return nil
+	}
+	if !hasPropertyType(b.Properties, property.TypeCSVMetadata) {
+		if b.CsvJSON == "" {
+			return fmt.Errorf("bundle has no CSV from which to build %s", property.TypeCSVMetadata)
+		}
+		var csv v1alpha1.ClusterServiceVersion
+		if err := json.Unmarshal([]byte(b.CsvJSON), &csv); err != nil {
+			return err
+		}
+		b.Properties = append(b.Properties, property.MustBuildCSVMetadata(csv))
+	}
+	b.Properties = removePropertyType(b.Properties, property.TypeBundleObject)
+	return nil
+}
+
+func hasPropertyType(props []property.Property, typ string) bool {
+	for _, p := range props {
+		if p.Type == typ {
+			return true
+		}
+	}
+	return false
+}
+
+func removePropertyType(props []property.Property, typ string) []property.Property {
+	out := props[:0]
+	for _, p := range props {
+		if p.Type != typ {
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+
+	"fbc-filter/pkg/filter"
+)
+
+func TestNormalizePropertyStyle(t *testing.T) {
+	const csvJSON = `{"kind":"ClusterServiceVersion","spec":{"displayName":"Foo"}}`
+	bundle := func(name, csv string, props ...property.Property) declcfg.Bundle {
+		return declcfg.Bundle{
+			Schema:     declcfg.SchemaBundle,
+			Name:       name,
+			Package:    "foo",
+			CsvJSON:    csv,
+			Properties: append([]property.Property{property.MustBuildPackage("foo", "1.0.0")}, props...),
+		}
+	}
+	object := property.MustBuildBundleObject([]byte(csvJSON))
+	metadata := property.MustBuildCSVMetadata(v1alpha1.ClusterServiceVersion{Spec: v1alpha1.ClusterServiceVersionSpec{DisplayName: "Foo"}})
+	// catalog has a bundle with both property types, one with only bundle objects, and one with only CSV metadata.
+	catalog := func() *declcfg.DeclarativeConfig {
+		return &declcfg.DeclarativeConfig{Bundles: []declcfg.Bundle{
+			bundle("foo.both", csvJSON, object, metadata),
+			bundle("foo.object", csvJSON, object),
+			bundle("foo.metadata", "", metadata),
+		}}
+	}
+	tests := []struct {
+		name   string
+		style  string
+		modify func(*declcfg.DeclarativeConfig)
+		// want is the property types of each bundle after the package property.
+		want         map[string][]string
+		wantWarnings []filter.WarningCode
+		wantErr      string
+	}{
+		{
+			name:  "keep-both",
+			style: propertyStyleKeepBoth,
+			want: map[string][]string{
+				"foo.both":     {property.TypeBundleObject, property.TypeCSVMetadata},
+				"foo.object":   {property.TypeBundleObject},
+				"foo.metadata": {property.TypeCSVMetadata},
+			},
+		},
+		{
+			name:  "csv-metadata",
+			style: propertyStyleCSVMetadata,
+			want: map[string][]string{
+				"foo.both":     {property.TypeCSVMetadata},
+				"foo.object":   {property.TypeCSVMetadata},
+				"foo.metadata": {property.TypeCSVMetadata},
+			},
+		},
+		{
+			name:  "bundle-object",
+			style: propertyStyleBundleObject,
+			want: map[string][]string{
+				"foo.both":     {property.TypeBundleObject},
+				"foo.object":   {property.TypeBundleObject},
+				"foo.metadata": {property.TypeCSVMetadata},
+			},
+			wantWarnings: []filter.WarningCode{filter.WarningCSVMetadataKept},
+		},
+		{
+			name:    "csv-metadata without a CSV",
+			style:   propertyStyleCSVMetadata,
+			modify:  func(fbc *declcfg.DeclarativeConfig) { fbc.Bundles[1].CsvJSON = "" },
+			wantErr: `could not convert bundle "foo.object" to olm.csv.metadata properties: bundle has no CSV`,
+		},
+		{name: "invalid style", style: "both", wantErr: `invalid property style "both"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fbc := catalog()
+			if tt.modify != nil {
+				tt.modify(fbc)
+			}
+			var warnings []filter.WarningCode
+			err := normalizePropertyStyle(fbc, tt.style, func(w filter.Warning) { warnings = append(warnings, w.Code) })
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := map[string][]string{}
+			for _, b := range fbc.Bundles {
+				got[b.Name] = []string{}
+				for _, p := range b.Properties[1:] {
+					got[b.Name] = append(got[b.Name], p.Type)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected property types %v, got %v", tt.want, got)
+			}
+			if !reflect.DeepEqual(warnings, tt.wantWarnings) {
+				t.Errorf("expected warnings %v, got %v", tt.wantWarnings, warnings)
+			}
+		})
+	}
+}
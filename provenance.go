@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/distribution/reference"
+	"github.com/operator-framework/operator-registry/alpha/model"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// provenanceEntry records where a kept bundle came from. Digest is only known when the bundle image is pinned by
+// digest; it is left empty for images referenced by tag.
+type provenanceEntry struct {
+	Package string `json:"package"`
+	Bundle  string `json:"bundle"`
+	Version string `json:"version"`
+	Image   string `json:"image"`
+	Digest  string `json:"digest,omitempty"`
+	Source  string `json:"source"`
+}
+
+// writeProvenance writes one entry per kept bundle, sorted by package and bundle name, mapping the bundle to its
+// image and the catalog it was filtered from, as returned by sourceOf for the bundle's package.
+func writeProvenance(m model.Model, sourceOf func(pkg string) string, path string) error {
+	entries := []provenanceEntry{}
+	for _, pkgName := range sets.List(sets.KeySet(m)) {
+		bundles := map[string]*model.Bundle{}
+		for _, ch := range m[pkgName].Channels {
+			for _, b := range ch.Bundles {
+				bundles[b.Name] = b
+			}
+		}
+		for _, bName := range sets.List(sets.KeySet(bundles)) {
+			b := bundles[bName]
+			entries = append(entries, provenanceEntry{
+				Package: pkgName,
+				Bundle:  b.Name,
+				Version: b.Version.String(),
+				Image:   b.Image,
+				Digest:  imageDigest(b.Image),
+				Source:  sourceOf(pkgName),
+			})
+		}
+	}
+	data, err := json.MarshalIndent(entries, "", "    ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func imageDigest(image string) string {
+	ref, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return ""
+	}
+	if canonical, ok := ref.(reference.Canonical); ok {
+		return canonical.Digest().String()
+	}
+	return ""
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestImageDigest(t *testing.T) {
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{image: "example.com/foo@" + approvedDigest, want: approvedDigest},
+		{image: "example.com/foo:v1.0.0@" + approvedDigest, want: approvedDigest},
+		{image: "example.com/foo:v1.0.0"},
+		{image: "foo"},
+		{image: "Example.com/FOO"},
+		{image: ""},
+	}
+	for _, tt := range tests {
+		if got := imageDigest(tt.image); got != tt.want {
+			t.Errorf("%q: expected digest %q, got %q", tt.image, tt.want, got)
+		}
+	}
+}
+
+func TestWriteProvenance(t *testing.T) {
+	m := generateModel(t)
+	m["bar"].Channels["alpha"].Bundles["bar.v0.1.0"].Image = "example.com/bar@" + approvedDigest
+
+	path := filepath.Join(t.TempDir(), "provenance.json")
+	sourceOf := func(pkg string) string { return "example.com/catalog-" + pkg + ":latest" }
+	if err := writeProvenance(m, sourceOf, path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []provenanceEntry
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []provenanceEntry{
+		{Package: "bar", Bundle: "bar.v0.1.0", Version: "0.1.0", Image: "example.com/bar@" + approvedDigest, Digest: approvedDigest, Source: "example.com/catalog-bar:latest"},
+		{Package: "foo", Bundle: "foo.v1.0.0", Version: "1.0.0", Image: "example.com/foo:v1.0.0", Source: "example.com/catalog-foo:latest"},
+		{Package: "foo", Bundle: "foo.v1.1.0", Version: "1.1.0", Image: "example.com/foo:v1.1.0", Source: "example.com/catalog-foo:latest"},
+		{Package: "foo", Bundle: "foo.v2.0.0", Version: "2.0.0", Image: "example.com/foo:v2.0.0", Source: "example.com/catalog-foo:latest"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected entries\n%+v\ngot\n%+v", want, got)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/containerd/containerd/errdefs"
+	"github.com/distribution/reference"
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/pkg/image/containerdregistry"
+)
+
+// catalogConfigsLabel is the label of a catalog image that tells OLM where in the image its FBC is located.
+const catalogConfigsLabel = "operators.operatorframework.io.index.configs.v1"
+
+// catalogConfigsDir is the directory of the pushed catalog image that contains the catalog.
+const catalogConfigsDir = "/configs"
+
+// pushCatalogImage builds a single-layer catalog image containing fbc as /configs/catalog.json and pushes it to
+// imageRef, authenticating with the docker config.json in configDir, or with the default docker config when
+// configDir is empty.
+func pushCatalogImage(ctx context.Context, fbc declcfg.DeclarativeConfig, imageRef string, configDir string, opts registryOptions) error {
+	named, err := reference.ParseDockerRef(imageRef)
+	if err != nil {
+		return fmt.Errorf("invalid image reference %q: %v", imageRef, err)
+	}
+
+	layer, diffID, err := catalogLayer(fbc)
+	if err != nil {
+		return fmt.Errorf("build catalog layer: %v", err)
+	}
+	config, err := json.Marshal(ocispec.Image{
+		Platform: ocispec.Platform{OS: "linux", Architecture: "amd64"},
+		Config: ocispec.ImageConfig{
+			Labels: map[string]string{catalogConfigsLabel: catalogConfigsDir},
+		},
+		RootFS: ocispec.RootFS{Type: "layers", DiffIDs: []digest.Digest{diffID}},
+	})
+	if err != nil {
+		return err
+	}
+	layerDesc := blobDescriptor(ocispec.MediaTypeImageLayerGzip, layer)
+	configDesc := blobDescriptor(ocispec.MediaTypeImageConfig, config)
+	manifest, err := json.Marshal(ocispec.Manifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    configDesc,
+		Layers:    []ocispec.Descriptor{layerDesc},
+	})
+	if err != nil {
+		return err
+	}
+
+	resolver, err := containerdregistry.NewResolver(opts.httpClient(), configDir, opts.plainHTTP, named.Name())
+	if err != nil {
+		return err
+	}
+	pusher, err := resolver.Pusher(ctx, named.String())
+	if err != nil {
+		return err
+	}
+	blobs := []struct {
+		desc ocispec.Descriptor
+		data []byte
+	}{
+		{layerDesc, layer},
+		{configDesc, config},
+		{blobDescriptor(ocispec.MediaTypeImageManifest, manifest), manifest},
+	}
+	for _, b := range blobs {
+		w, err := pusher.Push(ctx, b.desc)
+		if errdefs.IsAlreadyExists(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("push %s: %v", b.desc.MediaType, err)
+		}
+		if _, err := w.Write(b.data); err != nil {
+			w.Close()
+			return fmt.Errorf("push %s: %v", b.desc.MediaType, err)
+		}
+		if err := w.Commit(ctx, b.desc.Size, b.desc.Digest); err != nil && !errdefs.IsAlreadyExists(err) {
+			w.Close()
+			return fmt.Errorf("push %s: %v", b.desc.MediaType, err)
+		}
+		w.Close()
+	}
+	return nil
+}
+
+// catalogLayer returns a gzipped tar layer containing the catalog as JSON at /configs/catalog.json, along with the
+// digest of the uncompressed tar.
+func catalogLayer(fbc declcfg.DeclarativeConfig) ([]byte, digest.Digest, error) {
+	var catalog bytes.Buffer
+	if err := declcfg.WriteJSON(fbc, &catalog); err != nil {
+		return nil, "", err
+	}
+
+	var tarball bytes.Buffer
+	tw := tar.NewWriter(&tarball)
+	if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: catalogConfigsDir[1:] + "/", Mode: 0755}); err != nil {
+		return nil, "", err
+	}
+	if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: catalogConfigsDir[1:] + "/catalog.json", Mode: 0644, Size: int64(catalog.Len())}); err != nil {
+		return nil, "", err
+	}
+	if _, err := tw.Write(catalog.Bytes()); err != nil {
+		return nil, "", err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, "", err
+	}
+
+	var layer bytes.Buffer
+	gw := gzip.NewWriter(&layer)
+	if _, err := gw.Write(tarball.Bytes()); err != nil {
+		return nil, "", err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, "", err
+	}
+	return layer.Bytes(), digest.FromBytes(tarball.Bytes()), nil
+}
+
+func blobDescriptor(mediaType string, data []byte) ocispec.Descriptor {
+	return ocispec.Descriptor{MediaType: mediaType, Digest: digest.FromBytes(data), Size: int64(len(data))}
+}
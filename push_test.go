@@ -0,0 +1,66 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+)
+
+func TestCatalogLayer(t *testing.T) {
+	layer, diffID, err := catalogLayer(outputCatalog())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(layer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tarball, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := digest.FromBytes(tarball); got != diffID {
+		t.Errorf("expected the diff ID to be the digest %s of the uncompressed layer, got %s", got, diffID)
+	}
+
+	tr := tar.NewReader(bytes.NewReader(tarball))
+	var names []string
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, h.Name)
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		fbc, err := declcfg.LoadReader(tr)
+		if err != nil {
+			t.Fatalf("%s: %v", h.Name, err)
+		}
+		if len(fbc.Packages) != 2 || len(fbc.Bundles) != 2 {
+			t.Errorf("%s: expected 2 packages and 2 bundles, got %d and %d", h.Name, len(fbc.Packages), len(fbc.Bundles))
+		}
+	}
+	if want := []string{"configs/", "configs/catalog.json"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("expected entries %v, got %v", want, names)
+	}
+}
+
+func TestPushCatalogImageInvalidReference(t *testing.T) {
+	err := pushCatalogImage(context.Background(), outputCatalog(), "Example.com/Catalog:latest", "", registryOptions{})
+	if err == nil || !strings.Contains(err.Error(), `invalid image reference "Example.com/Catalog:latest"`) {
+		t.Fatalf("expected error containing %q, got %v", `invalid image reference "Example.com/Catalog:latest"`, err)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/operator-framework/operator-registry/alpha/action"
+)
+
+// refTypes are the names of the catalog reference types accepted by --allowed-ref-types, in the order they are
+// listed in help and errors.
+var refTypes = []struct {
+	name    string
+	refType action.RefType
+}{
+	{"dc-dir", action.RefDCDir},
+	{"dc-image", action.RefDCImage},
+	{"sqlite-file", action.RefSqliteFile},
+	{"sqlite-image", action.RefSqliteImage},
+	{"bundle-image", action.RefBundleImage},
+}
+
+// defaultAllowedRefTypes are the reference types allowed when --allowed-ref-types is not set.
+var defaultAllowedRefTypes = []string{"dc-dir", "dc-image", "sqlite-file", "sqlite-image"}
+
+// parseAllowedRefTypes returns the mask of the named reference types. At least one type must be named, since an
+// empty mask allows every type.
+func parseAllowedRefTypes(names []string) (action.RefType, error) {
+	if len(names) == 0 {
+		return 0, fmt.Errorf("--allowed-ref-types must name at least one reference type")
+	}
+	var mask action.RefType
+	for _, name := range names {
+		found := false
+		for _, t := range refTypes {
+			if strings.TrimSpace(name) == t.name {
+				mask |= t.refType
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("invalid --allowed-ref-types entry %q: must be one of %s", name, strings.Join(refTypeNames(), ", "))
+		}
+	}
+	return mask, nil
+}
+
+func refTypeNames() []string {
+	names := make([]string, 0, len(refTypes))
+	for _, t := range refTypes {
+		names = append(names, t.name)
+	}
+	return names
+}
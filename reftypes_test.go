@@ -0,0 +1,44 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/action"
+)
+
+func TestParseAllowedRefTypes(t *testing.T) {
+	tests := []struct {
+		name    string
+		names   []string
+		want    action.RefType
+		wantErr string
+	}{
+		{name: "one", names: []string{"dc-dir"}, want: action.RefDCDir},
+		{name: "several", names: []string{"dc-image", " sqlite-file "}, want: action.RefDCImage | action.RefSqliteFile},
+		{name: "defaults", names: defaultAllowedRefTypes, want: action.RefDCDir | action.RefDCImage | action.RefSqliteFile | action.RefSqliteImage},
+		{name: "none", wantErr: "--allowed-ref-types must name at least one reference type"},
+		{
+			name:    "unknown",
+			names:   []string{"dc-dir", "helm-chart"},
+			wantErr: `invalid --allowed-ref-types entry "helm-chart": must be one of dc-dir, dc-image, sqlite-file, sqlite-image, bundle-image`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseAllowedRefTypes(tt.names)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected mask %b, got %b", tt.want, got)
+			}
+		})
+	}
+}
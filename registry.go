@@ -0,0 +1,143 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/operator-framework/operator-registry/pkg/image/containerdregistry"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// registryOptions are the insecure connection settings for catalog image registries. They only apply to image
+// references; catalog directories and sqlite files are read locally.
+type registryOptions struct {
+	skipTLSVerify bool
+	plainHTTP     bool
+}
+
+// isSet reports whether any option differs from the default secure connection.
+func (o registryOptions) isSet() bool {
+	return o.skipTLSVerify || o.plainHTTP
+}
+
+// httpClient returns the client used to push catalog images.
+func (o registryOptions) httpClient() *http.Client {
+	client := &http.Client{Timeout: 5 * time.Minute}
+	if o.skipTLSVerify {
+		client.Transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}
+	}
+	return client
+}
+
+// newRegistry creates the registry used to pull catalog images, authenticating with the docker config.json in
+// configDir, or with the default docker config when configDir is empty. The caller must destroy the registry.
+func newRegistry(configDir string, opts registryOptions) (*containerdregistry.Registry, error) {
+	cacheDir, err := os.MkdirTemp("", "fbc-filter-registry-")
+	if err != nil {
+		return nil, fmt.Errorf("create tempdir: %v", err)
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return containerdregistry.NewRegistry(
+		containerdregistry.WithCacheDir(cacheDir),
+		containerdregistry.WithResolverConfigDir(configDir),
+		containerdregistry.WithLog(logrus.NewEntry(logger)),
+		containerdregistry.SkipTLSVerify(opts.skipTLSVerify),
+		containerdregistry.WithPlainHTTP(opts.plainHTTP),
+	)
+}
+
+// writeAuthConfig writes the credentials given by --registry-config or --pull-secret, of which at most one is set, to
+// a config.json in a new temporary directory, and returns the directory.
+func writeAuthConfig(pullSecret, registryConfig string) (string, error) {
+	if registryConfig == "" {
+		return writePullSecretConfig(pullSecret)
+	}
+	dockerConfig, err := readRegistryConfig(registryConfig)
+	if err != nil {
+		return "", err
+	}
+	return writeDockerConfig(dockerConfig)
+}
+
+// readRegistryConfig reads a docker config.json, as written by docker login or podman login with --authfile, and
+// returns it as JSON.
+func readRegistryConfig(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var dockerConfig map[string]json.RawMessage
+	if err := json.Unmarshal(data, &dockerConfig); err != nil {
+		return nil, fmt.Errorf("parse registry config %q: %v", path, err)
+	}
+	return data, nil
+}
+
+// writePullSecretConfig writes the docker config contained in a pull secret to a config.json in a new temporary
+// directory, and returns the directory. The pull secret is either a Kubernetes secret of type
+// kubernetes.io/dockerconfigjson, in YAML or JSON, or the bare .dockerconfigjson content.
+func writePullSecretConfig(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	dockerConfig, err := parsePullSecret(data)
+	if err != nil {
+		return "", fmt.Errorf("parse pull secret %q: %v", path, err)
+	}
+	return writeDockerConfig(dockerConfig)
+}
+
+func writeDockerConfig(dockerConfig []byte) (string, error) {
+	dir, err := os.MkdirTemp("", "fbc-filter-auth-")
+	if err != nil {
+		return "", fmt.Errorf("create tempdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), dockerConfig, 0600); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	return dir, nil
+}
+
+func parsePullSecret(data []byte) ([]byte, error) {
+	var secret corev1.Secret
+	if err := yaml.Unmarshal(data, &secret); err != nil {
+		return nil, err
+	}
+	if secret.Kind == "Secret" {
+		if secret.Type != corev1.SecretTypeDockerConfigJson {
+			return nil, fmt.Errorf("secret type must be %q, got %q", corev1.SecretTypeDockerConfigJson, secret.Type)
+		}
+		if v, ok := secret.StringData[corev1.DockerConfigJsonKey]; ok {
+			return []byte(v), nil
+		}
+		if v, ok := secret.Data[corev1.DockerConfigJsonKey]; ok {
+			return v, nil
+		}
+		return nil, fmt.Errorf("secret has no %q key", corev1.DockerConfigJsonKey)
+	}
+
+	var dockerConfig struct {
+		Auths map[string]json.RawMessage `json:"auths"`
+	}
+	if err := yaml.Unmarshal(data, &dockerConfig); err != nil {
+		return nil, err
+	}
+	if dockerConfig.Auths == nil {
+		return nil, fmt.Errorf("expected a Kubernetes Secret or a docker config with an \"auths\" key")
+	}
+	return yaml.YAMLToJSON(data)
+}
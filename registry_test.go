@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/base64"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const dockerConfigJSON = `{"auths":{"example.com":{"auth":"dXNlcjpwYXNz"}}}`
+
+func TestParsePullSecret(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{
+			name: "secret with data",
+			data: "apiVersion: v1\nkind: Secret\ntype: kubernetes.io/dockerconfigjson\ndata:\n  .dockerconfigjson: " + base64.StdEncoding.EncodeToString([]byte(dockerConfigJSON)) + "\n",
+		},
+		{
+			name: "secret with stringData",
+			data: `{"apiVersion": "v1", "kind": "Secret", "type": "kubernetes.io/dockerconfigjson", "stringData": {".dockerconfigjson": ` + strconv.Quote(dockerConfigJSON) + `}}`,
+		},
+		{name: "docker config", data: dockerConfigJSON},
+		{name: "docker config in YAML", data: "auths:\n  example.com:\n    auth: dXNlcjpwYXNz\n"},
+		{
+			name:    "wrong secret type",
+			data:    "apiVersion: v1\nkind: Secret\ntype: Opaque\n",
+			wantErr: `secret type must be "kubernetes.io/dockerconfigjson", got "Opaque"`,
+		},
+		{
+			name:    "secret without a docker config",
+			data:    "apiVersion: v1\nkind: Secret\ntype: kubernetes.io/dockerconfigjson\n",
+			wantErr: `secret has no ".dockerconfigjson" key`,
+		},
+		{name: "neither", data: "registry: example.com\n", wantErr: `expected a Kubernetes Secret or a docker config with an "auths" key`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePullSecret([]byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != dockerConfigJSON {
+				t.Errorf("expected docker config %s, got %s", dockerConfigJSON, got)
+			}
+		})
+	}
+}
+
+func TestWritePullSecretConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pull-secret.json")
+	if err := os.WriteFile(path, []byte(dockerConfigJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := writePullSecretConfig(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	config := filepath.Join(dir, "config.json")
+	info, err := os.Stat(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600, got %v", info.Mode().Perm())
+	}
+	data, err := os.ReadFile(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != dockerConfigJSON {
+		t.Errorf("expected docker config %s, got %s", dockerConfigJSON, data)
+	}
+
+	invalid := filepath.Join(t.TempDir(), "invalid.yaml")
+	if err := os.WriteFile(invalid, []byte("registry: example.com\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writePullSecretConfig(invalid); err == nil || !strings.Contains(err.Error(), "parse pull secret") {
+		t.Errorf("expected error containing %q, got %v", "parse pull secret", err)
+	}
+}
+
+func TestWriteAuthConfig(t *testing.T) {
+	tests := []struct {
+		name           string
+		pullSecret     string
+		registryConfig string
+		wantErr        string
+	}{
+		{name: "registry config", registryConfig: dockerConfigJSON},
+		{name: "pull secret", pullSecret: dockerConfigJSON},
+		{name: "invalid registry config", registryConfig: "auths: {}\n", wantErr: "parse registry config"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			var pullSecret, registryConfig string
+			if tt.pullSecret != "" {
+				pullSecret = filepath.Join(dir, "pull-secret.json")
+				if err := os.WriteFile(pullSecret, []byte(tt.pullSecret), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.registryConfig != "" {
+				registryConfig = filepath.Join(dir, "config.json")
+				if err := os.WriteFile(registryConfig, []byte(tt.registryConfig), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			authDir, err := writeAuthConfig(pullSecret, registryConfig)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer os.RemoveAll(authDir)
+			data, err := os.ReadFile(filepath.Join(authDir, "config.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != dockerConfigJSON {
+				t.Errorf("expected docker config %s, got %s", dockerConfigJSON, data)
+			}
+		})
+	}
+}
+
+func TestRegistryOptions(t *testing.T) {
+	tests := []struct {
+		name              string
+		opts              registryOptions
+		wantSet           bool
+		wantSkipTLSVerify bool
+	}{
+		{name: "secure"},
+		{name: "skip TLS verify", opts: registryOptions{skipTLSVerify: true}, wantSet: true, wantSkipTLSVerify: true},
+		{name: "plain HTTP", opts: registryOptions{plainHTTP: true}, wantSet: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.isSet(); got != tt.wantSet {
+				t.Errorf("expected isSet %t, got %t", tt.wantSet, got)
+			}
+			client := tt.opts.httpClient()
+			if client.Timeout == 0 {
+				t.Error("expected the client to time out")
+			}
+			var skipTLSVerify bool
+			if transport, ok := client.Transport.(*http.Transport); ok && transport.TLSClientConfig != nil {
+				skipTLSVerify = transport.TLSClientConfig.InsecureSkipVerify
+			}
+			if skipTLSVerify != tt.wantSkipTLSVerify {
+				t.Errorf("expected InsecureSkipVerify %t, got %t", tt.wantSkipTLSVerify, skipTLSVerify)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/operator-framework/operator-registry/alpha/model"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"fbc-filter/pkg/filter"
+)
+
+// reportVersion identifies the schema of the --report document. It changes whenever a field is removed or changes
+// meaning; fields may be added without changing it.
+const reportVersion = "fbc-filter.report/v1"
+
+// filterReport is the --report document, describing the catalog before and after filtering.
+type filterReport struct {
+	Version  string           `json:"version"`
+	Input    catalogCounts    `json:"input"`
+	Output   catalogCounts    `json:"output"`
+	Packages []packageReport  `json:"packages"`
+	Warnings []filter.Warning `json:"warnings"`
+	Errors   []packageFailure `json:"errors,omitempty"`
+}
+
+// catalogCounts counts the objects of a catalog. Bundles in several channels of a package are counted once.
+type catalogCounts struct {
+	Packages int `json:"packages"`
+	Channels int `json:"channels"`
+	Bundles  int `json:"bundles"`
+}
+
+// packageReport describes a package of the filtered catalog.
+type packageReport struct {
+	Name           string   `json:"name"`
+	DefaultChannel string   `json:"defaultChannel"`
+	Channels       []string `json:"channels"`
+	Bundles        int      `json:"bundles"`
+}
+
+// packageFailure is a package that could not be filtered with --best-effort.
+type packageFailure struct {
+	Package string `json:"package"`
+	Message string `json:"message"`
+}
+
+func countModel(m model.Model) catalogCounts {
+	var counts catalogCounts
+	for _, pkg := range m {
+		counts.Packages++
+		counts.Channels += len(pkg.Channels)
+		counts.Bundles += len(packageBundleNames(pkg))
+	}
+	return counts
+}
+
+func packageBundleNames(pkg *model.Package) sets.Set[string] {
+	names := sets.New[string]()
+	for _, ch := range pkg.Channels {
+		names.Insert(sets.List(sets.KeySet(ch.Bundles))...)
+	}
+	return names
+}
+
+func newFilterReport(input catalogCounts, m model.Model, result *filter.FilterResult) filterReport {
+	report := filterReport{
+		Version:  reportVersion,
+		Input:    input,
+		Output:   countModel(m),
+		Packages: []packageReport{},
+		Warnings: result.Warnings,
+	}
+	if report.Warnings == nil {
+		report.Warnings = []filter.Warning{}
+	}
+	for _, name := range sets.List(sets.KeySet(m)) {
+		pkg := m[name]
+		p := packageReport{
+			Name:     name,
+			Channels: sets.List(sets.KeySet(pkg.Channels)),
+			Bundles:  len(packageBundleNames(pkg)),
+		}
+		if pkg.DefaultChannel != nil {
+			p.DefaultChannel = pkg.DefaultChannel.Name
+		}
+		report.Packages = append(report.Packages, p)
+	}
+	for _, e := range result.Errors {
+		report.Errors = append(report.Errors, packageFailure{Package: e.Package, Message: e.Error()})
+	}
+	return report
+}
+
+func writeFilterReport(report filterReport, path string) error {
+	data, err := json.MarshalIndent(report, "", "    ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
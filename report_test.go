@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"fbc-filter/pkg/filter"
+)
+
+func TestWriteFilterReport(t *testing.T) {
+	tests := []struct {
+		name   string
+		result *filter.FilterResult
+		want   string
+	}{
+		{
+			name:   "no warnings",
+			result: &filter.FilterResult{},
+			want: `{
+    "version": "fbc-filter.report/v1",
+    "input": {
+        "packages": 3,
+        "channels": 4,
+        "bundles": 6
+    },
+    "output": {
+        "packages": 2,
+        "channels": 3,
+        "bundles": 4
+    },
+    "packages": [
+        {
+            "name": "bar",
+            "defaultChannel": "alpha",
+            "channels": [
+                "alpha"
+            ],
+            "bundles": 1
+        },
+        {
+            "name": "foo",
+            "defaultChannel": "stable",
+            "channels": [
+                "2.0",
+                "stable"
+            ],
+            "bundles": 3
+        }
+    ],
+    "warnings": []
+}`,
+		},
+		{
+			name: "warnings and errors",
+			result: &filter.FilterResult{
+				Warnings: []filter.Warning{{Code: filter.WarningPackageNotFound, Package: "qux", Message: `package "qux" not found`}},
+				Errors:   []filter.PackageError{{Package: "baz", Err: errors.New("invalid versionRange")}},
+			},
+			want: `{
+    "version": "fbc-filter.report/v1",
+    "input": {
+        "packages": 3,
+        "channels": 4,
+        "bundles": 6
+    },
+    "output": {
+        "packages": 2,
+        "channels": 3,
+        "bundles": 4
+    },
+    "packages": [
+        {
+            "name": "bar",
+            "defaultChannel": "alpha",
+            "channels": [
+                "alpha"
+            ],
+            "bundles": 1
+        },
+        {
+            "name": "foo",
+            "defaultChannel": "stable",
+            "channels": [
+                "2.0",
+                "stable"
+            ],
+            "bundles": 3
+        }
+    ],
+    "warnings": [
+        {
+            "code": "package-not-found",
+            "package": "qux",
+            "message": "package \"qux\" not found"
+        }
+    ],
+    "errors": [
+        {
+            "package": "baz",
+            "message": "invalid versionRange"
+        }
+    ]
+}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := catalogCounts{Packages: 3, Channels: 4, Bundles: 6}
+			path := filepath.Join(t.TempDir(), "report.json")
+			if err := writeFilterReport(newFilterReport(input, generateModel(t), tt.result), path); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("expected\n%s\ngot\n%s", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+// parseSelectExpressions builds a FilterConfiguration from --select expressions. Each expression selects a single
+// package and is a comma-separated list of terms:
+//
+//	package=<name>            the package to keep (required, exactly once)
+//	defaultChannel=<name>     the default channel override for the package
+//	channel=<name>            a channel to keep (may be repeated)
+//	version<op><version>      a version constraint, where <op> is one of =, !=, >, >=, <, <=
+//
+// Version constraints are joined into a single versionRange that applies to every selected channel, so they can
+// only be used together with at least one channel term. For example:
+//
+//	package=foo,channel=stable,version>=1.2.0,version<2.0.0
+func parseSelectExpressions(exprs []string) (v1.FilterConfiguration, error) {
+	config := v1.FilterConfiguration{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       v1.Kind,
+			APIVersion: v1.APIVersion,
+		},
+	}
+	for _, expr := range exprs {
+		pkg, err := parseSelectExpression(expr)
+		if err != nil {
+			return v1.FilterConfiguration{}, fmt.Errorf("invalid select expression %q: %v", expr, err)
+		}
+		config.Packages = append(config.Packages, pkg)
+	}
+	return config, nil
+}
+
+func parseSelectExpression(expr string) (v1.Package, error) {
+	var (
+		pkg         v1.Package
+		constraints []string
+	)
+	for _, term := range strings.Split(expr, ",") {
+		term = strings.TrimSpace(term)
+		if strings.HasPrefix(term, "version") {
+			constraint := strings.TrimSpace(strings.TrimPrefix(term, "version"))
+			if !hasComparisonOperator(constraint) {
+				return v1.Package{}, fmt.Errorf("version term %q must use one of the operators =, !=, >, >=, <, <=", term)
+			}
+			constraints = append(constraints, constraint)
+			continue
+		}
+
+		key, value, ok := strings.Cut(term, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok || value == "" {
+			return v1.Package{}, fmt.Errorf("term %q must have the form key=value", term)
+		}
+		switch key {
+		case "package":
+			if pkg.Name != "" {
+				return v1.Package{}, fmt.Errorf("package specified more than once")
+			}
+			pkg.Name = value
+		case "defaultChannel":
+			pkg.DefaultChannel = value
+		case "channel":
+			pkg.Channels = append(pkg.Channels, v1.Channel{Name: value})
+		default:
+			return v1.Package{}, fmt.Errorf("unknown key %q: must be one of package, defaultChannel, channel, or version", key)
+		}
+	}
+	if pkg.Name == "" {
+		return v1.Package{}, fmt.Errorf("package must be specified")
+	}
+	if len(constraints) > 0 {
+		if len(pkg.Channels) == 0 {
+			return v1.Package{}, fmt.Errorf("version constraints require at least one channel")
+		}
+		for i := range pkg.Channels {
+			pkg.Channels[i].VersionRange = strings.Join(constraints, " ")
+		}
+	}
+	return pkg, nil
+}
+
+func hasComparisonOperator(constraint string) bool {
+	// the two-character operators come first, so that the longest operator the constraint starts with is used.
+	for _, op := range []string{"!=", ">=", "<=", "=", ">", "<"} {
+		if strings.HasPrefix(constraint, op) {
+			return len(strings.TrimSpace(strings.TrimPrefix(constraint, op))) > 0
+		}
+	}
+	return false
+}
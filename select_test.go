@@ -0,0 +1,66 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+func TestParseSelectExpressions(t *testing.T) {
+	tests := []struct {
+		name    string
+		exprs   []string
+		want    []v1.Package
+		wantErr string
+	}{
+		{
+			name:  "package",
+			exprs: []string{"package=foo"},
+			want:  []v1.Package{{Name: "foo"}},
+		},
+		{
+			name:  "every term",
+			exprs: []string{"package=foo, defaultChannel=stable, channel=stable, channel=fast, version>=1.2.0, version<2.0.0"},
+			want: []v1.Package{{
+				Name:           "foo",
+				DefaultChannel: "stable",
+				Channels:       []v1.Channel{{Name: "stable", VersionRange: ">=1.2.0 <2.0.0"}, {Name: "fast", VersionRange: ">=1.2.0 <2.0.0"}},
+			}},
+		},
+		{
+			name:  "several packages",
+			exprs: []string{"package=foo", "package=bar,channel=alpha,version!=0.1.0"},
+			want:  []v1.Package{{Name: "foo"}, {Name: "bar", Channels: []v1.Channel{{Name: "alpha", VersionRange: "!=0.1.0"}}}},
+		},
+		{name: "no package", exprs: []string{"channel=stable"}, wantErr: `invalid select expression "channel=stable": package must be specified`},
+		{name: "package twice", exprs: []string{"package=foo,package=bar"}, wantErr: "package specified more than once"},
+		{name: "not key=value", exprs: []string{"package"}, wantErr: `term "package" must have the form key=value`},
+		{name: "empty value", exprs: []string{"package="}, wantErr: `term "package=" must have the form key=value`},
+		{name: "unknown key", exprs: []string{"package=foo,channels=stable"}, wantErr: `unknown key "channels"`},
+		{name: "version without an operator", exprs: []string{"package=foo,channel=stable,version1.2.0"}, wantErr: `version term "version1.2.0" must use one of the operators`},
+		{name: "version without a value", exprs: []string{"package=foo,channel=stable,version>="}, wantErr: `version term "version>=" must use one of the operators`},
+		{name: "version without a channel", exprs: []string{"package=foo,version>=1.2.0"}, wantErr: "version constraints require at least one channel"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSelectExpressions(tt.exprs)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Kind != v1.Kind || got.APIVersion != v1.APIVersion {
+				t.Errorf("expected kind %q and apiVersion %q, got %q and %q", v1.Kind, v1.APIVersion, got.Kind, got.APIVersion)
+			}
+			if !reflect.DeepEqual(got.Packages, tt.want) {
+				t.Errorf("expected packages %+v, got %+v", tt.want, got.Packages)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+// packageLabels returns the labels of a package, which are its properties whose value is a string, keyed by the
+// property type.
+func packageLabels(p declcfg.Package) labels.Set {
+	set := labels.Set{}
+	for _, prop := range p.Properties {
+		var value string
+		if err := json.Unmarshal(prop.Value, &value); err == nil {
+			set[prop.Type] = value
+		}
+	}
+	return set
+}
+
+// selectPackages adds a configuration entry, without channels, for each package of the catalog that matches the
+// configuration's package selector and is not already configured by name.
+func selectPackages(fbc *declcfg.DeclarativeConfig, configuration *v1.FilterConfiguration) error {
+	if configuration.PackageSelector == nil {
+		return nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(configuration.PackageSelector)
+	if err != nil {
+		return fmt.Errorf("invalid packageSelector: %v", err)
+	}
+	configured := map[string]struct{}{}
+	for _, p := range configuration.Packages {
+		configured[p.Name] = struct{}{}
+	}
+	for _, p := range fbc.Packages {
+		if _, ok := configured[p.Name]; ok {
+			continue
+		}
+		if selector.Matches(packageLabels(p)) {
+			configuration.Packages = append(configuration.Packages, v1.Package{Name: p.Name})
+			configured[p.Name] = struct{}{}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+func TestSelectPackages(t *testing.T) {
+	pkg := func(name string, labels map[string]interface{}) declcfg.Package {
+		p := declcfg.Package{Schema: declcfg.SchemaPackage, Name: name}
+		for k, v := range labels {
+			value, err := json.Marshal(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p.Properties = append(p.Properties, property.Property{Type: k, Value: value})
+		}
+		return p
+	}
+	// only string values are labels, so baz's tier does not match.
+	fbc := &declcfg.DeclarativeConfig{Packages: []declcfg.Package{
+		pkg("foo", map[string]interface{}{"tier": "gold", "team": "a"}),
+		pkg("bar", map[string]interface{}{"tier": "gold"}),
+		pkg("baz", map[string]interface{}{"tier": map[string]string{"name": "gold"}}),
+		pkg("qux", map[string]interface{}{"tier": "silver"}),
+	}}
+	tests := []struct {
+		name     string
+		packages []v1.Package
+		selector *metav1.LabelSelector
+		want     []string
+		wantErr  string
+	}{
+		{name: "no selector", packages: []v1.Package{{Name: "qux"}}, want: []string{"qux"}},
+		{name: "match labels", selector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "gold"}}, want: []string{"foo", "bar"}},
+		{
+			name:     "already configured",
+			packages: []v1.Package{{Name: "foo", DefaultChannel: "stable"}},
+			selector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "gold"}},
+			want:     []string{"foo", "bar"},
+		},
+		{
+			name: "match expressions",
+			selector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "tier", Operator: metav1.LabelSelectorOpIn, Values: []string{"gold", "silver"}},
+				{Key: "team", Operator: metav1.LabelSelectorOpDoesNotExist},
+			}},
+			want: []string{"bar", "qux"},
+		},
+		{
+			name:     "invalid selector",
+			selector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "tier", Operator: "Near"}}},
+			wantErr:  "invalid packageSelector",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := v1.FilterConfiguration{Packages: tt.packages, PackageSelector: tt.selector}
+			err := selectPackages(fbc, &config)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got []string
+			for _, p := range config.Packages {
+				got = append(got, p.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected packages %v, got %v", tt.want, got)
+			}
+			if len(tt.packages) > 0 && !reflect.DeepEqual(config.Packages[0], tt.packages[0]) {
+				t.Errorf("expected the configured package to be unchanged, got %+v", config.Packages[0])
+			}
+		})
+	}
+}
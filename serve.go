@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/operator-framework/operator-registry/alpha/action"
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/spf13/cobra"
+
+	v1 "fbc-filter/api/config/v1"
+	"fbc-filter/pkg/filter"
+)
+
+// maxFilterRequestSize limits the size of a filter request body, which only carries a reference and a configuration.
+const maxFilterRequestSize = 1 << 20
+
+// filterRequest is the body of a request to the filter endpoint. Output is the name of an output format and
+// defaults to json.
+type filterRequest struct {
+	Ref    string                 `json:"ref"`
+	Config v1.FilterConfiguration `json:"config"`
+	Output string                 `json:"output"`
+}
+
+var outputContentTypes = map[string]string{
+	"yaml": "application/yaml",
+	"json": "application/json",
+	"tar":  "application/x-tar",
+	"tgz":  "application/gzip",
+}
+
+func newServeCmd() *cobra.Command {
+	var (
+		listen        string
+		timeout       time.Duration
+		maxConcurrent int
+	)
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve an HTTP endpoint that filters catalog images on request",
+		Long: `Serve an HTTP endpoint that filters catalog images on request.
+
+POST /filter with a JSON body of the form {"ref": "<catalog image>", "config": <FilterConfiguration>, "output": "<format>"}
+responds with the filtered catalog in the requested output format (default json). Only image references are
+accepted, so that clients cannot read the server's filesystem.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := loggerFrom(cmd.Context())
+			if maxConcurrent < 1 {
+				logFatalf(logger, "--max-concurrent must be at least 1")
+			}
+			mux := http.NewServeMux()
+			mux.Handle("/filter", newFilterHandler(renderImage, timeout, maxConcurrent))
+			server := &http.Server{
+				Addr:              listen,
+				Handler:           mux,
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+			logger.Info("listening", "address", listen)
+			if err := server.ListenAndServe(); err != nil {
+				logFatalf(logger, "error serving: %v", err)
+			}
+		},
+	}
+	cmd.Flags().StringVar(&listen, "listen", ":8080", "Address to listen on")
+	cmd.Flags().DurationVar(&timeout, "timeout", 5*time.Minute, "Maximum time to render and filter the catalog of a single request")
+	cmd.Flags().IntVar(&maxConcurrent, "max-concurrent", 4, "Maximum number of requests filtered at once; further requests are rejected with 503 Service Unavailable")
+	return cmd
+}
+
+// renderImage renders a catalog image. Other references are rejected, so that clients cannot read the server's
+// filesystem.
+func renderImage(ctx context.Context, ref string) (*declcfg.DeclarativeConfig, error) {
+	render := action.Render{
+		Refs:           []string{ref},
+		AllowedRefMask: action.RefDCImage | action.RefSqliteImage,
+	}
+	return render.Run(ctx)
+}
+
+// newFilterHandler returns the handler of the filter endpoint, which renders the requested reference with render. At
+// most maxConcurrent requests are filtered at once, each within the timeout.
+func newFilterHandler(render func(ctx context.Context, ref string) (*declcfg.DeclarativeConfig, error), timeout time.Duration, maxConcurrent int) http.Handler {
+	slots := make(chan struct{}, maxConcurrent)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+			return
+		}
+		select {
+		case slots <- struct{}{}:
+			defer func() { <-slots }()
+		default:
+			http.Error(w, "too many filter requests in progress, try again later", http.StatusServiceUnavailable)
+			return
+		}
+
+		var req filterRequest
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxFilterRequestSize))
+		if err := dec.Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+			return
+		}
+		if req.Ref == "" {
+			http.Error(w, "invalid request: ref must be set", http.StatusBadRequest)
+			return
+		}
+		if req.Output == "" {
+			req.Output = "json"
+		}
+		format, err := lookupOutputFormat(req.Output)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := req.Config.ValidateTypeMeta(); err != nil {
+			http.Error(w, fmt.Sprintf("invalid configuration: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := req.Config.Validate(); err != nil {
+			http.Error(w, fmt.Sprintf("invalid configuration: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		fbc, err := render(ctx, req.Ref)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("error rendering %q: %v", req.Ref, err), http.StatusBadGateway)
+			return
+		}
+		var warnings []filter.Warning
+		m, err := applyConfig(fbc, req.Config, func(w filter.Warning) { warnings = append(warnings, w) })
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := format.writeFunc()(filteredCatalog(m, fbc.Deprecations, fbc.Others), &buf); err != nil {
+			http.Error(w, fmt.Sprintf("error writing output: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", outputContentTypes[format.name])
+		for _, warning := range warnings {
+			w.Header().Add("X-Filter-Warning", warning.Message)
+		}
+		buf.WriteTo(w)
+	})
+}
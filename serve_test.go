@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+)
+
+func TestFilterHandler(t *testing.T) {
+	const config = `{"apiVersion": "olm.operatorframework.io/v1", "kind": "FilterConfiguration", "packages": [{"name": "foo"}]}`
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "not a POST", method: http.MethodGet, wantStatus: http.StatusMethodNotAllowed, wantBody: "only POST is supported"},
+		{name: "not JSON", body: "ref: example.com/catalog", wantStatus: http.StatusBadRequest, wantBody: "invalid request: "},
+		{name: "no ref", body: `{"config": ` + config + `}`, wantStatus: http.StatusBadRequest, wantBody: "invalid request: ref must be set"},
+		{
+			name:       "invalid output",
+			body:       `{"ref": "example.com/catalog:latest", "config": ` + config + `, "output": "xml"}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `invalid request: invalid output format "xml"`,
+		},
+		{
+			name:       "wrong kind",
+			body:       `{"ref": "example.com/catalog:latest", "config": {"apiVersion": "olm.operatorframework.io/v1", "kind": "Catalog"}}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "invalid configuration: kind must be",
+		},
+		{
+			name:       "invalid configuration",
+			body:       `{"ref": "example.com/catalog:latest", "config": {"apiVersion": "olm.operatorframework.io/v1", "kind": "FilterConfiguration", "packages": [{"name": "foo", "channels": [{"name": "stable", "headOnly": true, "keepLatest": 2}]}]}}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `invalid configuration: package "foo": channel "stable": headOnly and keepLatest cannot both be set`,
+		},
+		{
+			name:       "local directory",
+			body:       `{"ref": "` + t.TempDir() + `", "config": ` + config + `}`,
+			wantStatus: http.StatusBadGateway,
+			wantBody:   "error rendering",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodPost
+			}
+			rec := httptest.NewRecorder()
+			newFilterHandler(renderImage, time.Minute, 1).ServeHTTP(rec, httptest.NewRequest(method, "/filter", strings.NewReader(tt.body)))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("expected body containing %q, got %q", tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestFilterHandlerOutput(t *testing.T) {
+	render := func(ctx context.Context, ref string) (*declcfg.DeclarativeConfig, error) {
+		fbc := outputCatalog()
+		for _, pkg := range []string{"foo", "bar"} {
+			fbc.Deprecations = append(fbc.Deprecations, declcfg.Deprecation{
+				Schema:  declcfg.SchemaDeprecation,
+				Package: pkg,
+				Entries: []declcfg.DeprecationEntry{{
+					Reference: declcfg.PackageScopedReference{Schema: declcfg.SchemaBundle, Name: pkg + ".v1.0.0"},
+					Message:   pkg + " 1.0.0 is deprecated",
+				}},
+			})
+			fbc.Others = append(fbc.Others, declcfg.Meta{Schema: "custom.icon", Package: pkg, Blob: json.RawMessage(`{"schema":"custom.icon","package":"` + pkg + `"}`)})
+		}
+		fbc.Others = append(fbc.Others, globalOther())
+		return &fbc, nil
+	}
+	body := `{"ref": "example.com/catalog:latest", "config": {"apiVersion": "olm.operatorframework.io/v1", "kind": "FilterConfiguration", "packages": [{"name": "foo"}]}}`
+	rec := httptest.NewRecorder()
+	newFilterHandler(render, time.Minute, 1).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/filter", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("expected content type application/json, got %q", got)
+	}
+	fbc, err := declcfg.LoadReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fbc.Packages) != 1 || fbc.Packages[0].Name != "foo" || len(fbc.Bundles) != 1 {
+		t.Errorf("expected only package foo and its bundle, got %+v", fbc.Packages)
+	}
+	if len(fbc.Deprecations) != 1 || fbc.Deprecations[0].Package != "foo" {
+		t.Errorf("expected the deprecations of package foo, got %+v", fbc.Deprecations)
+	}
+	var others []string
+	for _, o := range fbc.Others {
+		others = append(others, o.Schema+" "+o.Package)
+	}
+	if want := []string{"custom.icon foo", "custom.global "}; !reflect.DeepEqual(others, want) {
+		t.Errorf("expected others %q, got %q", want, others)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	blangsemver "github.com/blang/semver/v4"
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+)
+
+const (
+	sortBundlesByVersion = "version"
+	sortBundlesByName    = "name"
+)
+
+func validateSortBundlesBy(by string) error {
+	switch by {
+	case sortBundlesByVersion, sortBundlesByName:
+		return nil
+	}
+	return fmt.Errorf("invalid bundle sort order %q: must be one of %q or %q", by, sortBundlesByVersion, sortBundlesByName)
+}
+
+// sortChannelEntries orders the entries of every channel. Sorting by version puts the highest version first, with
+// ties broken by name. Sorting by name orders entries alphabetically.
+func sortChannelEntries(fbc *declcfg.DeclarativeConfig, by string) error {
+	switch by {
+	case sortBundlesByName:
+		for _, ch := range fbc.Channels {
+			sort.SliceStable(ch.Entries, func(i, j int) bool {
+				return ch.Entries[i].Name < ch.Entries[j].Name
+			})
+		}
+	case sortBundlesByVersion:
+		versions, err := bundleVersions(*fbc)
+		if err != nil {
+			return err
+		}
+		for _, ch := range fbc.Channels {
+			sort.SliceStable(ch.Entries, func(i, j int) bool {
+				vi, vj := versions[ch.Package][ch.Entries[i].Name], versions[ch.Package][ch.Entries[j].Name]
+				if c := vi.Compare(vj); c != 0 {
+					return c > 0
+				}
+				return ch.Entries[i].Name < ch.Entries[j].Name
+			})
+		}
+	default:
+		return validateSortBundlesBy(by)
+	}
+	return nil
+}
+
+// bundleVersions returns the version of each bundle, keyed by package name and then bundle name.
+func bundleVersions(fbc declcfg.DeclarativeConfig) (map[string]map[string]blangsemver.Version, error) {
+	versions := map[string]map[string]blangsemver.Version{}
+	for _, b := range fbc.Bundles {
+		props, err := property.Parse(b.Properties)
+		if err != nil {
+			return nil, fmt.Errorf("parse properties for bundle %q: %v", b.Name, err)
+		}
+		if len(props.Packages) != 1 {
+			return nil, fmt.Errorf("bundle %q must have exactly 1 %q property, found %d", b.Name, property.TypePackage, len(props.Packages))
+		}
+		v, err := blangsemver.Parse(props.Packages[0].Version)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing bundle %q version %q: %v", b.Name, props.Packages[0].Version, err)
+		}
+		if versions[b.Package] == nil {
+			versions[b.Package] = map[string]blangsemver.Version{}
+		}
+		versions[b.Package][b.Name] = v
+	}
+	return versions, nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+)
+
+func TestSortChannelEntries(t *testing.T) {
+	bundle := func(name, version string) declcfg.Bundle {
+		return declcfg.Bundle{
+			Schema:     declcfg.SchemaBundle,
+			Name:       name,
+			Package:    "foo",
+			Properties: []property.Property{property.MustBuildPackage("foo", version)},
+		}
+	}
+	// the names do not sort in version order, and two bundles share a version.
+	catalog := func() *declcfg.DeclarativeConfig {
+		return &declcfg.DeclarativeConfig{
+			Channels: []declcfg.Channel{{Schema: declcfg.SchemaChannel, Package: "foo", Name: "stable", Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v1.2.0"},
+				{Name: "foo.v1.10.0"},
+				{Name: "foo-rebuild.v1.2.0"},
+				{Name: "foo.v1.9.0"},
+			}}},
+			Bundles: []declcfg.Bundle{
+				bundle("foo.v1.2.0", "1.2.0"),
+				bundle("foo.v1.10.0", "1.10.0"),
+				bundle("foo-rebuild.v1.2.0", "1.2.0"),
+				bundle("foo.v1.9.0", "1.9.0"),
+			},
+		}
+	}
+	tests := []struct {
+		name    string
+		by      string
+		modify  func(*declcfg.DeclarativeConfig)
+		want    []string
+		wantErr string
+	}{
+		{name: "version", by: sortBundlesByVersion, want: []string{"foo.v1.10.0", "foo.v1.9.0", "foo-rebuild.v1.2.0", "foo.v1.2.0"}},
+		{name: "name", by: sortBundlesByName, want: []string{"foo-rebuild.v1.2.0", "foo.v1.10.0", "foo.v1.2.0", "foo.v1.9.0"}},
+		{name: "invalid order", by: "date", wantErr: `invalid bundle sort order "date"`},
+		{
+			name:    "invalid version",
+			by:      sortBundlesByVersion,
+			modify:  func(fbc *declcfg.DeclarativeConfig) { fbc.Bundles[0] = bundle("foo.v1.2.0", "one") },
+			wantErr: `error parsing bundle "foo.v1.2.0" version "one"`,
+		},
+		{
+			name:    "missing package property",
+			by:      sortBundlesByVersion,
+			modify:  func(fbc *declcfg.DeclarativeConfig) { fbc.Bundles[0].Properties = nil },
+			wantErr: `bundle "foo.v1.2.0" must have exactly 1 "olm.package" property, found 0`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fbc := catalog()
+			if tt.modify != nil {
+				tt.modify(fbc)
+			}
+			err := sortChannelEntries(fbc, tt.by)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got []string
+			for _, e := range fbc.Channels[0].Entries {
+				got = append(got, e.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected entries %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
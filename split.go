@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// majorVersionSplit is the part of a package's catalog containing the bundles of one major version. The part with
+// no package holds the other objects that belong to no package.
+type majorVersionSplit struct {
+	pkg   string
+	major uint64
+	fbc   declcfg.DeclarativeConfig
+}
+
+// splitByMajorVersion splits the catalog into one part per package and major version of its bundles. A channel
+// that spans major versions is split too: each part keeps the channel, under the same name, with only the entries
+// of that major version. Each part must be a valid catalog on its own, so the package's default channel must have
+// bundles of every major version in the package. Other objects that belong to no package are returned as a last
+// part of their own.
+func splitByMajorVersion(fbc declcfg.DeclarativeConfig) ([]majorVersionSplit, error) {
+	versions, err := bundleVersions(fbc)
+	if err != nil {
+		return nil, err
+	}
+	majors := map[string]sets.Set[uint64]{}
+	for pkgName, bundles := range versions {
+		majors[pkgName] = sets.New[uint64]()
+		for _, v := range bundles {
+			majors[pkgName].Insert(v.Major)
+		}
+	}
+
+	var splits []majorVersionSplit
+	for _, p := range fbc.Packages {
+		for _, major := range sets.List(majors[p.Name]) {
+			inMajor := func(bundleName string) bool {
+				v, ok := versions[p.Name][bundleName]
+				return ok && v.Major == major
+			}
+			part := declcfg.DeclarativeConfig{Packages: []declcfg.Package{p}}
+			channels := sets.New[string]()
+			for _, c := range fbc.Channels {
+				if c.Package != p.Name {
+					continue
+				}
+				var entries []declcfg.ChannelEntry
+				for _, e := range c.Entries {
+					if inMajor(e.Name) {
+						entries = append(entries, e)
+					}
+				}
+				if len(entries) == 0 {
+					continue
+				}
+				c.Entries = entries
+				part.Channels = append(part.Channels, c)
+				channels.Insert(c.Name)
+			}
+			if !channels.Has(p.DefaultChannel) {
+				return nil, fmt.Errorf("cannot split package %q by major version: default channel %q has no bundles with major version %d", p.Name, p.DefaultChannel, major)
+			}
+			for _, b := range fbc.Bundles {
+				if b.Package == p.Name && inMajor(b.Name) {
+					part.Bundles = append(part.Bundles, b)
+				}
+			}
+			for _, o := range fbc.Others {
+				if o.Package == p.Name {
+					part.Others = append(part.Others, o)
+				}
+			}
+			for _, d := range fbc.Deprecations {
+				if d.Package != p.Name {
+					continue
+				}
+				var entries []declcfg.DeprecationEntry
+				for _, e := range d.Entries {
+					switch e.Reference.Schema {
+					case declcfg.SchemaChannel:
+						if !channels.Has(e.Reference.Name) {
+							continue
+						}
+					case declcfg.SchemaBundle:
+						if !inMajor(e.Reference.Name) {
+							continue
+						}
+					}
+					entries = append(entries, e)
+				}
+				if len(entries) > 0 {
+					d.Entries = entries
+					part.Deprecations = append(part.Deprecations, d)
+				}
+			}
+
+			m, err := declcfg.ConvertToModel(part)
+			if err == nil {
+				err = m.Validate()
+			}
+			if err != nil {
+				return nil, fmt.Errorf("major version %d of package %q is not a valid catalog on its own: %v", major, p.Name, err)
+			}
+			splits = append(splits, majorVersionSplit{pkg: p.Name, major: major, fbc: part})
+		}
+	}
+	var global declcfg.DeclarativeConfig
+	for _, o := range fbc.Others {
+		if o.Package == "" {
+			global.Others = append(global.Others, o)
+		}
+	}
+	if len(global.Others) > 0 {
+		splits = append(splits, majorVersionSplit{fbc: global})
+	}
+	return splits, nil
+}
+
+// writeSplitOutputs writes each part of a split catalog next to every output file, naming the part's file after
+// the output file with the package name and major version inserted before the extension. For example, output
+// file catalog.yaml gets catalog-foo-v1.yaml and catalog-foo-v2.yaml for package foo, and catalog-others.yaml for
+// the objects of no package.
+func writeSplitOutputs(splits []majorVersionSplit, targets []outputTarget) error {
+	for _, t := range targets {
+		if t.path == "" {
+			return fmt.Errorf("splitting by major version requires output files; it cannot be written to stdout")
+		}
+	}
+	for _, t := range targets {
+		ext := t.format.extensionOf(t.path)
+		base := strings.TrimSuffix(t.path, ext)
+		for _, s := range splits {
+			part := outputTarget{path: fmt.Sprintf("%s-%s-v%d%s", base, s.pkg, s.major, ext), format: t.format}
+			if s.pkg == "" {
+				part.path = base + "-others" + ext
+			}
+			if err := writeOutputFile(s.fbc, part); err != nil {
+				return fmt.Errorf("write %q: %v", part.path, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+)
+
+// splitCatalog has a package foo with a stable channel 1.0.0 -> 1.1.0 -> 2.0.0 and a channel v2 with only 2.0.0,
+// in which foo.v1.0.0 and the v2 channel are deprecated, and a package bar with a single bundle.
+func splitCatalog() declcfg.DeclarativeConfig {
+	bundle := func(pkg, version string) declcfg.Bundle {
+		return declcfg.Bundle{
+			Schema:     declcfg.SchemaBundle,
+			Name:       pkg + ".v" + version,
+			Package:    pkg,
+			Image:      "example.com/" + pkg + ":v" + version,
+			Properties: []property.Property{property.MustBuildPackage(pkg, version)},
+		}
+	}
+	return declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{
+			{Schema: declcfg.SchemaPackage, Name: "foo", DefaultChannel: "stable"},
+			{Schema: declcfg.SchemaPackage, Name: "bar", DefaultChannel: "alpha"},
+		},
+		Channels: []declcfg.Channel{
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "stable", Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v1.0.0"},
+				{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+				{Name: "foo.v2.0.0", Replaces: "foo.v1.1.0"},
+			}},
+			{Schema: declcfg.SchemaChannel, Package: "foo", Name: "v2", Entries: []declcfg.ChannelEntry{{Name: "foo.v2.0.0"}}},
+			{Schema: declcfg.SchemaChannel, Package: "bar", Name: "alpha", Entries: []declcfg.ChannelEntry{{Name: "bar.v0.1.0"}}},
+		},
+		Bundles: []declcfg.Bundle{bundle("foo", "1.0.0"), bundle("foo", "1.1.0"), bundle("foo", "2.0.0"), bundle("bar", "0.1.0")},
+		Deprecations: []declcfg.Deprecation{{
+			Schema:  declcfg.SchemaDeprecation,
+			Package: "foo",
+			Entries: []declcfg.DeprecationEntry{
+				{Reference: declcfg.PackageScopedReference{Schema: declcfg.SchemaBundle, Name: "foo.v1.0.0"}, Message: "foo.v1.0.0 is deprecated"},
+				{Reference: declcfg.PackageScopedReference{Schema: declcfg.SchemaChannel, Name: "v2"}, Message: "v2 is deprecated"},
+			},
+		}},
+	}
+}
+
+func TestSplitByMajorVersion(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*declcfg.DeclarativeConfig)
+		// want describes each part as "<package> v<major>", followed by its channels, bundles, and deprecated
+		// references.
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "split",
+			want: []string{
+				"foo v1 channels=[stable:foo.v1.0.0,foo.v1.1.0] bundles=[foo.v1.0.0,foo.v1.1.0] deprecated=[foo.v1.0.0]",
+				"foo v2 channels=[stable:foo.v2.0.0 v2:foo.v2.0.0] bundles=[foo.v2.0.0] deprecated=[v2]",
+				"bar v0 channels=[alpha:bar.v0.1.0] bundles=[bar.v0.1.0] deprecated=[]",
+			},
+		},
+		{
+			name:    "default channel without a major version",
+			modify:  func(fbc *declcfg.DeclarativeConfig) { fbc.Packages[0].DefaultChannel = "v2" },
+			wantErr: `cannot split package "foo" by major version: default channel "v2" has no bundles with major version 1`,
+		},
+		{
+			// 2.1.0 also replaces 1.1.0, so the stable channel of major version 2 has two heads.
+			name: "part is not a valid catalog",
+			modify: func(fbc *declcfg.DeclarativeConfig) {
+				fbc.Channels[0].Entries = append(fbc.Channels[0].Entries, declcfg.ChannelEntry{Name: "foo.v2.1.0", Replaces: "foo.v1.1.0"})
+				fbc.Bundles = append(fbc.Bundles, declcfg.Bundle{
+					Schema:     declcfg.SchemaBundle,
+					Name:       "foo.v2.1.0",
+					Package:    "foo",
+					Image:      "example.com/foo:v2.1.0",
+					Properties: []property.Property{property.MustBuildPackage("foo", "2.1.0")},
+				})
+			},
+			wantErr: `major version 2 of package "foo" is not a valid catalog on its own`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fbc := splitCatalog()
+			if tt.modify != nil {
+				tt.modify(&fbc)
+			}
+			splits, err := splitByMajorVersion(fbc)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got []string
+			for _, s := range splits {
+				var channels, bundles, deprecated []string
+				for _, c := range s.fbc.Channels {
+					var entries []string
+					for _, e := range c.Entries {
+						entries = append(entries, e.Name)
+					}
+					channels = append(channels, c.Name+":"+strings.Join(entries, ","))
+				}
+				for _, b := range s.fbc.Bundles {
+					bundles = append(bundles, b.Name)
+				}
+				for _, d := range s.fbc.Deprecations {
+					for _, e := range d.Entries {
+						deprecated = append(deprecated, e.Reference.Name)
+					}
+				}
+				got = append(got, fmt.Sprintf("%s v%d channels=[%s] bundles=[%s] deprecated=[%s]",
+					s.pkg, s.major, strings.Join(channels, " "), strings.Join(bundles, ","), strings.Join(deprecated, ",")))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected parts\n%s\ngot\n%s", strings.Join(tt.want, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
+
+func TestWriteSplitOutputs(t *testing.T) {
+	fbc := splitCatalog()
+	fbc.Others = append(fbc.Others, globalOther())
+	splits, err := splitByMajorVersion(fbc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	yamlFormat, err := lookupOutputFormat("yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonFormat, err := lookupOutputFormat("json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	targets := []outputTarget{
+		{path: filepath.Join(dir, "catalog.yml"), format: yamlFormat},
+		{path: filepath.Join(dir, "catalog.json"), format: jsonFormat},
+	}
+	if err := writeSplitOutputs(splits, targets); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Name())
+	}
+	want := []string{
+		"catalog-bar-v0.json", "catalog-bar-v0.yml",
+		"catalog-foo-v1.json", "catalog-foo-v1.yml",
+		"catalog-foo-v2.json", "catalog-foo-v2.yml",
+		"catalog-others.json", "catalog-others.yml",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected files %v, got %v", want, got)
+	}
+
+	if err := writeSplitOutputs(splits, []outputTarget{{format: yamlFormat}}); err == nil || !strings.Contains(err.Error(), "cannot be written to stdout") {
+		t.Errorf("expected an error writing to stdout, got %v", err)
+	}
+}
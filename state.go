@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/operator-framework/operator-registry/alpha/model"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/yaml"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+// lastHeadUnknown is the version @lastHead resolves to when no head is recorded for a channel. It is the lowest
+// possible version, so that ">=@lastHead" keeps everything on the first run.
+const lastHeadUnknown = "0.0.0-0"
+
+// stateEntry is a single item of a --state-file, which is a YAML list of these entries recording the head version
+// of each channel in the previous run's output.
+type stateEntry struct {
+	Package string `json:"package"`
+	Channel string `json:"channel"`
+	Version string `json:"version"`
+}
+
+// runState is the head version of each channel recorded by the previous run, keyed by package name and then
+// channel name.
+type runState map[string]map[string]string
+
+// loadState reads the state file, returning an empty state if it does not exist yet.
+func loadState(path string) (runState, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return runState{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseState(data)
+}
+
+func parseState(data []byte) (runState, error) {
+	state := runState{}
+	var entries []stateEntry
+	if err := yaml.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parse state: %v", err)
+	}
+	for _, e := range entries {
+		if state[e.Package] == nil {
+			state[e.Package] = map[string]string{}
+		}
+		state[e.Package][e.Channel] = e.Version
+	}
+	return state, nil
+}
+
+// resolveLastHead replaces @lastHead in the configured version ranges with the recorded head version of each
+// channel.
+func resolveLastHead(configuration *v1.FilterConfiguration, state runState) {
+	for i := range configuration.Packages {
+		p := &configuration.Packages[i]
+		for j := range p.Channels {
+			c := &p.Channels[j]
+			if !strings.Contains(c.VersionRange, v1.LastHeadSentinel) {
+				continue
+			}
+			version, ok := state[p.Name][c.Name]
+			if !ok {
+				version = lastHeadUnknown
+			}
+			c.VersionRange = strings.ReplaceAll(c.VersionRange, v1.LastHeadSentinel, version)
+		}
+	}
+}
+
+// writeState records the head version of each channel of the filtered model, for use by the next run.
+func writeState(m model.Model, path string) error {
+	entries := []stateEntry{}
+	for _, pkgName := range sets.List(sets.KeySet(m)) {
+		pkg := m[pkgName]
+		for _, chName := range sets.List(sets.KeySet(pkg.Channels)) {
+			head, err := pkg.Channels[chName].Head()
+			if err != nil {
+				return fmt.Errorf("error getting head of channel %q in package %q: %v", chName, pkgName, err)
+			}
+			entries = append(entries, stateEntry{Package: pkgName, Channel: chName, Version: head.Version.String()})
+		}
+	}
+	data, err := yaml.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// loadExpectedHeads reads an --expected-heads file, which has the same format as a --state-file.
+func loadExpectedHeads(path string) (runState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseState(data)
+}
+
+// checkExpectedHeads verifies that each channel listed in the expected heads is in the filtered model, with a head
+// of the expected version.
+func checkExpectedHeads(m model.Model, expected runState) error {
+	var errs []error
+	for _, pkgName := range sets.List(sets.KeySet(expected)) {
+		for _, chName := range sets.List(sets.KeySet(expected[pkgName])) {
+			version := expected[pkgName][chName]
+			pkg, ok := m[pkgName]
+			if !ok {
+				errs = append(errs, fmt.Errorf("package %q: expected channel %q with head %s, but the package was filtered out", pkgName, chName, version))
+				continue
+			}
+			ch, ok := pkg.Channels[chName]
+			if !ok {
+				errs = append(errs, fmt.Errorf("package %q: expected channel %q with head %s, but the channel was filtered out", pkgName, chName, version))
+				continue
+			}
+			head, err := ch.Head()
+			if err != nil {
+				return fmt.Errorf("error getting head of channel %q in package %q: %v", chName, pkgName, err)
+			}
+			if head.Version.String() != version {
+				errs = append(errs, fmt.Errorf("package %q: expected channel %q to have head %s, got %s (%q)", pkgName, chName, version, head.Version, head.Name))
+			}
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
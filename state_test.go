@@ -0,0 +1,122 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+func TestLoadState(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    runState
+		wantErr string
+	}{
+		{name: "missing file", want: runState{}},
+		{
+			name: "entries",
+			data: "- package: foo\n  channel: stable\n  version: 1.1.0\n- package: foo\n  channel: fast\n  version: 2.0.0\n",
+			want: runState{"foo": {"stable": "1.1.0", "fast": "2.0.0"}},
+		},
+		{name: "not a list", data: "package: foo\n", wantErr: "parse state"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "state.yaml")
+			if tt.data != "" {
+				if err := os.WriteFile(path, []byte(tt.data), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := loadState(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected state %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestResolveLastHead(t *testing.T) {
+	config := v1.FilterConfiguration{Packages: []v1.Package{{Name: "foo", Channels: []v1.Channel{
+		{Name: "stable", VersionRange: ">=" + v1.LastHeadSentinel},
+		{Name: "fast", VersionRange: ">" + v1.LastHeadSentinel + " <3.0.0"},
+		{Name: "candidate", VersionRange: ">=1.0.0"},
+	}}}}
+	resolveLastHead(&config, runState{"foo": {"stable": "1.1.0", "candidate": "2.0.0"}})
+
+	var got []string
+	for _, c := range config.Packages[0].Channels {
+		got = append(got, c.VersionRange)
+	}
+	want := []string{">=1.1.0", ">" + lastHeadUnknown + " <3.0.0", ">=1.0.0"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected version ranges %v, got %v", want, got)
+	}
+}
+
+func TestWriteState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.yaml")
+	if err := writeState(generateModel(t), path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := loadState(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := runState{"foo": {"stable": "1.1.0", "2.0": "2.0.0"}, "bar": {"alpha": "0.1.0"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected state %v, got %v", want, got)
+	}
+}
+
+func TestCheckExpectedHeads(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected runState
+		wantErr  []string
+	}{
+		{name: "matching heads", expected: runState{"foo": {"stable": "1.1.0", "2.0": "2.0.0"}, "bar": {"alpha": "0.1.0"}}},
+		{
+			name:     "mismatches",
+			expected: runState{"foo": {"stable": "1.0.0", "fast": "1.0.0"}, "baz": {"alpha": "0.1.0"}},
+			wantErr: []string{
+				`package "baz": expected channel "alpha" with head 0.1.0, but the package was filtered out`,
+				`package "foo": expected channel "fast" with head 1.0.0, but the channel was filtered out`,
+				`package "foo": expected channel "stable" to have head 1.0.0, got 1.1.0 ("foo.v1.1.0")`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkExpectedHeads(generateModel(t), tt.expected)
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected errors %q, got none", tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("expected error containing %q, got %v", want, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/yaml"
+
+	v1 "fbc-filter/api/config/v1"
+)
+
+func newValidateCmd() *cobra.Command {
+	var configFile string
+	cmd := &cobra.Command{
+		Use:   "validate --config <config>",
+		Short: "Check a filter configuration without rendering a catalog",
+		Long: `Check a filter configuration without rendering a catalog.
+
+Besides the checks made before every filter run, validate reports default channel overrides that name a channel the
+package's channel list does not keep. It logs every problem it finds and exits non-zero if there are any.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := loggerFrom(cmd.Context())
+			problems := validateConfigFile(cmd.Context(), configFile)
+			for _, p := range problems {
+				logger.Error(p.Error())
+			}
+			if len(problems) > 0 {
+				logFatalf(logger, "%s: %d problem(s) found", configFile, len(problems))
+			}
+			fmt.Printf("%s: valid\n", configFile)
+		},
+	}
+	cmd.Flags().StringVarP(&configFile, "config", "c", "", "Path or http(s) URL of the filter configuration file")
+	cmd.MarkFlagRequired("config")
+	return cmd
+}
+
+// validateConfigFile returns every problem found in a configuration file. A file that cannot be read or parsed
+// reports only that.
+func validateConfigFile(ctx context.Context, location string) []error {
+	configData, err := readConfigData(ctx, location, nil, 30*time.Second, false)
+	if err != nil {
+		return []error{fmt.Errorf("error reading configuration file: %v", err)}
+	}
+	configData, err = expandConfigTemplate(configData)
+	if err != nil {
+		return []error{fmt.Errorf("error expanding configuration file: %v", err)}
+	}
+	var config v1.FilterConfiguration
+	if err := yaml.Unmarshal(configData, &config); err != nil {
+		return []error{fmt.Errorf("error parsing configuration file: %v", err)}
+	}
+
+	var problems []error
+	for _, err := range []error{config.ValidateTypeMeta(), config.Validate()} {
+		if agg, ok := err.(utilerrors.Aggregate); ok {
+			problems = append(problems, utilerrors.Flatten(agg).Errors()...)
+		}
+	}
+	if _, err := parseTargetOLMVersion(config.TargetOLMVersion); err != nil {
+		problems = append(problems, err)
+	}
+
+	for _, p := range config.Packages {
+		if p.DefaultChannel == "" || len(p.Channels) == 0 {
+			continue
+		}
+		channels := sets.New[string]()
+		for _, ch := range p.Channels {
+			channels.Insert(ch.Name)
+		}
+		for _, mc := range p.MergeChannels {
+			channels.Insert(mc.Into)
+		}
+		if !channels.Has(p.DefaultChannel) {
+			problems = append(problems, fmt.Errorf("package %q: defaultChannel %q is not one of the configured channels %v", p.Name, p.DefaultChannel, sets.List(channels)))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestValidateConfigFile(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{
+			name: "valid",
+			data: testConfig,
+		},
+		{
+			name: "default channel kept by a merge",
+			data: testConfig + `  defaultChannel: all
+  channels:
+  - name: stable
+  - name: fast
+  mergeChannels:
+  - into: all
+    from: [stable, fast]
+`,
+		},
+		{
+			name: "every problem",
+			data: `apiVersion: olm.operatorframework.io/v2
+kind: FilterConfiguration
+targetOLMVersion: latest
+packages:
+- name: foo
+  defaultChannel: fast
+  channels:
+  - name: stable
+    headOnly: true
+    keepLatest: 2
+`,
+			want: []string{
+				`apiVersion must be "olm.operatorframework.io/v1", got "olm.operatorframework.io/v2"`,
+				`package "foo": channel "stable": headOnly and keepLatest cannot both be set`,
+				`invalid targetOLMVersion "latest": Invalid Semantic Version`,
+				`package "foo": defaultChannel "fast" is not one of the configured channels [stable]`,
+			},
+		},
+		{
+			name: "not yaml",
+			data: "packages: [",
+			want: []string{"error parsing configuration file: error converting YAML to JSON: yaml: line 1: did not find expected node content"},
+		},
+		{
+			name: "invalid template",
+			data: "packages: {{ undefined }}",
+			want: []string{`error expanding configuration file: template: config:1: function "undefined" not defined`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, p := range validateConfigFile(context.Background(), path) {
+				got = append(got, p.Error())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected problems %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	if got := validateConfigFile(context.Background(), filepath.Join(t.TempDir(), "missing.yaml")); len(got) != 1 {
+		t.Errorf("expected a single problem for a missing file, got %v", got)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	blangsemver "github.com/blang/semver/v4"
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"fbc-filter/pkg/filter"
+)
+
+// checkBundleVersions scans the rendered catalog for bundles whose olm.package version is not strictly valid semver
+// (e.g. "v1.2.3" or "1.2"). Such versions would otherwise fail model conversion or be mishandled when matched
+// against version ranges. When normalize is true, versions that can be interpreted tolerantly (by stripping a "v"
+// prefix and padding missing segments with zeros) are rewritten in place and a warning is emitted. All remaining
+// problems are reported together in a single error, including bundles with no olm.package property or an empty
+// version, whose version would otherwise be treated as 0.0.0 when matched against version ranges. When bestEffort
+// is true, those bundles are instead removed from the catalog and their channels, with a warning.
+func checkBundleVersions(fbc *declcfg.DeclarativeConfig, normalize, bestEffort bool, warnf func(filter.Warning)) error {
+	var errs []string
+	skipped := map[string]sets.Set[string]{}
+	unknown := func(b *declcfg.Bundle, reason string) {
+		if !bestEffort {
+			errs = append(errs, fmt.Sprintf("bundle %q in package %q: %s", b.Name, b.Package, reason))
+			return
+		}
+		warnf(filter.Warning{
+			Code:    filter.WarningBundleVersionUnknown,
+			Package: b.Package,
+			Bundle:  b.Name,
+			Message: fmt.Sprintf("leaving out bundle %q in package %q: %s", b.Name, b.Package, reason),
+		})
+		if skipped[b.Package] == nil {
+			skipped[b.Package] = sets.New[string]()
+		}
+		skipped[b.Package].Insert(b.Name)
+	}
+	for bi := range fbc.Bundles {
+		b := &fbc.Bundles[bi]
+		if !hasPropertyType(b.Properties, property.TypePackage) {
+			unknown(b, fmt.Sprintf("missing %s property, so its version is unknown", property.TypePackage))
+			continue
+		}
+		for pi, p := range b.Properties {
+			if p.Type != property.TypePackage {
+				continue
+			}
+			var pkg property.Package
+			if err := json.Unmarshal(p.Value, &pkg); err != nil {
+				errs = append(errs, fmt.Sprintf("bundle %q in package %q: invalid %s property: %v", b.Name, b.Package, property.TypePackage, err))
+				continue
+			}
+			if pkg.Version == "" {
+				unknown(b, fmt.Sprintf("%s property has no version", property.TypePackage))
+				continue
+			}
+			if _, err := blangsemver.Parse(pkg.Version); err == nil {
+				continue
+			}
+			tolerant, err := blangsemver.ParseTolerant(pkg.Version)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("bundle %q in package %q: version %q is not valid semver: %v", b.Name, b.Package, pkg.Version, err))
+				continue
+			}
+			if !normalize {
+				errs = append(errs, fmt.Sprintf("bundle %q in package %q: version %q is not valid semver (use --normalize-versions to rewrite it as %q)", b.Name, b.Package, pkg.Version, tolerant.String()))
+				continue
+			}
+			warnf(filter.Warning{
+				Code:    filter.WarningVersionNormalized,
+				Package: b.Package,
+				Bundle:  b.Name,
+				Message: fmt.Sprintf("normalizing version %q of bundle %q in package %q to %q", pkg.Version, b.Name, b.Package, tolerant.String()),
+			})
+			b.Properties[pi] = property.MustBuildPackage(pkg.PackageName, tolerant.String())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("found invalid bundle versions:\n  %s", strings.Join(errs, "\n  "))
+	}
+	if len(skipped) > 0 {
+		removeBundles(fbc, skipped)
+	}
+	return nil
+}
+
+// removeBundles removes the named bundles of each package from the catalog, along with their channel entries.
+func removeBundles(fbc *declcfg.DeclarativeConfig, names map[string]sets.Set[string]) {
+	bundles := fbc.Bundles[:0]
+	for _, b := range fbc.Bundles {
+		if !names[b.Package].Has(b.Name) {
+			bundles = append(bundles, b)
+		}
+	}
+	fbc.Bundles = bundles
+	for ci := range fbc.Channels {
+		ch := &fbc.Channels[ci]
+		entries := ch.Entries[:0]
+		for _, e := range ch.Entries {
+			if !names[ch.Package].Has(e.Name) {
+				entries = append(entries, e)
+			}
+		}
+		ch.Entries = entries
+	}
+}
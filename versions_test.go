@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+
+	"fbc-filter/pkg/filter"
+)
+
+func TestCheckBundleVersions(t *testing.T) {
+	bundle := func(name string, props ...property.Property) declcfg.Bundle {
+		return declcfg.Bundle{Schema: declcfg.SchemaBundle, Name: name, Package: "foo", Properties: props}
+	}
+	catalog := func() *declcfg.DeclarativeConfig {
+		return &declcfg.DeclarativeConfig{Bundles: []declcfg.Bundle{
+			bundle("foo.v1.0.0", property.MustBuildPackage("foo", "1.0.0")),
+			bundle("foo.v1.1.0", property.MustBuildPackage("foo", "v1.1.0")),
+			bundle("foo.v1.2", property.MustBuildPackage("foo", "1.2")),
+		}}
+	}
+
+	tests := []struct {
+		name         string
+		fbc          *declcfg.DeclarativeConfig
+		normalize    bool
+		bestEffort   bool
+		wantVersions []string
+		wantWarnings []filter.WarningCode
+		wantErr      []string
+	}{
+		{
+			name:      "tolerant versions rejected",
+			fbc:       catalog(),
+			normalize: false,
+			wantErr: []string{
+				`bundle "foo.v1.1.0" in package "foo": version "v1.1.0" is not valid semver (use --normalize-versions to rewrite it as "1.1.0")`,
+				`bundle "foo.v1.2" in package "foo": version "1.2" is not valid semver (use --normalize-versions to rewrite it as "1.2.0")`,
+			},
+		},
+		{
+			name:         "tolerant versions normalized",
+			fbc:          catalog(),
+			normalize:    true,
+			wantVersions: []string{"1.0.0", "1.1.0", "1.2.0"},
+			wantWarnings: []filter.WarningCode{filter.WarningVersionNormalized, filter.WarningVersionNormalized},
+		},
+		{
+			name: "unknown versions",
+			fbc: &declcfg.DeclarativeConfig{Bundles: []declcfg.Bundle{
+				bundle("foo.v1.0.0"),
+				bundle("foo.empty", property.MustBuildPackage("foo", "")),
+				bundle("foo.invalid", property.Property{Type: property.TypePackage, Value: json.RawMessage(`"foo"`)}),
+				bundle("foo.one", property.MustBuildPackage("foo", "one")),
+			}},
+			normalize: true,
+			wantErr: []string{
+				`bundle "foo.v1.0.0" in package "foo": missing olm.package property, so its version is unknown`,
+				`bundle "foo.empty" in package "foo": olm.package property has no version`,
+				`bundle "foo.invalid" in package "foo": invalid olm.package property`,
+				`bundle "foo.one" in package "foo": version "one" is not valid semver`,
+			},
+		},
+		{
+			name: "unknown versions left out with best effort",
+			fbc: &declcfg.DeclarativeConfig{
+				Channels: []declcfg.Channel{{Schema: declcfg.SchemaChannel, Package: "foo", Name: "stable", Entries: []declcfg.ChannelEntry{
+					{Name: "foo.missing"},
+					{Name: "foo.empty", Replaces: "foo.missing"},
+					{Name: "foo.v1.0.0", Replaces: "foo.empty"},
+				}}},
+				Bundles: []declcfg.Bundle{
+					bundle("foo.missing"),
+					bundle("foo.empty", property.MustBuildPackage("foo", "")),
+					bundle("foo.v1.0.0", property.MustBuildPackage("foo", "1.0.0")),
+				},
+			},
+			bestEffort:   true,
+			wantVersions: []string{"1.0.0"},
+			wantWarnings: []filter.WarningCode{filter.WarningBundleVersionUnknown, filter.WarningBundleVersionUnknown},
+		},
+		{
+			name: "invalid versions still rejected with best effort",
+			fbc: &declcfg.DeclarativeConfig{Bundles: []declcfg.Bundle{
+				bundle("foo.v1.0.0"),
+				bundle("foo.one", property.MustBuildPackage("foo", "one")),
+			}},
+			bestEffort: true,
+			wantErr:    []string{`bundle "foo.one" in package "foo": version "one" is not valid semver`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var warnings []filter.Warning
+			err := checkBundleVersions(tt.fbc, tt.normalize, tt.bestEffort, func(w filter.Warning) { warnings = append(warnings, w) })
+			if len(tt.wantErr) > 0 {
+				if err == nil {
+					t.Fatalf("expected errors %q, got none", tt.wantErr)
+				}
+				for _, want := range tt.wantErr {
+					if !strings.Contains(err.Error(), want) {
+						t.Errorf("expected error containing %q, got %v", want, err)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var versions []string
+			for _, b := range tt.fbc.Bundles {
+				for _, p := range b.Properties {
+					var pkg property.Package
+					if err := json.Unmarshal(p.Value, &pkg); err != nil {
+						t.Fatal(err)
+					}
+					versions = append(versions, pkg.Version)
+				}
+			}
+			if !reflect.DeepEqual(versions, tt.wantVersions) {
+				t.Errorf("expected versions %v, got %v", tt.wantVersions, versions)
+			}
+			var codes []filter.WarningCode
+			for _, w := range warnings {
+				codes = append(codes, w.Code)
+			}
+			if !reflect.DeepEqual(codes, tt.wantWarnings) {
+				t.Errorf("expected warnings %v, got %v", tt.wantWarnings, warnings)
+			}
+			for _, ch := range tt.fbc.Channels {
+				if len(ch.Entries) != len(tt.fbc.Bundles) {
+					t.Errorf("expected channel %q to only have entries for the kept bundles, got %+v", ch.Name, ch.Entries)
+				}
+			}
+		})
+	}
+}